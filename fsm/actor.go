@@ -0,0 +1,277 @@
+package fsm
+
+import "fmt"
+
+// SupervisionStrategy decides what a parent Machine does when a child it
+// spawned fails, by calling m.Error() or failing to update its Context.
+type SupervisionStrategy int
+
+const (
+	// SupervisionStop stops the child
+	SupervisionStop SupervisionStrategy = iota
+	// SupervisionRestart replaces the child with a new Machine from the same
+	// Definition
+	SupervisionRestart
+	// SupervisionEscalate stops the child, and fails the parent with
+	// MachineErrorChild
+	SupervisionEscalate
+)
+
+// SpawnOptions for a child Machine
+type SpawnOptions struct {
+	// Done is sent to the parent when the child enters a Final State, if
+	// it's set
+	Done *Event
+	// Error is sent to the parent when the child fails, after the Strategy
+	// has been applied, if it's set
+	Error    *Event
+	Strategy SupervisionStrategy
+}
+
+type child struct {
+	machine    *Machine
+	definition Definition
+	options    SpawnOptions
+	failures   chan error
+}
+
+// Spawn a child Machine from a Definition. The parent receives the child's
+// StateChanges, so don't read from the child's m.StateChangeChannel().
+//
+// When the child enters a Final State, opts.Done is sent to the parent, and if
+// it fails, opts.Strategy is applied and opts.Error is sent. Stopping the
+// parent stops all of its children.
+//
+// It's safe to call from a handler, to keep the child in Context return it
+// from an UpdateContextHandler.
+func (m *Machine) Spawn(id string, d Definition, opts SpawnOptions) *Machine {
+	m.checkIfCreatedCorrectly()
+	m.childrenMtx.Lock()
+	defer m.childrenMtx.Unlock()
+
+	if _, ok := m.children[id]; ok {
+		panic(fmt.Sprintf("[%s] fsm.Spawn() called with duplicate child id '%s'", m.id, id))
+	}
+
+	if !m.mailboxStarted {
+		m.mailboxStarted = true
+		m.childrenWg.Add(1)
+		go m.readMailbox()
+	}
+
+	c := &child{
+		definition: d,
+		options:    opts,
+	}
+	c.start(m, id)
+	m.children[id] = c
+
+	m.childrenWg.Add(1)
+	go m.superviseChild(id, c)
+
+	return c.machine
+}
+
+func (c *child) start(parent *Machine, id string) {
+	c.failures = make(chan error, 1)
+	c.machine = c.definition.New(id)
+	c.machine.parent = parent
+	c.machine.failures = c.failures
+	c.machine.Start()
+}
+
+// Child returns a child Machine spawned with m.Spawn()
+func (m *Machine) Child(id string) (*Machine, bool) {
+	m.checkIfCreatedCorrectly()
+	m.childrenMtx.Lock()
+	defer m.childrenMtx.Unlock()
+
+	if c, ok := m.children[id]; ok {
+		return c.machine, true
+	}
+	return nil, false
+}
+
+// Parent returns the Machine that spawned this one, or nil
+func (m *Machine) Parent() *Machine {
+	m.checkIfCreatedCorrectly()
+	return m.parent
+}
+
+// SendTo sends an Event to a child Machine. It's called with m.EventContext(),
+// so it can be used from a handler.
+func (m *Machine) SendTo(id string, e Event) error {
+	c, ok := m.Child(id)
+	if !ok {
+		return fmt.Errorf("[%s] fsm.SendTo() no child with id '%s'", m.id, id)
+	}
+	return c.SendEventContext(m.EventContext(), e)
+}
+
+// SendParent sends an Event to the parent Machine. It doesn't wait for the
+// parent to handle it, so it's safe to call from a handler. Events sent by
+// a child reach the parent in the order they were sent.
+func (m *Machine) SendParent(e Event) {
+	m.checkIfCreatedCorrectly()
+	if m.parent == nil {
+		panic(fmt.Sprintf("[%s] fsm.SendParent() called on a Machine without a parent", m.id))
+	}
+	m.parent.deliver(&e)
+}
+
+// StopChild stops a child Machine, and forgets about it
+func (m *Machine) StopChild(id string) {
+	m.checkIfCreatedCorrectly()
+	m.childrenMtx.Lock()
+	c, ok := m.children[id]
+	delete(m.children, id)
+	m.childrenMtx.Unlock()
+
+	if ok {
+		c.machine.Stop()
+	}
+}
+
+// deliver an Event to m's mailbox without waiting for it to be handled.
+// Nothing is sent if e isn't set.
+func (m *Machine) deliver(e *Event) {
+	if e == nil {
+		return
+	}
+
+	m.mailboxMtx.Lock()
+	defer m.mailboxMtx.Unlock()
+
+	m.mailbox = append(m.mailbox, *e)
+	select {
+	case m.mailboxReady <- struct{}{}:
+	default:
+	}
+}
+
+// readMailbox sends Events from the mailbox to m in order, until m is
+// stopped
+func (m *Machine) readMailbox() {
+	defer m.childrenWg.Done()
+
+	for {
+		select {
+		case <-m.lifetime.Done():
+			return
+		case <-m.mailboxReady:
+		}
+
+		for {
+			m.mailboxMtx.Lock()
+			if len(m.mailbox) == 0 {
+				m.mailboxMtx.Unlock()
+				break
+			}
+			e := m.mailbox[0]
+			m.mailbox = m.mailbox[1:]
+			m.mailboxMtx.Unlock()
+
+			if m.SendEventContext(m.lifetime, e) != nil && m.lifetime.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// escalate fails m with an error from a child, without waiting for it
+func (m *Machine) escalate(err error) {
+	m.childrenWg.Add(1)
+	go func() {
+		defer m.childrenWg.Done()
+		if m.stateChangeMtx.LockContext(m.lifetime) != nil {
+			return
+		}
+		defer m.stateChangeMtx.Unlock()
+		m.handleError(err, MachineErrorChild)
+	}()
+}
+
+// notifySupervisor tells the parent, if there is one, that m failed
+func (m *Machine) notifySupervisor(err error) {
+	if m.failures == nil {
+		return
+	}
+
+	// a failure is already waiting to be handled
+	select {
+	case m.failures <- err:
+	default:
+	}
+}
+
+// superviseChild reads a child's StateChanges and failures until it stops
+func (m *Machine) superviseChild(id string, c *child) {
+	defer m.childrenWg.Done()
+
+	machine := c.machine
+	failures := c.failures
+	for {
+		select {
+		case sc := <-machine.StateChangeChannel():
+			if sc.IsLast {
+				return
+			}
+			if machine.states[sc.To].Final {
+				m.deliver(c.options.Done)
+			}
+		case err := <-failures:
+			m.handleChildFailure(id, c, err)
+			drain(machine)
+			if c.machine != machine {
+				// restarted, supervise the new Machine instead
+				m.childrenWg.Add(1)
+				go m.superviseChild(id, c)
+			}
+			return
+		}
+	}
+}
+
+func (m *Machine) handleChildFailure(id string, c *child, err error) {
+	stopping := c.machine
+	go stopping.Stop()
+
+	m.childrenMtx.Lock()
+	if m.children[id] == c {
+		switch {
+		case m.lifetime.Err() != nil:
+		case c.options.Strategy == SupervisionRestart:
+			c.start(m, id)
+		default:
+			delete(m.children, id)
+		}
+	}
+	m.childrenMtx.Unlock()
+
+	if c.options.Strategy == SupervisionEscalate {
+		m.escalate(err)
+	}
+	m.deliver(c.options.Error)
+}
+
+// drain a stopping Machine's StateChanges until the last one
+func drain(m *Machine) {
+	for sc := range m.StateChangeChannel() {
+		if sc.IsLast {
+			return
+		}
+	}
+}
+
+// stopChildren must be called after m.lifetime is cancelled
+func (m *Machine) stopChildren() {
+	m.childrenMtx.Lock()
+	children := m.children
+	m.children = map[string]*child{}
+	m.childrenMtx.Unlock()
+
+	for _, c := range children {
+		c.machine.Stop()
+	}
+	m.childrenWg.Wait()
+}
@@ -0,0 +1,255 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_SpawnChildren(t *testing.T) {
+	// worker
+	const (
+		Working fsm.State = iota
+		Finished
+	)
+
+	const (
+		Finish fsm.Event = iota
+	)
+
+	workersStopped := make(chan struct{}, 10)
+	worker := fsm.Definition{
+		InitialState: Working,
+		Events:       []fsm.Event{Finish},
+		States: fsm.States{
+			Working: fsm.StateNode{
+				Invoke: []fsm.Service{{
+					Id: "work",
+					Run: func(ctx context.Context, m *fsm.Machine) (interface{}, error) {
+						<-ctx.Done()
+						workersStopped <- struct{}{}
+						return nil, ctx.Err()
+					},
+				}},
+				Events: fsm.EventToTransition{
+					Finish: fsm.Transition{State: Finished},
+				},
+			},
+			Finished: fsm.StateNode{Final: true},
+		},
+	}
+
+	// supervisor
+	const (
+		Supervising fsm.State = iota
+	)
+
+	const (
+		WorkerDone fsm.Event = iota + 100
+		WorkerFailed
+	)
+
+	events := make(chan fsm.Event, 10)
+	escalated := make(chan fsm.MachineError, 1)
+
+	supervisor := fsm.New(
+		"supervisor",
+		10,
+		Supervising,
+		fsm.Context{},
+		[]fsm.Event{WorkerDone, WorkerFailed},
+		fsm.States{
+			Supervising: fsm.StateNode{
+				Error: func(m *fsm.Machine, current fsm.State, next fsm.State, machineError fsm.MachineError) {
+					escalated <- machineError
+				},
+				Events: fsm.EventToTransition{
+					WorkerDone: fsm.Transition{
+						State: Supervising,
+						Actions: []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+							events <- WorkerDone
+						}},
+					},
+					WorkerFailed: fsm.Transition{
+						State: Supervising,
+						Actions: []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+							events <- WorkerFailed
+						}},
+					},
+				},
+			},
+		},
+		nil,
+	)
+	go func() {
+		for range supervisor.StateChangeChannel() {
+		}
+	}()
+
+	done, failed := fsm.EventPtr(WorkerDone), fsm.EventPtr(WorkerFailed)
+	restart := fsm.SpawnOptions{Done: done, Error: failed, Strategy: fsm.SupervisionRestart}
+	first := supervisor.Spawn("first", worker, restart)
+	supervisor.Spawn("second", worker, fsm.SpawnOptions{Done: done, Error: failed, Strategy: fsm.SupervisionEscalate})
+	supervisor.Spawn("third", worker, fsm.SpawnOptions{Done: done, Error: failed})
+
+	assert.Equal(t, supervisor, first.Parent())
+	assert.Panics(t, func() { supervisor.Spawn("first", worker, restart) })
+
+	// completion
+	assert.NoError(t, supervisor.SendTo("first", Finish))
+	assert.Equal(t, WorkerDone, <-events)
+	<-workersStopped
+	assert.Error(t, supervisor.SendTo("missing", Finish))
+
+	// restart
+	first.Error(errors.New("worker broke"))
+	assert.Equal(t, WorkerFailed, <-events)
+	restarted, ok := supervisor.Child("first")
+	assert.True(t, ok)
+	assert.NotEqual(t, first, restarted)
+	assert.Equal(t, Working, restarted.State())
+
+	// escalate
+	second, _ := supervisor.Child("second")
+	second.Error(errors.New("worker broke"))
+	assert.Equal(t, fsm.MachineErrorChild, <-escalated)
+	assert.Equal(t, WorkerFailed, <-events)
+	_, ok = supervisor.Child("second")
+	assert.False(t, ok)
+	<-workersStopped
+
+	// stopping the parent stops the remaining children, and waits for them
+	supervisor.Stop()
+	assert.Len(t, workersStopped, 2)
+}
+
+func Test_SpawnWithoutEvents(t *testing.T) {
+	const (
+		Working fsm.State = iota
+		Finished
+	)
+
+	const (
+		Finish fsm.Event = iota
+	)
+
+	worker := fsm.Definition{
+		InitialState: Working,
+		Events:       []fsm.Event{Finish},
+		States: fsm.States{
+			Working: fsm.StateNode{Events: fsm.EventToTransition{
+				Finish: fsm.Transition{State: Finished},
+			}},
+			Finished: fsm.StateNode{Final: true},
+		},
+	}
+
+	// the parent also has Event 0, which must not be sent
+	const (
+		Supervising fsm.State = iota
+		Surprised
+	)
+
+	escalated := make(chan fsm.MachineError, 1)
+	supervisor := fsm.New(
+		"supervisor",
+		10,
+		Supervising,
+		fsm.Context{},
+		[]fsm.Event{Finish},
+		fsm.States{
+			Supervising: fsm.StateNode{Events: fsm.EventToTransition{
+				Finish: fsm.Transition{State: Surprised},
+			}},
+		},
+		func(m *fsm.Machine, current fsm.State, next fsm.State, machineError fsm.MachineError) {
+			escalated <- machineError
+		},
+	)
+
+	child := supervisor.Spawn("child", worker, fsm.SpawnOptions{Strategy: fsm.SupervisionEscalate})
+	assert.NoError(t, supervisor.SendTo("child", Finish))
+	child.Error(errors.New("worker broke"))
+
+	// escalating reaches the parent's error handler
+	assert.Equal(t, fsm.MachineErrorChild, <-escalated)
+
+	// give an Event sent by mistake time to arrive
+	time.Sleep(20 * time.Millisecond)
+	supervisor.Stop()
+	assert.Equal(t, Supervising, supervisor.State())
+}
+
+func Test_SendParentInOrder(t *testing.T) {
+	const (
+		Start fsm.Event = iota
+	)
+
+	const (
+		Waiting fsm.State = iota
+		Progressing
+	)
+
+	const (
+		Progress fsm.Event = iota + 100
+		Done
+	)
+
+	worker := fsm.Definition{
+		InitialState: 0,
+		Events:       []fsm.Event{Start},
+		States: fsm.States{
+			0: fsm.StateNode{Events: fsm.EventToTransition{
+				Start: fsm.Transition{
+					State: 1,
+					Actions: []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+						m.SendParent(Progress)
+						m.SendParent(Done)
+					}},
+				},
+			}},
+			1: fsm.StateNode{},
+		},
+	}
+
+	// Done is only handled after Progress
+	received := make(chan fsm.State, 100)
+	supervisor := fsm.New(
+		"supervisor",
+		100,
+		Waiting,
+		fsm.Context{},
+		[]fsm.Event{Progress, Done},
+		fsm.States{
+			Waiting: fsm.StateNode{Events: fsm.EventToTransition{
+				Progress: fsm.Transition{State: Progressing},
+			}},
+			Progressing: fsm.StateNode{Events: fsm.EventToTransition{
+				Done: fsm.Transition{State: Waiting},
+			}},
+		},
+		func(m *fsm.Machine, current fsm.State, next fsm.State, machineError fsm.MachineError) {
+			received <- current
+		},
+	)
+	go func() {
+		for sc := range supervisor.StateChangeChannel() {
+			if !sc.IsLast {
+				received <- sc.To
+			}
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		child := supervisor.Spawn(fmt.Sprintf("worker-%d", i), worker, fsm.SpawnOptions{})
+		assert.True(t, child.SendEvent(Start))
+		assert.Equal(t, Progressing, <-received)
+		assert.Equal(t, Waiting, <-received)
+	}
+	supervisor.Stop()
+}
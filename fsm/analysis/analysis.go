@@ -0,0 +1,181 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ojkelly.dev/fsm"
+)
+
+// defaultBound is how many Configurations are explored, unless
+// Options.Bound is set
+const defaultBound = 10000
+
+// Options change what is analysed
+type Options struct {
+	// Product explores each State together with its Context, evaluating
+	// guards and UpdateContext handlers. Otherwise guards are ignored.
+	Product bool
+
+	// Domains are the values each unprotected ContextKey can be set to
+	// between Events, when Product is set. Protected keys are ignored, only
+	// Transitions can change them.
+	Domains map[fsm.ContextKey][]interface{}
+
+	// Bound is how many Configurations are explored, 10000 by default
+	Bound int
+}
+
+// Report of what was found. Each list is sorted.
+type Report struct {
+	// Unreachable States can't be reached from the initial State. It's left
+	// empty when Truncated, as they may be reached past the bound.
+	Unreachable []fsm.State
+
+	// Traps are States that aren't Final, where no Transition can be taken
+	Traps []fsm.State
+
+	// Livelocks are groups of States that can only Transition between each
+	// other, none of which is Final
+	Livelocks [][]fsm.State
+
+	// DeadEvents never cause a Transition. It's left empty when Truncated,
+	// as they may fire past the bound.
+	DeadEvents []fsm.Event
+
+	// Explored is how many Configurations were explored, and Truncated is
+	// true if Bound was reached before all of them were
+	Explored  int
+	Truncated bool
+
+	m *fsm.Machine
+}
+
+// OK is true if there are no problems
+func (r Report) OK() bool {
+	return len(r.Unreachable) == 0 && len(r.Traps) == 0 && len(r.Livelocks) == 0 && len(r.DeadEvents) == 0
+}
+
+// Problems describes each problem on one line
+func (r Report) Problems() []string {
+	problems := []string{}
+	for _, s := range r.Unreachable {
+		problems = append(problems, fmt.Sprintf("state '%s' is unreachable", r.m.GetNameForState(s)))
+	}
+	for _, s := range r.Traps {
+		problems = append(problems, fmt.Sprintf("state '%s' is a trap, it can't be left and isn't final", r.m.GetNameForState(s)))
+	}
+	for _, states := range r.Livelocks {
+		if len(states) == 1 {
+			problems = append(problems, fmt.Sprintf("state '%s' can only transition to itself, and isn't final", r.m.GetNameForState(states[0])))
+			continue
+		}
+		names := make([]string, 0, len(states))
+		for _, s := range states {
+			names = append(names, fmt.Sprintf("'%s'", r.m.GetNameForState(s)))
+		}
+		problems = append(problems, fmt.Sprintf("states %s can't be left, and none of them is final", strings.Join(names, ", ")))
+	}
+	for _, e := range r.DeadEvents {
+		problems = append(problems, fmt.Sprintf("event '%s' can never fire", r.m.GetNameForEvent(e)))
+	}
+	return problems
+}
+
+// Analyze the Definition
+func Analyze(d fsm.Definition, o Options) Report {
+	if o.Bound <= 0 {
+		o.Bound = defaultBound
+	}
+
+	var g *graph
+	if o.Product {
+		g = product(d, o)
+	} else {
+		g = structure(d)
+	}
+
+	r := Report{Explored: g.explored, Truncated: g.truncated, m: d.New("analysis")}
+
+	// past the bound any State may be reached, and any Event may fire
+	if !g.truncated {
+		r.Unreachable, r.DeadEvents = unused(d, g)
+	}
+
+	traps := map[fsm.State]bool{}
+	livelocks := map[string]bool{}
+	for _, component := range g.bottom() {
+		states := map[fsm.State]bool{}
+		final := false
+		moves := false
+		inside := map[int]bool{}
+		for _, i := range component {
+			inside[i] = true
+		}
+		for _, i := range component {
+			s := g.nodes[i].state
+			states[s] = true
+			final = final || d.States[s].Final
+			for _, a := range g.out[i] {
+				moves = moves || (a.transition && inside[a.to])
+			}
+		}
+		if final {
+			continue
+		}
+
+		sorted := sortStates(states)
+		if !moves {
+			for _, s := range sorted {
+				traps[s] = true
+			}
+			continue
+		}
+		if key := fmt.Sprint(sorted); !livelocks[key] {
+			livelocks[key] = true
+			r.Livelocks = append(r.Livelocks, sorted)
+		}
+	}
+	r.Traps = sortStates(traps)
+	sort.Slice(r.Livelocks, func(i, j int) bool { return r.Livelocks[i][0] < r.Livelocks[j][0] })
+
+	return r
+}
+
+// unused returns the States that weren't reached and the Events that never
+// caused a Transition, which is only conclusive if g wasn't truncated
+func unused(d fsm.Definition, g *graph) (unreachable []fsm.State, dead []fsm.Event) {
+	reached := map[fsm.State]bool{}
+	fired := map[fsm.Event]bool{}
+	for i, n := range g.nodes {
+		reached[n.state] = true
+		for _, a := range g.out[i] {
+			if a.transition {
+				fired[a.event] = true
+			}
+		}
+	}
+
+	for _, s := range d.SortedStates() {
+		if !reached[s] {
+			unreachable = append(unreachable, s)
+		}
+	}
+	for _, e := range d.Events {
+		if !fired[e] {
+			dead = append(dead, e)
+		}
+	}
+	sort.Slice(dead, func(i, j int) bool { return dead[i] < dead[j] })
+	return unreachable, dead
+}
+
+func sortStates(states map[fsm.State]bool) []fsm.State {
+	sorted := make([]fsm.State, 0, len(states))
+	for s := range states {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
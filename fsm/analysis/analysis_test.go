@@ -0,0 +1,133 @@
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/analysis"
+)
+
+const (
+	Idle fsm.State = iota
+	Running
+	Stuck
+	Ping
+	Pong
+	Done
+	Orphan
+)
+
+const (
+	Start fsm.Event = iota
+	Finish
+	Fail
+	Spin
+	Bounce
+	Never
+)
+
+const (
+	KeyReady fsm.ContextKey = iota
+)
+
+func definition() fsm.Definition {
+	return fsm.Definition{
+		InitialState: Idle,
+		Context:      fsm.Context{KeyReady: fsm.ContextMeta{Inital: false}},
+		Events:       []fsm.Event{Start, Finish, Fail, Spin, Bounce, Never},
+		States: fsm.States{
+			Idle: fsm.StateNode{Events: fsm.EventToTransition{
+				Start: fsm.Transition{State: Running},
+			}},
+			Running: fsm.StateNode{Events: fsm.EventToTransition{
+				Finish: fsm.Transition{State: Done, Condition: fsm.ContextIsTrue(KeyReady)},
+				Fail:   fsm.Transition{State: Stuck},
+				Spin:   fsm.Transition{State: Ping},
+			}},
+			Stuck: fsm.StateNode{},
+			Ping: fsm.StateNode{Events: fsm.EventToTransition{
+				Bounce: fsm.Transition{State: Pong},
+			}},
+			Pong: fsm.StateNode{Events: fsm.EventToTransition{
+				Bounce: fsm.Transition{State: Ping},
+			}},
+			Done: fsm.StateNode{Final: true},
+			Orphan: fsm.StateNode{Events: fsm.EventToTransition{
+				Never: fsm.Transition{State: Done},
+			}},
+		},
+		StateNames: fsm.StateNames{
+			Idle:    "Idle",
+			Running: "Running",
+			Stuck:   "Stuck",
+			Ping:    "Ping",
+			Pong:    "Pong",
+			Done:    "Done",
+			Orphan:  "Orphan",
+		},
+		EventNames: fsm.EventNames{
+			Start:  "Start",
+			Finish: "Finish",
+			Fail:   "Fail",
+			Spin:   "Spin",
+			Bounce: "Bounce",
+			Never:  "Never",
+		},
+		ContextKeyNames: fsm.ContextKeyNames{KeyReady: "Ready"},
+	}
+}
+
+func Test_Analyze(t *testing.T) {
+	r := analysis.Analyze(definition(), analysis.Options{})
+	assert.False(t, r.OK())
+	assert.Equal(t, []fsm.State{Orphan}, r.Unreachable)
+	assert.Equal(t, []fsm.State{Stuck}, r.Traps)
+	assert.Equal(t, [][]fsm.State{{Ping, Pong}}, r.Livelocks)
+	assert.Equal(t, []fsm.Event{Never}, r.DeadEvents)
+	assert.Equal(t, []string{
+		"state 'Orphan' is unreachable",
+		"state 'Stuck' is a trap, it can't be left and isn't final",
+		"states 'Ping', 'Pong' can't be left, and none of them is final",
+		"event 'Never' can never fire",
+	}, r.Problems())
+
+	d := definition()
+	delete(d.States, Stuck)
+	delete(d.States, Ping)
+	delete(d.States, Pong)
+	delete(d.States, Orphan)
+	d.States[Running] = fsm.StateNode{Events: fsm.EventToTransition{Finish: fsm.Transition{State: Done}}}
+	d.Events = []fsm.Event{Start, Finish}
+	assert.True(t, analysis.Analyze(d, analysis.Options{}).OK())
+}
+
+func Test_AnalyzeProduct(t *testing.T) {
+	// Ready is never set, so Finish never fires
+	r := analysis.Analyze(definition(), analysis.Options{Product: true})
+	assert.Equal(t, []fsm.State{Done, Orphan}, r.Unreachable)
+	assert.Equal(t, []fsm.Event{Finish, Never}, r.DeadEvents)
+	assert.Equal(t, 5, r.Explored)
+	assert.False(t, r.Truncated)
+
+	r = analysis.Analyze(definition(), analysis.Options{
+		Product: true,
+		Domains: map[fsm.ContextKey][]interface{}{KeyReady: {false, true}},
+	})
+	assert.Equal(t, []fsm.State{Orphan}, r.Unreachable)
+	assert.Equal(t, []fsm.State{Stuck}, r.Traps)
+	assert.Equal(t, [][]fsm.State{{Ping, Pong}}, r.Livelocks)
+	assert.Equal(t, []fsm.Event{Never}, r.DeadEvents)
+	assert.Equal(t, 12, r.Explored)
+
+	// nothing past the bound is a trap, as it wasn't explored, and nothing
+	// is unreachable or dead, as it may be past the bound
+	r = analysis.Analyze(definition(), analysis.Options{Product: true, Bound: 2})
+	assert.True(t, r.Truncated)
+	assert.Equal(t, 2, r.Explored)
+	assert.Empty(t, r.Traps)
+	assert.Empty(t, r.Livelocks)
+	assert.Empty(t, r.Unreachable)
+	assert.Empty(t, r.DeadEvents)
+	assert.True(t, r.OK())
+}
@@ -0,0 +1,29 @@
+/*
+Package analysis inspects a Definition for States and Events that can't be
+used as intended.
+
+	report := analysis.Analyze(definition, analysis.Options{})
+	for _, problem := range report.Problems() {
+		fmt.Println(problem)
+	}
+
+It reports States that are unreachable from the initial State, trap States
+that can't be left and aren't Final, groups of States that can only
+Transition between each other and none of which is Final, and Events that can
+never fire.
+
+By default guards are ignored, and every Transition is assumed to be
+possible. With Product set, guards and UpdateContext handlers are evaluated,
+exploring every State together with its Context, up to Bound of them. Values
+that unprotected ContextKeys can be set to between Events are listed in
+Domains. If Bound is reached the Report is Truncated, and unreachable States
+and dead Events aren't reported, as they may be found past it.
+
+	report := analysis.Analyze(definition, analysis.Options{
+		Product: true,
+		Domains: map[fsm.ContextKey][]interface{}{KeyInStock: {true, false}},
+	})
+
+The same analysis is run by fsmctl validate and fsmctl analyze.
+*/
+package analysis // import "ojkelly.dev/fsm/analysis"
@@ -0,0 +1,213 @@
+package analysis
+
+import (
+	"sort"
+
+	"ojkelly.dev/fsm"
+)
+
+// graph of what was explored, each node is a State, or a Configuration when
+// exploring the product
+type graph struct {
+	// explored is how many nodes were expanded
+	explored  int
+	nodes     []node
+	out       [][]arc
+	truncated bool
+}
+
+type node struct {
+	state fsm.State
+	// expanded is false if the node's arcs weren't explored, because the
+	// bound was reached
+	expanded bool
+}
+
+type arc struct {
+	to    int
+	event fsm.Event
+	// transition is false when a Context value was set between Events
+	transition bool
+}
+
+// structure is the graph of States reachable from the initial State,
+// ignoring guards
+func structure(d fsm.Definition) *graph {
+	out := map[fsm.State][]fsm.Edge{}
+	for _, e := range d.Edges() {
+		out[e.From] = append(out[e.From], e)
+	}
+
+	g := &graph{}
+	index := map[fsm.State]int{}
+	add := func(s fsm.State) int {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = len(g.nodes)
+		g.nodes = append(g.nodes, node{state: s, expanded: true})
+		g.out = append(g.out, nil)
+		return index[s]
+	}
+
+	add(d.InitialState)
+	for i := 0; i < len(g.nodes); i++ {
+		g.explored++
+		for _, e := range out[g.nodes[i].state] {
+			to := add(e.To)
+			g.out[i] = append(g.out[i], arc{to: to, event: e.Event, transition: true})
+		}
+	}
+	return g
+}
+
+// product is the graph of Configurations reachable from the initial one,
+// evaluating guards, up to the bound
+func product(d fsm.Definition, o Options) *graph {
+	out := map[fsm.State][]fsm.Edge{}
+	for _, e := range d.Edges() {
+		out[e.From] = append(out[e.From], e)
+	}
+
+	domains := []fsm.ContextKey{}
+	for key := range o.Domains {
+		if meta, ok := d.Context[key]; ok && !meta.Protected {
+			domains = append(domains, key)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i] < domains[j] })
+
+	g := &graph{}
+	configs := []fsm.Configuration{}
+	index := map[string]int{}
+	add := func(c fsm.Configuration) int {
+		key := c.Key()
+		if i, ok := index[key]; ok {
+			return i
+		}
+		index[key] = len(g.nodes)
+		g.nodes = append(g.nodes, node{state: c.State})
+		g.out = append(g.out, nil)
+		configs = append(configs, c)
+		return index[key]
+	}
+
+	add(d.InitialConfiguration())
+	for i := 0; i < len(g.nodes); i++ {
+		if i >= o.Bound {
+			g.truncated = true
+			break
+		}
+		g.nodes[i].expanded = true
+		g.explored++
+		c := configs[i]
+
+		for _, e := range out[c.State] {
+			next, blocked := d.Step(c, e)
+			if blocked != nil {
+				continue
+			}
+			to := add(next)
+			g.out[i] = append(g.out[i], arc{to: to, event: e.Event, transition: true})
+		}
+
+		for _, key := range domains {
+			for _, v := range o.Domains[key] {
+				next := c.With(key, v)
+				if next.Key() == c.Key() {
+					continue
+				}
+				g.out[i] = append(g.out[i], arc{to: add(next)})
+			}
+		}
+	}
+	return g
+}
+
+// bottom returns the strongly connected components that have no arcs out of
+// them, leaving out nodes that weren't expanded, as they may have arcs out
+func (g *graph) bottom() [][]int {
+	components := g.components()
+
+	component := make([]int, len(g.nodes))
+	for c, nodes := range components {
+		for _, i := range nodes {
+			component[i] = c
+		}
+	}
+
+	bottom := [][]int{}
+	for c, nodes := range components {
+		exits := false
+		for _, i := range nodes {
+			if !g.nodes[i].expanded {
+				exits = true
+			}
+			for _, a := range g.out[i] {
+				if component[a.to] != c {
+					exits = true
+				}
+			}
+		}
+		if !exits {
+			bottom = append(bottom, nodes)
+		}
+	}
+	return bottom
+}
+
+// components are the strongly connected components, with Tarjan's algorithm
+func (g *graph) components() [][]int {
+	index := make([]int, len(g.nodes))
+	low := make([]int, len(g.nodes))
+	onStack := make([]bool, len(g.nodes))
+	for i := range index {
+		index[i] = -1
+	}
+
+	next := 0
+	stack := []int{}
+	components := [][]int{}
+
+	var connect func(v int)
+	connect = func(v int) {
+		index[v] = next
+		low[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, a := range g.out[v] {
+			w := a.to
+			if index[w] == -1 {
+				connect(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+
+		if low[v] == index[v] {
+			component := []int{}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for v := range g.nodes {
+		if index[v] == -1 {
+			connect(v)
+		}
+	}
+	return components
+}
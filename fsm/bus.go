@@ -0,0 +1,221 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBusCycle is passed to the Bus error handler when delivering an Event
+// would send it back to a Machine that caused it
+var ErrBusCycle = errors.New("fsm.Bus: event would cause a cycle")
+
+// ErrBusMaxHops is passed to the Bus error handler when an Event has been
+// passed between more Machines than the Bus allows
+var ErrBusMaxHops = errors.New("fsm.Bus: event exceeded the maximum number of hops")
+
+// Bus lets Machines react to each other's Transitions. A Machine published on
+// the Bus has its StateChanges delivered to every matching Subscription.
+//
+// StateChanges from one publisher are delivered in the order they were
+// committed. Events are never delivered back to a Machine that caused them,
+// directly or through other Machines.
+type Bus struct {
+	mtx          sync.Mutex
+	maxHops      int
+	publishers   map[string]*busPublisher
+	mappings     map[string][]Mapping
+	errorHandler func(d BusDelivery, err error)
+	closed       bool
+	wg           sync.WaitGroup
+}
+
+// Mapping says: when the Machine with id From enters State Enter, send Event
+// to Target
+type Mapping struct {
+	From   string
+	Enter  State
+	Target *Machine
+	Event  Event
+}
+
+// BusEvent is a StateChange published on the Bus
+type BusEvent struct {
+	Publisher string
+	Change    StateChange
+	// Chain is every Machine id that caused this StateChange, oldest first,
+	// ending with the Publisher
+	Chain []string
+}
+
+// BusDelivery is a BusEvent being sent to a Mapping's Target
+type BusDelivery struct {
+	BusEvent BusEvent
+	Mapping  Mapping
+}
+
+type busPublisher struct {
+	queue []BusEvent
+	ready chan struct{}
+}
+
+type busChainKey struct{}
+
+// NewBus creates a Bus. maxHops limits how many Machines an Event can be
+// passed through, 0 means no limit.
+func NewBus(maxHops int) *Bus {
+	return &Bus{
+		maxHops:    maxHops,
+		publishers: map[string]*busPublisher{},
+		mappings:   map[string][]Mapping{},
+	}
+}
+
+// SetErrorHandler is called when an Event isn't delivered, because of a cycle
+// or because the Target couldn't handle it.
+func (b *Bus) SetErrorHandler(h func(d BusDelivery, err error)) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.errorHandler = h
+}
+
+// Publish the StateChanges of a Machine on the Bus. It does nothing once the
+// Bus is closed.
+func (b *Bus) Publish(m *Machine) {
+	m.checkIfCreatedCorrectly()
+	b.mtx.Lock()
+	if _, ok := b.publishers[m.id]; ok || b.closed {
+		b.mtx.Unlock()
+		return
+	}
+
+	p := &busPublisher{ready: make(chan struct{}, 1)}
+	b.publishers[m.id] = p
+
+	b.wg.Add(1)
+	go b.dispatch(p)
+	b.mtx.Unlock()
+
+	// observe takes the Machine's lock, which is never taken while holding
+	// b.mtx
+	id := m.id
+	m.observe(func(ctx context.Context, change StateChange) {
+		if change.IsLast {
+			return
+		}
+		chain, _ := ctx.Value(busChainKey{}).([]string)
+		b.enqueue(p, BusEvent{
+			Publisher: id,
+			Change:    change,
+			Chain:     append(append([]string{}, chain...), id),
+		})
+	})
+}
+
+// Subscribe adds a Mapping
+func (b *Bus) Subscribe(mapping Mapping) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.mappings[mapping.From] = append(b.mappings[mapping.From], mapping)
+}
+
+// Close stops accepting StateChanges, and waits for the ones already
+// published to be delivered
+func (b *Bus) Close() {
+	b.mtx.Lock()
+	if b.closed {
+		b.mtx.Unlock()
+		return
+	}
+	b.closed = true
+	for _, p := range b.publishers {
+		close(p.ready)
+	}
+	b.mtx.Unlock()
+
+	b.wg.Wait()
+}
+
+func (b *Bus) enqueue(p *busPublisher, e BusEvent) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	p.queue = append(p.queue, e)
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch delivers one publisher's BusEvents in order, until the Bus is
+// closed and the queue is empty
+func (b *Bus) dispatch(p *busPublisher) {
+	defer b.wg.Done()
+
+	for range p.ready {
+		b.drain(p)
+	}
+	b.drain(p)
+}
+
+func (b *Bus) drain(p *busPublisher) {
+	for {
+		b.mtx.Lock()
+		if len(p.queue) == 0 {
+			b.mtx.Unlock()
+			return
+		}
+		e := p.queue[0]
+		p.queue = p.queue[1:]
+		mappings := append([]Mapping{}, b.mappings[e.Publisher]...)
+		b.mtx.Unlock()
+
+		for _, mapping := range mappings {
+			if mapping.Enter == e.Change.To {
+				b.deliver(BusDelivery{BusEvent: e, Mapping: mapping})
+			}
+		}
+	}
+}
+
+func (b *Bus) deliver(d BusDelivery) {
+	target := d.Mapping.Target
+
+	var err error
+	switch {
+	case b.maxHops > 0 && len(d.BusEvent.Chain) > b.maxHops:
+		err = ErrBusMaxHops
+	case inChain(d.BusEvent.Chain, target.id):
+		err = ErrBusCycle
+	default:
+		ctx := context.WithValue(context.Background(), busChainKey{}, d.BusEvent.Chain)
+		err = target.SendEventContext(ctx, d.Mapping.Event)
+	}
+
+	if err == nil {
+		return
+	}
+
+	b.mtx.Lock()
+	h := b.errorHandler
+	b.mtx.Unlock()
+
+	if h != nil {
+		h(d, err)
+	}
+}
+
+func inChain(chain []string, id string) bool {
+	for _, c := range chain {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package fsm_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+// drainStateChanges reads a Machine's StateChanges, and returns how many
+// there were once it's stopped
+func drainStateChanges(m *fsm.Machine) <-chan int {
+	count := make(chan int, 1)
+	go func() {
+		n := 0
+		for change := range m.StateChangeChannel() {
+			if change.IsLast {
+				count <- n
+				return
+			}
+			n++
+		}
+	}()
+	return count
+}
+
+func Test_Bus(t *testing.T) {
+	// orders cycle through Placed -> Packed -> Shipped -> Placed
+	const (
+		Placed fsm.State = iota
+		Packed
+		Shipped
+	)
+
+	const (
+		Next fsm.Event = iota
+	)
+
+	// stock is Held between Packed and Shipped, out of order Events would be
+	// unhandled
+	const (
+		Free fsm.State = iota
+		Held
+	)
+
+	const (
+		Reserve fsm.Event = iota
+		Release
+		Audit
+	)
+
+	const (
+		KeyShipped fsm.ContextKey = iota
+	)
+
+	newMachine := func(id string, initial fsm.State, events []fsm.Event, states fsm.States) *fsm.Machine {
+		return fsm.New(id, 0, initial, fsm.Context{KeyShipped: fsm.ContextMeta{Inital: 0}}, events, states, nil)
+	}
+
+	bus := fsm.NewBus(0)
+	errs := []error{}
+	var errsMtx sync.Mutex
+	bus.SetErrorHandler(func(d fsm.BusDelivery, err error) {
+		errsMtx.Lock()
+		defer errsMtx.Unlock()
+		errs = append(errs, err)
+	})
+
+	audit := newMachine("audit", Free, []fsm.Event{Audit}, fsm.States{
+		Free: fsm.StateNode{Events: fsm.EventToTransition{
+			Audit: fsm.Transition{
+				State: Free,
+				UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+					return fsm.UpdateContext{KeyShipped: m.GetContext(KeyShipped).(int) + 1}, nil
+				},
+			},
+		}},
+	})
+	auditCount := drainStateChanges(audit)
+
+	orders := []*fsm.Machine{}
+	stock := []*fsm.Machine{}
+	counts := []<-chan int{}
+	for _, id := range []string{"order-1", "order-2", "order-3"} {
+		order := newMachine(id, Placed, []fsm.Event{Next}, fsm.States{
+			Placed:  fsm.StateNode{Events: fsm.EventToTransition{Next: fsm.Transition{State: Packed}}},
+			Packed:  fsm.StateNode{Events: fsm.EventToTransition{Next: fsm.Transition{State: Shipped}}},
+			Shipped: fsm.StateNode{Events: fsm.EventToTransition{Next: fsm.Transition{State: Placed}}},
+		})
+		item := newMachine("stock-"+id, Free, []fsm.Event{Reserve, Release}, fsm.States{
+			Free: fsm.StateNode{Events: fsm.EventToTransition{Reserve: fsm.Transition{State: Held}}},
+			Held: fsm.StateNode{Events: fsm.EventToTransition{Release: fsm.Transition{State: Free}}},
+		})
+
+		bus.Publish(order)
+		bus.Subscribe(fsm.Mapping{From: id, Enter: Packed, Target: item, Event: Reserve})
+		bus.Subscribe(fsm.Mapping{From: id, Enter: Shipped, Target: item, Event: Release})
+		bus.Subscribe(fsm.Mapping{From: id, Enter: Shipped, Target: audit, Event: Audit})
+
+		orders = append(orders, order)
+		stock = append(stock, item)
+		counts = append(counts, drainStateChanges(order), drainStateChanges(item))
+	}
+
+	const cycles = 100
+	var sent sync.WaitGroup
+	for _, order := range orders {
+		sent.Add(1)
+		go func(order *fsm.Machine) {
+			defer sent.Done()
+			for i := 0; i < cycles*3; i++ {
+				order.SendEvent(Next)
+			}
+		}(order)
+	}
+	sent.Wait()
+
+	// orders are stopped first, so everything they published is delivered
+	// once the Bus is closed
+	for _, order := range orders {
+		order.Stop()
+	}
+	bus.Close()
+	for _, item := range stock {
+		item.Stop()
+	}
+	audit.Stop()
+
+	for i := range orders {
+		assert.Equal(t, cycles*3, <-counts[i*2])
+		assert.Equal(t, cycles*2, <-counts[i*2+1])
+		assert.Equal(t, Free, stock[i].State())
+	}
+	assert.Equal(t, cycles*len(orders), <-auditCount)
+	assert.Equal(t, cycles*len(orders), audit.GetContext(KeyShipped))
+	assert.Empty(t, errs)
+}
+
+func Test_BusCycles(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Pinged
+	)
+
+	const (
+		Ping fsm.Event = iota
+		Reset
+	)
+
+	newMachine := func(id string) *fsm.Machine {
+		return fsm.New(id, 0, Idle, fsm.Context{}, []fsm.Event{Ping, Reset}, fsm.States{
+			Idle:   fsm.StateNode{Events: fsm.EventToTransition{Ping: fsm.Transition{State: Pinged}}},
+			Pinged: fsm.StateNode{Events: fsm.EventToTransition{Reset: fsm.Transition{State: Idle}}},
+		}, nil)
+	}
+
+	a := newMachine("a")
+	b := newMachine("b")
+	aCount := drainStateChanges(a)
+	bCount := drainStateChanges(b)
+
+	dropped := make(chan error, 1)
+	bus := fsm.NewBus(0)
+	bus.SetErrorHandler(func(d fsm.BusDelivery, err error) {
+		dropped <- err
+	})
+	bus.Publish(a)
+	bus.Publish(b)
+	bus.Subscribe(fsm.Mapping{From: "a", Enter: Pinged, Target: b, Event: Ping})
+	bus.Subscribe(fsm.Mapping{From: "b", Enter: Pinged, Target: a, Event: Reset})
+
+	// a -> b -> a would loop
+	a.SendEvent(Ping)
+	assert.Equal(t, fsm.ErrBusCycle, <-dropped)
+
+	bus.Close()
+
+	// publishing on a closed Bus does nothing
+	c := newMachine("c")
+	cCount := drainStateChanges(c)
+	bus.Publish(c)
+	bus.Subscribe(fsm.Mapping{From: "c", Enter: Pinged, Target: a, Event: Reset})
+	c.SendEvent(Ping)
+	bus.Close()
+
+	a.Stop()
+	b.Stop()
+	c.Stop()
+	assert.Equal(t, 1, <-aCount)
+	assert.Equal(t, 1, <-bCount)
+	assert.Equal(t, 1, <-cCount)
+	assert.Equal(t, Pinged, a.State())
+}
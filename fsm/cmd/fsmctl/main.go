@@ -0,0 +1,476 @@
+// Command fsmctl validates, renders and simulates fsm definition files, see
+// ojkelly.dev/fsm/fsmfile for the format.
+//
+//	fsmctl validate order.json
+//	fsmctl analyze -product -domain Express=true,false order.json
+//	fsmctl render -format dot order.json
+//	fsmctl simulate -deny HasStock order.json < events.txt
+//	fsmctl diff order.v1.json order.v2.json
+//	fsmctl paths order.json Refunded
+//	fsmctl cover -html coverage.html api.fsmcov worker.fsmcov
+package main // import "ojkelly.dev/fsm/cmd/fsmctl"
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/analysis"
+	"ojkelly.dev/fsm/fsmcover"
+	"ojkelly.dev/fsm/fsmfile"
+)
+
+const usage = `usage: fsmctl <command> [flags] <file>...
+
+commands:
+  validate <file>...           report errors, unreachable and trap states, and dead events
+  analyze [-product] [-bound n] [-domain key=json,...] [-deny guard,...] <file>
+                               analyse states and context together, evaluating guards
+  render [-format mermaid|dot] <file>
+  simulate [-events file] [-deny guard,...] <file>
+                               send events, one name per line, from stdin or -events
+  diff <old> <new>             compare two definitions
+  paths [-max n] <file> <state>
+                               list event sequences from the initial state to state
+  cover [-html file] [-o file] <profile>...
+                               merge fsmcover profiles and report what wasn't covered
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	commands := map[string]func([]string, io.Reader, io.Writer) error{
+		"validate": validate,
+		"analyze":  analyze,
+		"render":   render,
+		"simulate": simulate,
+		"diff":     diff,
+		"paths":    paths,
+		"cover":    cover,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "fsmctl: unknown command '%s'\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if err := command(args[1:], stdin, stdout); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "fsmctl %s: %s\n", args[0], err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(out)
+	return flags
+}
+
+// load a definition file with guards looked up in guards
+func load(path string, guards fsmfile.Guards) (fsm.Definition, error) {
+	f, err := fsmfile.Load(path)
+	if err != nil {
+		return fsm.Definition{}, err
+	}
+
+	d, err := f.Definition(guards)
+	if err != nil {
+		return fsm.Definition{}, fmt.Errorf("%s:\n%w", path, err)
+	}
+	return d, nil
+}
+
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("validate", stdout)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no files given")
+	}
+
+	failed := 0
+	for _, path := range flags.Args() {
+		d, err := load(path, nil)
+		if err != nil {
+			fmt.Fprintln(stdout, err)
+			failed++
+			continue
+		}
+
+		problems := analysis.Analyze(d, analysis.Options{}).Problems()
+		if len(problems) > 0 {
+			fmt.Fprintf(stdout, "%s:\n%s\n", path, strings.Join(problems, "\n"))
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", path)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files have problems", failed, flags.NArg())
+	}
+	return nil
+}
+
+func analyze(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("analyze", stdout)
+	product := flags.Bool("product", false, "explore states together with context, evaluating guards")
+	bound := flags.Int("bound", 0, "most configurations to explore, 10000 by default")
+	deny := flags.String("deny", "", "comma separated guards that fail, the others pass")
+	domains := domainFlag{}
+	flags.Var(domains, "domain", "values an unprotected context key can be set to, as key=json,json, can be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one file")
+	}
+
+	d, err := load(flags.Arg(0), denyGuards(*deny))
+	if err != nil {
+		return err
+	}
+
+	o := analysis.Options{Product: *product || len(domains) > 0, Bound: *bound, Domains: map[fsm.ContextKey][]interface{}{}}
+	m := d.New("analyze")
+	for name, values := range domains {
+		key, ok := m.ContextKeyByName(name)
+		if !ok {
+			return fmt.Errorf("no context key named '%s'", name)
+		}
+		o.Domains[key] = values
+	}
+
+	report := analysis.Analyze(d, o)
+	for _, problem := range report.Problems() {
+		fmt.Fprintln(stdout, problem)
+	}
+	if o.Product {
+		fmt.Fprintf(stdout, "explored %d configurations\n", report.Explored)
+	}
+	if report.Truncated {
+		fmt.Fprintln(stdout, "the bound was reached, so some states may not have been explored")
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("%d problems found", len(report.Problems()))
+	}
+	return nil
+}
+
+// domainFlag is a repeated key=json,json flag
+type domainFlag map[string][]interface{}
+
+func (f domainFlag) String() string {
+	return ""
+}
+
+func (f domainFlag) Set(raw string) error {
+	parts := strings.SplitN(raw, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=json,json, got '%s'", raw)
+	}
+
+	values := []interface{}{}
+	for _, v := range strings.Split(parts[1], ",") {
+		var value interface{}
+		if err := json.Unmarshal([]byte(v), &value); err != nil {
+			// anything that isn't JSON is a string
+			value = v
+		}
+		values = append(values, value)
+	}
+	f[parts[0]] = append(f[parts[0]], values...)
+	return nil
+}
+
+// denyGuards makes the comma separated guards fail, so the others pass
+func denyGuards(deny string) fsmfile.Guards {
+	guards := fsmfile.Guards{}
+	for _, name := range strings.Split(deny, ",") {
+		if name != "" {
+			guards[strings.TrimSpace(name)] = func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return false }
+		}
+	}
+	return guards
+}
+
+func render(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("render", stdout)
+	format := flags.String("format", "mermaid", "mermaid or dot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one file")
+	}
+
+	d, err := load(flags.Arg(0), nil)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "mermaid":
+		fmt.Fprint(stdout, d.Mermaid())
+	case "dot":
+		fmt.Fprint(stdout, d.DOT())
+	default:
+		return fmt.Errorf("unknown format '%s'", *format)
+	}
+	return nil
+}
+
+func simulate(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("simulate", stdout)
+	events := flags.String("events", "", "read events from this file instead of stdin")
+	deny := flags.String("deny", "", "comma separated guards that fail, the others pass")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected one file")
+	}
+
+	d, err := load(flags.Arg(0), denyGuards(*deny))
+	if err != nil {
+		return err
+	}
+
+	input := stdin
+	if *events != "" {
+		f, err := os.Open(*events)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	m := d.New("simulate")
+	m.SetUnregisteredEventPolicy(fsm.EventPolicyError)
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	m.Start()
+	defer m.Stop()
+
+	fmt.Fprintf(stdout, "start in %s\n", m.GetNameForState(m.State()))
+
+	lines := bufio.NewScanner(input)
+	for lines.Scan() {
+		name := strings.TrimSpace(lines.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		e, ok := m.EventByName(name)
+		if !ok {
+			fmt.Fprintf(stdout, "%s: unknown event\n", name)
+			continue
+		}
+
+		before := m.ContextValues()
+		from := m.State()
+		if err := m.SendEventContext(context.Background(), e); err != nil {
+			fmt.Fprintf(stdout, "%s: rejected, %s\n", name, err)
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s --%s--> %s\n", m.GetNameForState(from), name, m.GetNameForState(m.State()))
+		for i, v := range m.ContextValues() {
+			if !reflect.DeepEqual(before[i].Value, v.Value) {
+				fmt.Fprintf(stdout, "    %s: %v -> %v\n", v.Name, before[i].Value, v.Value)
+			}
+		}
+		if d.States[m.State()].Final {
+			fmt.Fprintf(stdout, "%s is final\n", m.GetNameForState(m.State()))
+		}
+	}
+	return lines.Err()
+}
+
+func diff(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("diff", stdout)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected an old and a new file")
+	}
+
+	old, err := load(flags.Arg(0), nil)
+	if err != nil {
+		return err
+	}
+	new, err := load(flags.Arg(1), nil)
+	if err != nil {
+		return err
+	}
+
+	changes := fsm.Diff(old, new)
+	if len(changes) == 0 {
+		fmt.Fprintln(stdout, "no changes")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+	return nil
+}
+
+func paths(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("paths", stdout)
+	max := flags.Int("max", 0, "longest sequence to list, defaults to the number of states")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected a file and a state")
+	}
+
+	d, err := load(flags.Arg(0), nil)
+	if err != nil {
+		return err
+	}
+
+	m := d.New("paths")
+	target, ok := m.StateByName(flags.Arg(1))
+	if !ok {
+		return fmt.Errorf("no state named '%s'", flags.Arg(1))
+	}
+	if *max <= 0 {
+		*max = len(d.SortedStates())
+	}
+
+	found := 0
+	for _, p := range eventPaths(d, target, *max) {
+		names := make([]string, 0, len(p))
+		for _, e := range p {
+			names = append(names, m.GetNameForEvent(e))
+		}
+		if len(names) == 0 {
+			names = append(names, "(initial state)")
+		}
+		fmt.Fprintln(stdout, strings.Join(names, ", "))
+		found++
+	}
+
+	if found == 0 {
+		return fmt.Errorf("'%s' can't be reached in %d events", flags.Arg(1), *max)
+	}
+	return nil
+}
+
+// eventPaths lists the sequences of Events from the initial State to target
+// that don't visit a State twice, ignoring guards
+func eventPaths(d fsm.Definition, target fsm.State, max int) [][]fsm.Event {
+	edges := map[fsm.State][]fsm.Edge{}
+	for _, e := range d.Edges() {
+		edges[e.From] = append(edges[e.From], e)
+	}
+
+	found := [][]fsm.Event{}
+	visited := map[fsm.State]bool{}
+	var walk func(s fsm.State, path []fsm.Event)
+	walk = func(s fsm.State, path []fsm.Event) {
+		if s == target {
+			found = append(found, append([]fsm.Event{}, path...))
+			return
+		}
+		if len(path) == max {
+			return
+		}
+
+		visited[s] = true
+		defer delete(visited, s)
+
+		// several candidates for an Event to the same State are one path
+		type step struct {
+			event fsm.Event
+			to    fsm.State
+		}
+		seen := map[step]bool{}
+		for _, e := range edges[s] {
+			step := step{event: e.Event, to: e.To}
+			if visited[e.To] || seen[step] {
+				continue
+			}
+			seen[step] = true
+			walk(e.To, append(path, e.Event))
+		}
+	}
+	walk(d.InitialState, []fsm.Event{})
+	return found
+}
+
+func cover(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := newFlagSet("cover", stdout)
+	html := flags.String("html", "", "write an HTML report to this file")
+	out := flags.String("o", "", "write the merged profiles to this file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("no profiles given")
+	}
+
+	profiles := []*fsmcover.Profile{}
+	for _, path := range flags.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		p, err := fsmcover.ReadProfiles(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		profiles = append(profiles, p...)
+	}
+	merged := fsmcover.Merge(profiles...)
+
+	if *out != "" {
+		if err := writeFile(*out, func(w io.Writer) error { return fsmcover.WriteProfiles(w, merged...) }); err != nil {
+			return err
+		}
+	}
+	if *html != "" {
+		if err := writeFile(*html, func(w io.Writer) error { return fsmcover.WriteHTML(w, merged...) }); err != nil {
+			return err
+		}
+	}
+	return fsmcover.WriteText(stdout, merged...)
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fsmctl(stdin string, args ...string) (string, int) {
+	out := &bytes.Buffer{}
+	code := run(args, strings.NewReader(stdin), out, out)
+	return out.String(), code
+}
+
+func Test_Validate(t *testing.T) {
+	out, code := fsmctl("", "validate", "testdata/order.json")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "testdata/order.json: ok\n", out)
+
+	out, code = fsmctl("", "validate", "testdata/invalid.json", "testdata/unreachable.json")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "event 'Go' is declared twice")
+	assert.Contains(t, out, "target state 'Nowhere' is not declared")
+	assert.Contains(t, out, "state 'Orphan' is unreachable")
+
+	out, code = fsmctl("", "validate", "testdata/trap.json")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, strings.Join([]string{
+		"testdata/trap.json:",
+		"state 'Waiting' is a trap, it can't be left and isn't final",
+		"state 'Loop' can only transition to itself, and isn't final",
+		"event 'Never' can never fire",
+		"fsmctl validate: 1 of 1 files have problems",
+		"",
+	}, "\n"), out)
+}
+
+func Test_Analyze(t *testing.T) {
+	out, code := fsmctl("", "analyze", "-product", "testdata/order.json")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "explored 6 configurations\n", out)
+
+	out, code = fsmctl("", "analyze", "-deny", "InStock", "-domain", "Express=true,false", "testdata/order.json")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, strings.Join([]string{
+		"state 'Shipped' is unreachable",
+		"state 'Delivered' is unreachable",
+		"event 'Ship' can never fire",
+		"event 'Deliver' can never fire",
+		"explored 8 configurations",
+		"fsmctl analyze: 4 problems found",
+		"",
+	}, "\n"), out)
+
+	out, code = fsmctl("", "analyze", "-product", "-bound", "2", "testdata/order.json")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "explored 2 configurations\n"+
+		"the bound was reached, so some states may not have been explored\n", out)
+
+	out, code = fsmctl("", "analyze", "-domain", "Nope=1", "testdata/order.json")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "no context key named 'Nope'")
+}
+
+func Test_Render(t *testing.T) {
+	out, code := fsmctl("", "render", "testdata/order.json")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "s_Paid --> s_Shipped: Ship [InStock]")
+
+	out, code = fsmctl("", "render", "-format", "dot", "testdata/order.json")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, `"Delivered" [shape=doublecircle];`)
+}
+
+func Test_Simulate(t *testing.T) {
+	out, code := fsmctl("Pay\n# a comment\nShip\nRefund\nNope\n", "simulate", "-deny", "InStock", "testdata/order.json")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Equal(t, "start in Created", lines[0])
+	assert.Equal(t, "Created --Pay--> Paid", lines[1])
+	assert.Equal(t, "    Paid: false -> true", lines[2])
+	assert.Contains(t, lines[3], "Ship: rejected")
+	assert.Contains(t, lines[3], "InStock: fail")
+	assert.Equal(t, "Paid --Refund--> Refunded", lines[4])
+	assert.Equal(t, "Refunded is final", lines[5])
+	assert.Equal(t, "Nope: unknown event", lines[6])
+}
+
+func Test_Diff(t *testing.T) {
+	out, code := fsmctl("", "diff", "testdata/order.json", "testdata/order.v2.json")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, strings.Join([]string{
+		"+ state Returned: not final",
+		"+ event Return",
+		"- context key Express: initial false (bool)",
+		"- transition Delivered on Refund: Refunded",
+		"+ transition Delivered on Return: Returned",
+		"+ transition Returned on Refund: Refunded",
+	}, "\n")+"\n", out)
+}
+
+func Test_Paths(t *testing.T) {
+	out, code := fsmctl("", "paths", "testdata/order.v2.json", "Refunded")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "Pay, Ship, Deliver, Return, Refund\nPay, Refund\n", out)
+
+	out, code = fsmctl("", "paths", "-max", "1", "testdata/order.json", "Refunded")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "'Refunded' can't be reached in 1 events")
+}
+
+func Test_Usage(t *testing.T) {
+	_, code := fsmctl("")
+	assert.Equal(t, 2, code)
+
+	out, code := fsmctl("", "explode")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out, "unknown command 'explode'")
+}
+
+func Test_Cover(t *testing.T) {
+	dir := t.TempDir()
+	profile := func(name string, taken int) string {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(fmt.Sprintf(
+			"fsmcover: light\nInactive\tActivate\t0\tActive\tfalse\t%d\t0\t0\tActivate\nActive\tDeactivate\t0\tInactive\tfalse\t0\t0\t0\tDeactivate\n",
+			taken,
+		)), 0644)
+		return path
+	}
+
+	html := filepath.Join(dir, "coverage.html")
+	merged := filepath.Join(dir, "merged.fsmcov")
+	out, code := fsmctl("", "cover", "-html", html, "-o", merged, profile("a.fsmcov", 1), profile("b.fsmcov", 2))
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "light: 1 of 2 transitions taken (50.0%), 0 of 0 guards passed and failed (100.0%)\n    not taken: Active --Deactivate--> Inactive\n", out)
+
+	report, err := ioutil.ReadFile(html)
+	assert.Nil(t, err)
+	assert.Contains(t, string(report), `<tr class="uncovered">`)
+
+	profiles, err := ioutil.ReadFile(merged)
+	assert.Nil(t, err)
+	assert.Contains(t, string(profiles), "Inactive\tActivate\t0\tActive\tfalse\t3\t0\t0\tActivate\n")
+}
@@ -0,0 +1,73 @@
+// Command fsmgen generates typed constants, names maps and constructors for
+// Machines, see ojkelly.dev/fsm/fsmgen. It's meant to be run by go generate.
+//
+//	//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -file order.json -name Order
+//	//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -consts counter.go -name Counter
+package main // import "ojkelly.dev/fsm/cmd/fsmgen"
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ojkelly.dev/fsm/fsmfile"
+	"ojkelly.dev/fsm/fsmgen"
+)
+
+func main() {
+	file := flag.String("file", "", "generate a Machine from this definition file")
+	consts := flag.String("consts", "", "generate names for the annotated const blocks in this Go file")
+	name := flag.String("name", "", "name of the Machine, used as a prefix for generated types and functions")
+	prefix := flag.String("prefix", "", "prefix for generated constants")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated file")
+	out := flag.String("out", "", "file to write, defaults to <name>_fsm.go")
+	flag.Parse()
+
+	if err := run(*file, *consts, *out, fsmgen.Options{
+		Package: *pkg,
+		Name:    *name,
+		Prefix:  *prefix,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "fsmgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(file string, consts string, out string, o fsmgen.Options) error {
+	if (file == "") == (consts == "") {
+		return fmt.Errorf("set one of -file or -consts")
+	}
+	if out == "" {
+		out = strings.ToLower(o.Name) + "_fsm.go"
+	}
+
+	var src []byte
+	if file != "" {
+		f, err := fsmfile.Load(file)
+		if err != nil {
+			return err
+		}
+
+		o.Source = filepath.Base(file)
+		src, err = fsmgen.FromFile(f, o)
+		if err != nil {
+			return fmt.Errorf("%s:\n%w", file, err)
+		}
+	} else {
+		input, err := ioutil.ReadFile(consts)
+		if err != nil {
+			return err
+		}
+
+		o.Source = filepath.Base(consts)
+		src, err = fsmgen.FromConsts(consts, input, o)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(out, src, 0644)
+}
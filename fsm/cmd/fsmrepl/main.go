@@ -0,0 +1,59 @@
+// Command fsmrepl steps through a definition file in the terminal, see
+// ojkelly.dev/fsm/repl for the commands.
+//
+//	fsmrepl order.json
+//	fsmrepl -script refund.txt order.json
+package main // import "ojkelly.dev/fsm/cmd/fsmrepl"
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"ojkelly.dev/fsm/fsmfile"
+	"ojkelly.dev/fsm/repl"
+)
+
+func main() {
+	script := flag.String("script", "", "replay a saved script before starting")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: fsmrepl [-script file] <file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := fsmfile.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	session, err := repl.New(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s:\n%s\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	if *script != "" {
+		r, err := os.Open(*script)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		err = session.Run(r, os.Stdout, false)
+		r.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := session.Run(os.Stdin, os.Stdout, true); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
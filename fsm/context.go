@@ -1,6 +1,10 @@
 package fsm
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"sort"
+)
 
 type ContextKey int
 
@@ -68,6 +72,7 @@ func (m *Machine) SetContext(key ContextKey, value interface{}) {
 
 		v.value = value
 		m.context[key] = v
+		m.logContextUpdate(context.Background(), "", key, value)
 	}
 }
 
@@ -78,13 +83,14 @@ func (m *Machine) SetContext(key ContextKey, value interface{}) {
 func (m *Machine) GetContext(key ContextKey) interface{} {
 	m.checkIfCreatedCorrectly()
 	m.contextChangeMtx.Lock()
-	defer m.contextChangeMtx.Unlock()
-
 	v, ok := m.context[key]
+	m.contextChangeMtx.Unlock()
 
 	if !ok {
+		// called without the lock, so the handler can read the Context
 		if m.errorHandler != nil {
-			m.errorHandler(m, m.state, m.state, MachineErrorGuardFail)
+			state, _ := m.current()
+			m.errorHandler(m, state, state, MachineErrorGuardFail)
 		}
 		return nil
 	}
@@ -96,6 +102,47 @@ func (m *Machine) GetContext(key ContextKey) interface{} {
 	return nil
 }
 
+// ContextValue is a snapshot of one key in the Machine's Context
+type ContextValue struct {
+	Key       ContextKey
+	Name      string
+	Protected bool
+	Value     interface{}
+}
+
+// ContextValues returns a snapshot of every key in the Context, sorted by key
+func (m *Machine) ContextValues() []ContextValue {
+	m.checkIfCreatedCorrectly()
+	m.contextChangeMtx.Lock()
+	defer m.contextChangeMtx.Unlock()
+
+	values := make([]ContextValue, 0, len(m.context))
+	for key, v := range m.context {
+		values = append(values, ContextValue{
+			Key:       key,
+			Name:      m.GetNameForContextKey(key),
+			Protected: v.protected,
+			Value:     v.value,
+		})
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values
+}
+
+// ContextKeyByName returns the ContextKey registered with a name in
+// m.AddContextKeyNames()
+func (m *Machine) ContextKeyByName(name string) (ContextKey, bool) {
+	m.checkIfCreatedCorrectly()
+
+	for k, n := range m.contextKeyNames {
+		if n == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
 // UpdateContext returned from UpdateContextHandler is a map of which
 // key, value pairs in Context to update
 type UpdateContext map[ContextKey]interface{}
@@ -111,7 +158,15 @@ type UpdateContextHandler func(
 )
 
 func (m *Machine) handleUpdateContext(t Transition, currentState State) {
+	span := m.startSpan(
+		SpanContextUpdate,
+		"ContextUpdate",
+		Attribute{Key: AttributeState, Value: m.GetNameForState(currentState)},
+		Attribute{Key: AttributeNextState, Value: m.GetNameForState(t.State)},
+	)
+
 	update, err := t.UpdateContext(m, currentState, t.State, TransitionEventEntry)
+	defer func() { span.End(err) }()
 
 	if err != nil {
 		m.handleError(err, MachineErrorUpdateContext)
@@ -119,6 +174,8 @@ func (m *Machine) handleUpdateContext(t Transition, currentState State) {
 
 	for key, value := range update {
 		if value != nil {
+			// readers like m.ContextValues() don't hold m.stateChangeMtx
+			m.contextChangeMtx.Lock()
 			v := m.context[key]
 			if v != nil {
 				m.context[key] = &contextMeta{
@@ -126,9 +183,13 @@ func (m *Machine) handleUpdateContext(t Transition, currentState State) {
 					protected: v.protected,
 					value:     value,
 				}
-			} else {
+			}
+			m.contextChangeMtx.Unlock()
+
+			if v == nil {
 				panic(fmt.Sprintf("[%s] You tried to update an unregistered ContextKey. Register it first in fsm.New()", m.id))
 			}
+			m.logContextUpdate(m.EventContext(), m.GetNameForState(currentState), key, value)
 		}
 	}
 }
@@ -0,0 +1,57 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+// run with -race, reading the Context mustn't race with Transitions
+// updating it
+func Test_ContextValuesWhileUpdating(t *testing.T) {
+	const (
+		Counting fsm.State = iota
+	)
+
+	const (
+		Increment fsm.Event = iota
+	)
+
+	const (
+		KeyCounter fsm.ContextKey = iota
+	)
+
+	m := fsm.New(
+		"counting",
+		500,
+		Counting,
+		fsm.Context{KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0}},
+		[]fsm.Event{Increment},
+		fsm.States{
+			Counting: fsm.StateNode{Events: fsm.EventToTransition{
+				Increment: fsm.Transition{
+					State: Counting,
+					UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+						return fsm.UpdateContext{KeyCounter: m.GetContext(KeyCounter).(int) + 1}, nil
+					},
+				},
+			}},
+		},
+		nil,
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 500; i++ {
+			m.ContextValues()
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		m.SendEvent(Increment)
+	}
+	<-done
+
+	assert.Equal(t, 500, m.GetContext(KeyCounter))
+}
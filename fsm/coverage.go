@@ -0,0 +1,43 @@
+package fsm
+
+// CoverageRecorder is told which Transitions and guards a Machine tried for
+// every registered Event, so tests can report which ones were never taken.
+// See the fsmcover package.
+//
+// Record is called while the Machine is locked, so it must not call back into
+// the Machine.
+type CoverageRecorder interface {
+	// Record the guards tried for an Event in state, and the Edge that was
+	// taken, or nil if the Event was rejected
+	Record(machineId string, state State, e Event, tried []GuardAttempt, taken *Edge)
+}
+
+// SetCoverageRecorder sets the CoverageRecorder, or removes it if r is nil
+func (m *Machine) SetCoverageRecorder(r CoverageRecorder) {
+	m.checkIfCreatedCorrectly()
+
+	m.hooksMtx.Lock()
+	defer m.hooksMtx.Unlock()
+
+	m.coverage = r
+}
+
+func (m *Machine) getCoverageRecorder() CoverageRecorder {
+	m.hooksMtx.RLock()
+	defer m.hooksMtx.RUnlock()
+
+	return m.coverage
+}
+
+func (m *Machine) recordCoverage(state State, e Event, tried []GuardAttempt, taken Edge, tErr *TransitionError) {
+	r := m.getCoverageRecorder()
+	if r == nil {
+		return
+	}
+
+	if tErr != nil {
+		r.Record(m.id, state, e, tried, nil)
+		return
+	}
+	r.Record(m.id, state, e, tried, &taken)
+}
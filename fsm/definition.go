@@ -0,0 +1,80 @@
+package fsm
+
+// Definition holds everything fsm.New() needs, so many Machines can be created
+// from the same States, Events and Context. It's used to spawn child
+// Machines, and by tools that inspect a Machine without running it.
+type Definition struct {
+	StateChangeChannelSize int
+	InitialState           State
+	Context                Context
+	Events                 []Event
+	States                 States
+	ErrorHandler           MachineErrorHandler
+
+	// Optional
+	Wildcards       EventToTransition
+	StateNames      StateNames
+	EventNames      EventNames
+	ContextKeyNames ContextKeyNames
+
+	// CoverageRecorder is set on every Machine created from the Definition
+	CoverageRecorder CoverageRecorder
+
+	// Invariants are added to every Machine created from the Definition
+	Invariants []Invariant
+}
+
+// New creates a Machine from the Definition
+func (d Definition) New(id string) *Machine {
+	m := New(
+		id,
+		d.StateChangeChannelSize,
+		d.InitialState,
+		d.Context,
+		d.Events,
+		d.States,
+		d.ErrorHandler,
+	)
+
+	if d.Wildcards != nil {
+		m.AddWildcardTransitions(d.Wildcards)
+	}
+	if d.StateNames != nil {
+		m.AddStateNames(d.StateNames)
+	}
+	if d.EventNames != nil {
+		m.AddEventNames(d.EventNames)
+	}
+	if d.ContextKeyNames != nil {
+		m.AddContextKeyNames(d.ContextKeyNames)
+	}
+	if d.CoverageRecorder != nil {
+		m.SetCoverageRecorder(d.CoverageRecorder)
+	}
+	if len(d.Invariants) > 0 {
+		m.AddInvariants(d.Invariants...)
+	}
+
+	return m
+}
+
+// Definition returns the Definition the Machine was created with, including
+// any names and wildcard Transitions added since
+func (m *Machine) Definition() Definition {
+	m.checkIfCreatedCorrectly()
+
+	return Definition{
+		StateChangeChannelSize: cap(m.stateChangeChannel),
+		InitialState:           m.initialState,
+		Context:                m.initialContext,
+		Events:                 m.eventList,
+		States:                 m.states,
+		ErrorHandler:           m.errorHandler,
+		Wildcards:              m.wildcards,
+		StateNames:             m.stateNames,
+		EventNames:             m.eventNames,
+		ContextKeyNames:        m.contextKeyNames,
+		CoverageRecorder:       m.getCoverageRecorder(),
+		Invariants:             m.invariants,
+	}
+}
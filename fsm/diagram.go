@@ -0,0 +1,184 @@
+package fsm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Edge is one Transition in a Definition's graph
+type Edge struct {
+	From  State
+	Event Event
+	To    State
+	// Candidate is the Transition's position in the list of candidates for
+	// the Event, or -1 for a wildcard Transition
+	Candidate  int
+	Transition Transition
+}
+
+// Edges returns every Transition in the Definition, sorted by State, Event
+// and candidate. Wildcard Transitions are included for each State that
+// doesn't handle the Event itself.
+func (d Definition) Edges() []Edge {
+	edges := []Edge{}
+	for _, s := range d.SortedStates() {
+		node := d.States[s]
+
+		events := map[Event]bool{}
+		for e := range node.Events {
+			events[e] = true
+		}
+		for e := range node.Choices {
+			events[e] = true
+		}
+		for e := range d.Wildcards {
+			events[e] = true
+		}
+
+		for _, e := range sortEvents(events) {
+			candidates := node.candidates(e)
+			if len(candidates) == 0 {
+				edges = append(edges, Edge{From: s, Event: e, To: d.Wildcards[e].State, Candidate: -1, Transition: d.Wildcards[e]})
+				continue
+			}
+			for i, t := range candidates {
+				edges = append(edges, Edge{From: s, Event: e, To: t.State, Candidate: i, Transition: t})
+			}
+		}
+	}
+	return edges
+}
+
+// SortedStates returns every State in the Definition, including ones that
+// are only Transitioned to, in order
+func (d Definition) SortedStates() []State {
+	seen := map[State]bool{d.InitialState: true}
+	for s, node := range d.States {
+		seen[s] = true
+		for _, t := range node.Events {
+			seen[t.State] = true
+		}
+		for _, candidates := range node.Choices {
+			for _, t := range candidates {
+				seen[t.State] = true
+			}
+		}
+	}
+	for _, t := range d.Wildcards {
+		seen[t.State] = true
+	}
+
+	states := make([]State, 0, len(seen))
+	for s := range seen {
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	return states
+}
+
+func sortEvents(events map[Event]bool) []Event {
+	sorted := make([]Event, 0, len(events))
+	for e := range events {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// EdgeLabel describes an Edge as "Event [guard]"
+func (d Definition) EdgeLabel(e Edge) string {
+	return edgeLabel(d.New("label"), e)
+}
+
+// edgeLabel uses m for its debug names
+func edgeLabel(m *Machine, e Edge) string {
+	label := m.GetNameForEvent(e.Event)
+
+	guards := []string{}
+	if e.Transition.Guard != nil {
+		guards = append(guards, "guard")
+	}
+	if e.Transition.Condition != nil {
+		guards = append(guards, e.Transition.Condition.Describe(m))
+	}
+	if len(guards) > 0 {
+		label = fmt.Sprintf("%s [%s]", label, strings.Join(guards, " && "))
+	}
+	return label
+}
+
+var notIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Mermaid renders the Definition as a Mermaid state diagram, with the
+// highlight States styled as current
+func (d Definition) Mermaid(highlight ...State) string {
+	m := d.New("mermaid")
+	id := func(s State) string {
+		return "s_" + notIdentifier.ReplaceAllString(m.GetNameForState(s), "_")
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "stateDiagram-v2")
+	for _, s := range d.SortedStates() {
+		fmt.Fprintf(b, "    state \"%s\" as %s\n", m.GetNameForState(s), id(s))
+	}
+	fmt.Fprintf(b, "    [*] --> %s\n", id(d.InitialState))
+	for _, e := range d.Edges() {
+		fmt.Fprintf(b, "    %s --> %s: %s\n", id(e.From), id(e.To), edgeLabel(m, e))
+	}
+	for _, s := range d.SortedStates() {
+		if d.States[s].Final {
+			fmt.Fprintf(b, "    %s --> [*]\n", id(s))
+		}
+	}
+	if len(highlight) > 0 {
+		fmt.Fprintln(b, "    classDef current fill:#ffcc66,stroke:#cc8800")
+		for _, s := range highlight {
+			fmt.Fprintf(b, "    class %s current\n", id(s))
+		}
+	}
+	return b.String()
+}
+
+// DOT renders the Definition as a Graphviz digraph, with the highlight
+// States filled in
+func (d Definition) DOT(highlight ...State) string {
+	m := d.New("dot")
+	current := map[State]bool{}
+	for _, s := range highlight {
+		current[s] = true
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "digraph fsm {")
+	fmt.Fprintln(b, "    rankdir=LR;")
+	fmt.Fprintln(b, "    __start [shape=point];")
+	for _, s := range d.SortedStates() {
+		attrs := []string{}
+		if d.States[s].Final {
+			attrs = append(attrs, "shape=doublecircle")
+		}
+		if current[s] {
+			attrs = append(attrs, "style=filled", `fillcolor="#ffcc66"`)
+		}
+		fmt.Fprintf(b, "    %q", m.GetNameForState(s))
+		if len(attrs) > 0 {
+			fmt.Fprintf(b, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(b, ";")
+	}
+	fmt.Fprintf(b, "    __start -> %q;\n", m.GetNameForState(d.InitialState))
+	for _, e := range d.Edges() {
+		fmt.Fprintf(
+			b,
+			"    %q -> %q [label=%q];\n",
+			m.GetNameForState(e.From),
+			m.GetNameForState(e.To),
+			edgeLabel(m, e),
+		)
+	}
+	fmt.Fprintln(b, "}")
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is how something differs between two Definitions
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// DefinitionChange is one difference found by Diff
+type DefinitionChange struct {
+	Kind ChangeKind
+	// Subject is what changed, "initial state", "state", "event",
+	// "transition" or "context key"
+	Subject string
+	// Name of the State, Event or ContextKey, or "State on Event" for a
+	// transition
+	Name string
+	Old  string
+	New  string
+}
+
+// String returns the change as a line, for example
+//
+//	~ transition Paid on Refund: Refunded -> Refunding
+func (c DefinitionChange) String() string {
+	line := c.Subject
+	if c.Name != "" {
+		line += " " + c.Name
+	}
+
+	switch c.Kind {
+	case ChangeAdded:
+		return withDetail("+ "+line, c.New)
+	case ChangeRemoved:
+		return withDetail("- "+line, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", line, c.Old, c.New)
+	}
+}
+
+func withDetail(line string, detail string) string {
+	if detail == "" {
+		return line
+	}
+	return line + ": " + detail
+}
+
+// Diff compares two Definitions by the names of their States, Events and
+// ContextKeys, so renumbering them isn't a change. Transitions are compared
+// by their targets and guards, as they'd be labelled in a diagram.
+func Diff(old Definition, new Definition) []DefinitionChange {
+	o := old.New("old")
+	n := new.New("new")
+	changes := []DefinitionChange{}
+
+	compare := func(subject string, before map[string]string, after map[string]string) {
+		names := map[string]bool{}
+		for k := range before {
+			names[k] = true
+		}
+		for k := range after {
+			names[k] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for k := range names {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, name := range sorted {
+			b, inBefore := before[name]
+			a, inAfter := after[name]
+			switch {
+			case !inBefore:
+				changes = append(changes, DefinitionChange{Kind: ChangeAdded, Subject: subject, Name: name, New: a})
+			case !inAfter:
+				changes = append(changes, DefinitionChange{Kind: ChangeRemoved, Subject: subject, Name: name, Old: b})
+			case a != b:
+				changes = append(changes, DefinitionChange{Kind: ChangeChanged, Subject: subject, Name: name, Old: b, New: a})
+			}
+		}
+	}
+
+	if o.GetNameForState(old.InitialState) != n.GetNameForState(new.InitialState) {
+		changes = append(changes, DefinitionChange{
+			Kind:    ChangeChanged,
+			Subject: "initial state",
+			Old:     o.GetNameForState(old.InitialState),
+			New:     n.GetNameForState(new.InitialState),
+		})
+	}
+
+	compare("state", describeStates(o, old), describeStates(n, new))
+	compare("event", describeEvents(o, old), describeEvents(n, new))
+	compare("context key", describeContext(o, old), describeContext(n, new))
+	compare("transition", describeTransitions(o, old), describeTransitions(n, new))
+
+	return changes
+}
+
+func describeStates(m *Machine, d Definition) map[string]string {
+	states := map[string]string{}
+	for _, s := range d.SortedStates() {
+		desc := "not final"
+		if d.States[s].Final {
+			desc = "final"
+		}
+		states[m.GetNameForState(s)] = desc
+	}
+	return states
+}
+
+func describeEvents(m *Machine, d Definition) map[string]string {
+	events := map[string]string{}
+	for _, e := range d.Events {
+		events[m.GetNameForEvent(e)] = ""
+	}
+	return events
+}
+
+func describeContext(m *Machine, d Definition) map[string]string {
+	keys := map[string]string{}
+	for k, meta := range d.Context {
+		desc := fmt.Sprintf("initial %v", meta.Inital)
+		if meta.Protected {
+			desc = "protected, " + desc
+		}
+		if meta.Inital != nil {
+			desc = fmt.Sprintf("%s (%s)", desc, reflect.TypeOf(meta.Inital))
+		}
+		keys[m.GetNameForContextKey(k)] = desc
+	}
+	return keys
+}
+
+func describeTransitions(m *Machine, d Definition) map[string]string {
+	targets := map[string][]string{}
+	for _, e := range d.Edges() {
+		name := fmt.Sprintf("%s on %s", m.GetNameForState(e.From), m.GetNameForEvent(e.Event))
+		target := m.GetNameForState(e.To)
+		if label := edgeLabel(m, e); label != m.GetNameForEvent(e.Event) {
+			target += strings.TrimPrefix(label, m.GetNameForEvent(e.Event))
+		}
+		if e.Candidate == -1 {
+			target += " (wildcard)"
+		}
+		targets[name] = append(targets[name], target)
+	}
+
+	transitions := map[string]string{}
+	for name, t := range targets {
+		transitions[name] = strings.Join(t, ", ")
+	}
+	return transitions
+}
@@ -0,0 +1,55 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_Diff(t *testing.T) {
+	old := fsm.Definition{
+		InitialState: 0,
+		Context:      fsm.Context{0: fsm.ContextMeta{Inital: 0}},
+		Events:       []fsm.Event{0, 1},
+		States: fsm.States{
+			0: fsm.StateNode{Events: fsm.EventToTransition{0: fsm.Transition{State: 1}}},
+			1: fsm.StateNode{Events: fsm.EventToTransition{1: fsm.Transition{State: 0}}},
+		},
+		StateNames:      fsm.StateNames{0: "Inactive", 1: "Active"},
+		EventNames:      fsm.EventNames{0: "Activate", 1: "Deactivate"},
+		ContextKeyNames: fsm.ContextKeyNames{0: "Counter"},
+	}
+
+	// renumbered, with Done added and Deactivate guarded
+	new := fsm.Definition{
+		InitialState: 1,
+		Context:      fsm.Context{0: fsm.ContextMeta{Protected: true, Inital: 0}},
+		Events:       []fsm.Event{0, 1, 2},
+		States: fsm.States{
+			1: fsm.StateNode{Events: fsm.EventToTransition{1: fsm.Transition{State: 0}}},
+			0: fsm.StateNode{Events: fsm.EventToTransition{
+				0: fsm.Transition{State: 1, Condition: fsm.NamedGuard("CanStop", nil)},
+				2: fsm.Transition{State: 2},
+			}},
+			2: fsm.StateNode{Final: true},
+		},
+		StateNames:      fsm.StateNames{1: "Inactive", 0: "Active", 2: "Done"},
+		EventNames:      fsm.EventNames{1: "Activate", 0: "Deactivate", 2: "Finish"},
+		ContextKeyNames: fsm.ContextKeyNames{0: "Counter"},
+	}
+
+	changes := []string{}
+	for _, c := range fsm.Diff(old, new) {
+		changes = append(changes, c.String())
+	}
+	assert.Equal(t, []string{
+		"+ state Done: final",
+		"+ event Finish",
+		"~ context key Counter: initial 0 (int) -> protected, initial 0 (int)",
+		"~ transition Active on Deactivate: Inactive -> Inactive [CanStop]",
+		"+ transition Active on Finish: Done",
+	}, changes)
+
+	assert.Empty(t, fsm.Diff(old, old))
+}
@@ -74,6 +74,85 @@ on Active if it exists. After that, the State will transition to Active.
 		// ... Active State defintion not shown, see full example
 	}
 
+Entry and Exit actions
+
+Handlers that should run no matter which Transition enters or leaves a State
+belong on the fsm.StateNode, rather than on every incoming Transition.
+
+	Active: fsm.StateNode{
+		OnEntry: []fsm.TransitionEventHandler{startTimer},
+		OnExit:  []fsm.TransitionEventHandler{stopTimer},
+		Success: logSuccess,
+		Events:  fsm.EventToTransition{
+			Deactivate: fsm.Transition{
+				State:   Inactive,
+				Actions: []fsm.TransitionEventHandler{flush},
+			},
+		},
+	}
+
+When a Transition passes its guard the handlers run in this order:
+
+	1. OnExit of the current State, then Transition.Exit
+	2. Transition.UpdateContext, then Transition.Actions
+	3. Transition.Entry, then OnEntry of the next State
+	4. the new State is committed, and Success of the previous State is called
+
+Exit and Entry handlers only run when the State actually changes, Actions and
+Success run on every Transition.
+
+Choosing between Transitions
+
+When the same Event should lead to different States depending on Context, use
+StateNode.Choices to list candidate Transitions in order. The first one whose
+Guard passes is taken, and a candidate without a Guard is the fallback.
+
+	Draft: fsm.StateNode{
+		Choices: fsm.EventToTransitions{
+			Submit: {
+				{State: Approved, Guard: amountUnder100},
+				{State: NeedsReview},
+			},
+		},
+	}
+
+If nothing matches, m.LastError() returns a *fsm.TransitionError listing the
+guards that were tried.
+
+Named Guards
+
+A Guard is an opaque function, so when it fails all we know is that it
+failed. A Condition has a name and can be combined with And, Or and Not, and
+evaluating it explains which part of it failed.
+
+	Activate: fsm.Transition{
+		State: Active,
+		Condition: fsm.And(
+			fsm.ContextIsTrue(KeyIsReady),
+			fsm.ContextInRange(KeyCounter, 0, 10),
+			fsm.NamedGuard("HasLicence", hasLicence),
+		),
+	}
+
+The explanation is on the fsm.TransitionError returned by m.LastError(),
+including when called from an error handler.
+
+Events for every State
+
+Events like Cancel or Reset that every State accepts can be added once, as
+wildcard Transitions. A State that handles the same Event itself wins.
+
+	machine.AddWildcardTransitions(fsm.EventToTransition{
+		Reset: fsm.Transition{State: Inactive},
+	})
+
+Events that the current State doesn't handle go to the error handler, and
+Events that were never registered panic. Either can be changed to ignore,
+error, panic or send the Event to a dead letter channel.
+
+	machine.SetUnhandledEventPolicy(fsm.EventPolicyIgnore)
+	machine.SetUnregisteredEventPolicy(fsm.EventPolicyDeadLetter)
+	machine.SetDeadLetterChannel(deadLetters)
 
 Adding debug information
 
@@ -92,12 +171,248 @@ We can send events like this:
 
 	machine.Event(Increment)
 
+To give up on an Event, for example in an RPC handler, send it with a
+context.Context. If ctx is done while waiting for another Event to finish,
+ctx.Err() is returned and nothing happened. If it's done after the Transition
+was committed, while the StateChange is waiting to be received,
+fsm.ErrStateChangeNotDelivered is returned, and the Machine is in the new
+State.
+
+	err := machine.SendEventContext(ctx, Increment)
+
+Guards and handlers can get the same ctx for their own cancellable calls.
+
+	ctx := m.EventContext()
+
 Updating Context
 
 And update context values like this:
 
 	machine.Set(KeyIsReady, true)
 
+Invoked Services
+
+Work that should only run while the Machine is in a State, like polling a job,
+can be declared as a Service. It's started with a context.Context when the
+State is entered, and that ctx is cancelled when the State is left. When it
+returns, its Done or Error Event is sent to the Machine, if they're set.
+
+	Polling: fsm.StateNode{
+		Invoke: []fsm.Service{{
+			Id:    "poll",
+			Run:   pollJob,
+			Done:  fsm.EventPtr(JobFinished),
+			Error: fsm.EventPtr(JobFailed),
+		}},
+		Events: fsm.EventToTransition{
+			JobFinished: fsm.Transition{State: Finished},
+			JobFailed:   fsm.Transition{State: Failed},
+		},
+	}
+
+The result is available with m.ServiceResult("poll"). Call m.Start() if the
+initial State invokes Services, and m.Stop() cancels any that are running.
+Stop waits for them, so a Service or handler that stops its own Machine calls
+go m.Stop().
+
+Spawning child Machines
+
+A Machine can spawn child Machines from an fsm.Definition, send them Events,
+and receive Events when they finish or fail.
+
+	worker := supervisor.Spawn("worker-1", workerDefinition, fsm.SpawnOptions{
+		Done:     fsm.EventPtr(WorkerDone),
+		Error:    fsm.EventPtr(WorkerFailed),
+		Strategy: fsm.SupervisionRestart,
+	})
+
+	supervisor.SendTo("worker-1", Start)
+
+Done, if it's set, is sent when the child enters a StateNode marked Final.
+When the child calls m.Error(), the parent restarts it, stops it, or
+escalates the error to its own error handler, and then sends Error if it's
+set. Stopping the parent stops all of its children.
+
+Machines reacting to each other
+
+Peer Machines can react to each other's Transitions through an fsm.Bus. When a
+published Machine enters a State, every matching Mapping sends an Event to its
+Target.
+
+	bus := fsm.NewBus(0)
+	bus.Publish(order)
+	bus.Subscribe(fsm.Mapping{
+		From:   order.Id(),
+		Enter:  Packed,
+		Target: inventory,
+		Event:  Reserve,
+	})
+
+StateChanges from each publisher are delivered in the order they happened, and
+an Event is never delivered back to a Machine that caused it.
+
+History
+
+To find out how a Machine got into its current State, keep a history of the
+last Events it handled. Each entry has the States, Event, time, how long was
+spent in the previous State, the guards that were tried and any error.
+
+	machine.EnableHistory(50)
+	entries := machine.History()
+
+	// or dump the history, in a panic message or a log line
+	fmt.Sprintf("%+v", machine)
+
+Logging
+
+Rather than logging from every handler, set a Logger to get a structured
+record of every Event received, guard result, Transition, Context update and
+MachineError. The fsmslog package adapts a *slog.Logger, with a level per
+record.
+
+	machine.SetLogger(fsmslog.New(slog.Default(), fsmslog.Levels{
+		fsm.LogGuard: slog.LevelInfo,
+	}))
+
+Tracing
+
+To see each step inside your distributed traces, set an Instrumentation. It's
+called when handling an Event starts, and for every guard, handler and
+Context update within it. The fsmotel package turns them into spans on a
+Tracer shaped like OpenTelemetry's, which a small wrapper can adapt.
+
+	machine.SetInstrumentation(fsmotel.New(tracer))
+
+Metrics
+
+For dashboards, set Metrics to count Transitions, guard rejections and
+MachineErrors, and to track how many Machines are in each State and for how
+long. The fsmprom package collects them across Machines and serves them to
+Prometheus.
+
+	machine.SetMetrics(collector)
+
+Inspecting running Machines
+
+To look at running Machines in staging, register them with an Inspector from
+the inspect package. It serves their State, Context, history and available
+Events, streams their StateChanges, and renders a diagram with the current
+State highlighted.
+
+	inspector := inspect.New(inspect.Options{})
+	inspector.Register(machine)
+	http.Handle("/fsm/", http.StripPrefix("/fsm", inspector))
+
+A Definition can also be rendered on its own, with d.Mermaid() or d.DOT(),
+and fsm.Diff() lists what changed between two of them.
+
+Planning
+
+To find out how to get a Machine to a State, for example to push an order to
+Refunded from a support tool, plan it. Guards are ignored unless they're
+evaluated against a copy of the Context, and a *fsm.PlanError explains why a
+State can't be reached.
+
+	plan, err := machine.PlanTo(Refunded, fsm.PlanOptions{EvaluateGuards: true})
+	for _, e := range plan.Events() {
+		machine.SendEvent(e)
+	}
+
+Versions
+
+To persist a Machine, save m.Snapshot(version), which has its State and
+Context by name, and create it again with d.Restore(). When a new version of
+the Definition renames or removes States, Migrations upgrade old Snapshots
+one version at a time. Every State in the old version has to map to one in
+the new version, Validate checks it, so it can run in a test.
+
+	migrations := fsm.Migrations{{
+		Version: 1,
+		From:    v1,
+		To:      v2,
+		States:  map[string]string{"Shipping": "InTransit"},
+	}}
+	snapshot, err := migrations.Migrate(saved)
+	machine, err := v2.Restore(id, snapshot)
+
+Definition files
+
+Definitions can be written as JSON files, read by the fsmfile package. The
+fsmctl command validates, renders, simulates and diffs them, and lists the
+Events that reach a State, for use in code review.
+
+	go run ojkelly.dev/fsm/cmd/fsmctl validate order.json
+	go run ojkelly.dev/fsm/cmd/fsmctl paths order.json Refunded
+
+The analysis package, which fsmctl validate uses, reports unreachable States,
+States that can't be left and aren't Final, and Events that never fire. It can
+also explore States together with their Context, evaluating guards.
+
+	report := analysis.Analyze(definition, analysis.Options{Product: true})
+
+To walk through a definition file with someone, fsmrepl sends Events by name,
+sets Context, switches stubbed guards off and on, undoes steps and saves them
+as a script to replay later.
+
+	go run ojkelly.dev/fsm/cmd/fsmrepl order.json
+
+Rather than keeping the const blocks and names maps in sync by hand, fsmgen
+generates them from go:generate. From a definition file it generates typed
+constants and a constructor, from annotated const blocks it generates the
+names maps. Either way, go generate fails if the definition is invalid.
+
+	//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -file order.json -name Order
+
+Testing
+
+The fsmtest package generates sequences of Events from a Definition to cover
+every State, Transition or pair of Transitions, and runs them against real
+Machines, reporting the shortest sequence that fails.
+
+	fsmtest.Run(t, definition, fsmtest.AllTransitions, fsmtest.Options{StubGuards: true})
+
+For tests written by hand, fsmtest.Record receives a Machine's StateChanges
+for you, and compares a transcript of them with a golden file.
+
+	r := fsmtest.Record(t, machine)
+	r.Send(Activate, Increment)
+	r.ExpectGolden("counter")
+
+To find out which Transitions the tests actually take, set a CoverageRecorder
+from the fsmcover package on the Definition. Its profiles can be merged across
+test packages, and reported as text or HTML with fsmctl cover.
+
+	definition.CoverageRecorder = fsmcover.New("order", definition)
+
+Invariants are rules about the State and Context that must always hold, like
+a Counter never going below zero. m.CheckInvariants() checks them, and in
+builds with the fsmdebug tag they're checked after every Transition, panicking
+with the history when one doesn't hold.
+
+	definition.Invariants = []fsm.Invariant{{Name: "Counter >= 0", Check: counterNotNegative}}
+
+	go test -tags fsmdebug ./...
+
+fsmtest.Fuzz turns Go 1.18 fuzz input into Events, and reports the shortest
+sequence that breaks an Invariant or makes a handler panic.
+
+Properties about whole runs, like every Requested eventually reaching
+Completed, are checked by the modelcheck package. It explores every State
+with its Context, and returns a Counterexample that can be replayed on a
+Machine.
+
+	results := modelcheck.Check(definition, modelcheck.Options{},
+		modelcheck.NeverBefore(modelcheck.In(Refunded), modelcheck.In(Paid)),
+	)
+
+After refactoring a Definition, the equivalence package checks that it still
+accepts the same Events in the same order, and returns the shortest sequence
+that tells the two apart if it doesn't. It can also merge States that behave
+the same.
+
+	if diff := equivalence.Equivalent(before, after); diff != nil {
+		t.Fatal(diff)
+	}
 
 */
 package fsm // import "ojkelly.dev/fsm"
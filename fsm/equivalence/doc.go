@@ -0,0 +1,26 @@
+/*
+Package equivalence checks that refactoring a Definition didn't change how
+it behaves, and merges States that behave the same.
+
+What can be observed of a Machine is which Events it accepts, and whether
+it's in a Final State. Two Definitions are language equivalent if every
+sequence of Events is accepted by both or neither, and ends in a Final State
+in both or neither. They're bisimilar if each State of one can be matched
+with a State of the other that accepts the same Events, leading to matched
+States again. Events are matched by name, as they may be numbered
+differently.
+
+	if diff := equivalence.Equivalent(before, after); diff != nil {
+		t.Fatal(diff)
+	}
+
+When a Definition has guards, or Choices, they're ignored and any of the
+candidate Transitions could be taken. For such Definitions bisimilarity is
+stricter than language equivalence. Otherwise they're the same.
+
+Minimize merges States that can't be told apart, for a Definition without
+guards, and returns which State each one was merged into.
+
+	minimal, merged, err := equivalence.Minimize(definition)
+*/
+package equivalence // import "ojkelly.dev/fsm/equivalence"
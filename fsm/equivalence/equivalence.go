@@ -0,0 +1,176 @@
+package equivalence
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ojkelly.dev/fsm"
+)
+
+// Difference is a sequence of Events that tells two Definitions apart
+type Difference struct {
+	// Events by name, as the Definitions may number them differently
+	Events []string
+
+	// A and B describe where each Definition is after the Events, or that
+	// it rejected the last one
+	A string
+	B string
+}
+
+func (d *Difference) Error() string {
+	after := "initially"
+	if len(d.Events) > 0 {
+		after = "after " + strings.Join(d.Events, ", ")
+	}
+	return fmt.Sprintf("%s: a is %s, b is %s", after, d.A, d.B)
+}
+
+// Equivalent checks that a and b accept the same sequences of Events, and
+// that the same sequences end in a Final State. It returns the shortest
+// sequence that tells them apart, or nil if there isn't one.
+//
+// When a Transition has a guard it may or may not be taken, so a sequence is
+// accepted if any of the Transitions it could take accept it.
+func Equivalent(a fsm.Definition, b fsm.Definition) *Difference {
+	la, lb := newLTS(a), newLTS(b)
+	events := alphabet(la, lb)
+
+	type pair struct {
+		a      []int
+		b      []int
+		events []string
+	}
+	key := func(p pair) string {
+		return fmt.Sprintf("%v %v", p.a, p.b)
+	}
+
+	start := pair{a: []int{la.index[a.InitialState]}, b: []int{lb.index[b.InitialState]}, events: []string{}}
+	seen := map[string]bool{key(start): true}
+	queue := []pair{start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if observe(la, p.a) != observe(lb, p.b) {
+			return &Difference{Events: p.events, A: la.describe(p.a), B: lb.describe(p.b)}
+		}
+		if len(p.a) == 0 {
+			continue
+		}
+
+		for _, e := range events {
+			next := pair{
+				a:      step(la, p.a, e),
+				b:      step(lb, p.b, e),
+				events: append(append([]string{}, p.events...), e),
+			}
+			if !seen[key(next)] {
+				seen[key(next)] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// observe what can be seen of a set of States: whether the Events leading
+// to them were accepted, and whether one of them is Final
+func observe(l *lts, states []int) [2]bool {
+	final := false
+	for _, s := range states {
+		final = final || l.final[s]
+	}
+	return [2]bool{len(states) > 0, final}
+}
+
+// step every State in a set with an Event
+func step(l *lts, states []int, e string) []int {
+	seen := map[int]bool{}
+	next := []int{}
+	for _, s := range states {
+		for _, to := range l.next[s][e] {
+			if !seen[to] {
+				seen[to] = true
+				next = append(next, to)
+			}
+		}
+	}
+	sort.Ints(next)
+	return next
+}
+
+// Bisimilar checks that the initial States of a and b can be matched, so
+// that they're both Final or not, and every Transition one can take with an
+// Event can be matched by the other, leading to matched States again. It
+// returns a sequence of Events that leads to States that can't be matched,
+// or nil if they're bisimilar.
+//
+// When neither Definition has guards, Bisimilar and Equivalent agree.
+func Bisimilar(a fsm.Definition, b fsm.Definition) *Difference {
+	la, lb := newLTS(a), newLTS(b)
+	events := alphabet(la, lb)
+	offset := len(la.final)
+
+	u := union(la, lb)
+	rounds := refinements(u, events)
+
+	p, q := la.index[a.InitialState], lb.index[b.InitialState]+offset
+	if rounds[len(rounds)-1][p] == rounds[len(rounds)-1][q] {
+		return nil
+	}
+
+	// follow the Events that split p and q, each one leads to States that
+	// were split a round earlier, until they're split by their Final flag
+	d := &Difference{Events: []string{}}
+	for {
+		k := 0
+		for rounds[k][p] == rounds[k][q] {
+			k++
+		}
+		if k == 0 {
+			d.A, d.B = la.describe([]int{p}), lb.describe([]int{q - offset})
+			return d
+		}
+
+		previous := rounds[k-1]
+		for _, e := range events {
+			ps, qs := classes(previous, u.next[p][e]), classes(previous, u.next[q][e])
+			if fmt.Sprint(ps) == fmt.Sprint(qs) {
+				continue
+			}
+			d.Events = append(d.Events, e)
+
+			if to, ok := unmatched(previous, u.next[p][e], qs); ok {
+				if len(qs) == 0 {
+					d.A, d.B = la.describe([]int{to}), "rejected"
+					return d
+				}
+				p, q = to, u.next[q][e][0]
+			} else {
+				to, _ := unmatched(previous, u.next[q][e], ps)
+				if len(ps) == 0 {
+					d.A, d.B = "rejected", lb.describe([]int{to - offset})
+					return d
+				}
+				p, q = u.next[p][e][0], to
+			}
+			break
+		}
+	}
+}
+
+// unmatched returns a State whose class isn't in others
+func unmatched(class []int, states []int, others []int) (int, bool) {
+	for _, s := range states {
+		found := false
+		for _, c := range others {
+			found = found || class[s] == c
+		}
+		if !found {
+			return s, true
+		}
+	}
+	return 0, false
+}
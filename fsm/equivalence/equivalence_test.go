@@ -0,0 +1,180 @@
+package equivalence_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/equivalence"
+)
+
+const (
+	Off fsm.State = iota
+	On
+	OffAgain
+	Broken
+)
+
+const (
+	Toggle fsm.Event = iota
+	Kick
+)
+
+// light has two Off States that can't be told apart
+func light() fsm.Definition {
+	return fsm.Definition{
+		InitialState: Off,
+		Events:       []fsm.Event{Toggle, Kick},
+		States: fsm.States{
+			Off: fsm.StateNode{Events: fsm.EventToTransition{
+				Toggle: fsm.Transition{State: On},
+			}},
+			On: fsm.StateNode{Events: fsm.EventToTransition{
+				Toggle: fsm.Transition{State: OffAgain},
+				Kick:   fsm.Transition{State: Broken},
+			}},
+			OffAgain: fsm.StateNode{Events: fsm.EventToTransition{
+				Toggle: fsm.Transition{State: On},
+			}},
+			Broken: fsm.StateNode{Final: true},
+		},
+		StateNames: fsm.StateNames{Off: "Off", On: "On", OffAgain: "OffAgain", Broken: "Broken"},
+		EventNames: fsm.EventNames{Toggle: "Toggle", Kick: "Kick"},
+	}
+}
+
+// refactored light, with the Events numbered the other way around
+func refactored(broken fsm.State) fsm.Definition {
+	const (
+		kick fsm.Event = iota
+		toggle
+	)
+	return fsm.Definition{
+		InitialState: Off,
+		Events:       []fsm.Event{kick, toggle},
+		States: fsm.States{
+			Off: fsm.StateNode{Events: fsm.EventToTransition{
+				toggle: fsm.Transition{State: On},
+			}},
+			On: fsm.StateNode{Events: fsm.EventToTransition{
+				toggle: fsm.Transition{State: Off},
+				kick:   fsm.Transition{State: broken},
+			}},
+			Broken: fsm.StateNode{Final: true},
+		},
+		StateNames: fsm.StateNames{Off: "Off", On: "On", Broken: "Broken"},
+		EventNames: fsm.EventNames{toggle: "Toggle", kick: "Kick"},
+	}
+}
+
+func Test_Minimize(t *testing.T) {
+	minimal, merged, err := equivalence.Minimize(light())
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[fsm.State]fsm.State{Off: Off, On: On, OffAgain: Off, Broken: Broken}, merged)
+	assert.Equal(t, []fsm.State{Off, On, Broken}, minimal.SortedStates())
+	assert.Equal(t, Off, minimal.States[On].Events[Toggle].State)
+	assert.Nil(t, equivalence.Equivalent(light(), minimal))
+	assert.Nil(t, equivalence.Bisimilar(light(), minimal))
+
+	minimal.StateChangeChannelSize = 10
+	m := minimal.New("minimal")
+	assert.True(t, m.SendEvent(Toggle))
+	assert.True(t, m.SendEvent(Toggle))
+	assert.Equal(t, Off, m.State())
+}
+
+func Test_MinimizeGuards(t *testing.T) {
+	d := light()
+	d.States[Off].Events[Toggle] = fsm.Transition{State: On, Guard: func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return true }}
+
+	_, _, err := equivalence.Minimize(d)
+	assert.EqualError(t, err, "equivalence: can't minimize a definition with guards, 'Off' --Toggle--> 'On' has one")
+}
+
+func Test_Equivalent(t *testing.T) {
+	assert.Nil(t, equivalence.Equivalent(light(), refactored(Broken)))
+	assert.Nil(t, equivalence.Bisimilar(light(), refactored(Broken)))
+
+	// kicking the light on now turns it off
+	diff := equivalence.Equivalent(light(), refactored(Off))
+	assert.Equal(t, []string{"Toggle", "Kick"}, diff.Events)
+	assert.EqualError(t, diff, "after Toggle, Kick: a is Broken (final), b is Off")
+
+	diff = equivalence.Bisimilar(light(), refactored(Off))
+	assert.EqualError(t, diff, "after Toggle, Kick: a is Broken (final), b is Off")
+
+	// and can't be kicked when off
+	diff = equivalence.Equivalent(refactored(Off), refactored(Broken))
+	assert.EqualError(t, diff, "after Toggle, Kick: a is Off, b is Broken (final)")
+
+	d := refactored(Broken)
+	d.States[Off] = fsm.StateNode{}
+	diff = equivalence.Equivalent(light(), d)
+	assert.EqualError(t, diff, "after Toggle: a is On, b is rejected")
+}
+
+const (
+	Start fsm.State = iota
+	Choose
+	Left
+	Right
+	End
+)
+
+const (
+	Go fsm.Event = iota
+	A
+	B
+)
+
+// late decides between A and B after Go, early decides when Go is sent
+func late() fsm.Definition {
+	return fsm.Definition{
+		InitialState: Start,
+		Events:       []fsm.Event{Go, A, B},
+		States: fsm.States{
+			Start: fsm.StateNode{Events: fsm.EventToTransition{
+				Go: fsm.Transition{State: Choose},
+			}},
+			Choose: fsm.StateNode{Events: fsm.EventToTransition{
+				A: fsm.Transition{State: End},
+				B: fsm.Transition{State: End},
+			}},
+			End: fsm.StateNode{Final: true},
+		},
+		StateNames: fsm.StateNames{Start: "Start", Choose: "Choose", End: "End"},
+		EventNames: fsm.EventNames{Go: "Go", A: "A", B: "B"},
+	}
+}
+
+func early() fsm.Definition {
+	return fsm.Definition{
+		InitialState: Start,
+		Events:       []fsm.Event{Go, A, B},
+		States: fsm.States{
+			Start: fsm.StateNode{Choices: fsm.EventToTransitions{
+				Go: {
+					{State: Left, Guard: func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return true }},
+					{State: Right},
+				},
+			}},
+			Left: fsm.StateNode{Events: fsm.EventToTransition{
+				A: fsm.Transition{State: End},
+			}},
+			Right: fsm.StateNode{Events: fsm.EventToTransition{
+				B: fsm.Transition{State: End},
+			}},
+			End: fsm.StateNode{Final: true},
+		},
+		StateNames: fsm.StateNames{Start: "Start", Left: "Left", Right: "Right", End: "End"},
+		EventNames: fsm.EventNames{Go: "Go", A: "A", B: "B"},
+	}
+}
+
+func Test_Bisimilar(t *testing.T) {
+	assert.Nil(t, equivalence.Equivalent(late(), early()))
+
+	diff := equivalence.Bisimilar(late(), early())
+	assert.EqualError(t, diff, "after Go, B: a is End (final), b is rejected")
+}
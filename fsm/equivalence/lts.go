@@ -0,0 +1,124 @@
+package equivalence
+
+import (
+	"sort"
+	"strings"
+
+	"ojkelly.dev/fsm"
+)
+
+// lts is a Definition as a labelled transition system, with Events by name
+type lts struct {
+	m      *fsm.Machine
+	states []fsm.State
+	index  map[fsm.State]int
+	final  []bool
+	// next lists the States each Event could lead to, from each State
+	next []map[string][]int
+}
+
+func newLTS(d fsm.Definition) *lts {
+	l := &lts{
+		m:      d.New("equivalence"),
+		states: d.SortedStates(),
+		index:  map[fsm.State]int{},
+	}
+	for i, s := range l.states {
+		l.index[s] = i
+		l.final = append(l.final, d.States[s].Final)
+		l.next = append(l.next, map[string][]int{})
+	}
+
+	registered := map[fsm.Event]bool{}
+	for _, e := range d.Events {
+		registered[e] = true
+	}
+
+	// every guarded candidate could be taken, but only the first one
+	// without a guard, as it's the fallback
+	fallback := map[[2]int]bool{}
+	for _, e := range d.Edges() {
+		if !registered[e.Event] {
+			continue
+		}
+		from := l.index[e.From]
+		name := l.m.GetNameForEvent(e.Event)
+		guarded := e.Transition.Guard != nil || e.Transition.Condition != nil
+
+		key := [2]int{from, int(e.Event)}
+		if !guarded {
+			if fallback[key] {
+				continue
+			}
+			fallback[key] = true
+		}
+		l.add(from, name, l.index[e.To])
+	}
+	return l
+}
+
+func (l *lts) add(from int, event string, to int) {
+	for _, existing := range l.next[from][event] {
+		if existing == to {
+			return
+		}
+	}
+	l.next[from][event] = append(l.next[from][event], to)
+	sort.Ints(l.next[from][event])
+}
+
+// describe a set of States, for a Difference
+func (l *lts) describe(states []int) string {
+	if len(states) == 0 {
+		return "rejected"
+	}
+
+	names := make([]string, 0, len(states))
+	final := false
+	for _, s := range states {
+		names = append(names, l.m.GetNameForState(l.states[s]))
+		final = final || l.final[s]
+	}
+	description := strings.Join(names, " or ")
+	if final {
+		description += " (final)"
+	}
+	return description
+}
+
+// alphabet of both, sorted
+func alphabet(a *lts, b *lts) []string {
+	seen := map[string]bool{}
+	for _, l := range []*lts{a, b} {
+		for _, next := range l.next {
+			for e := range next {
+				seen[e] = true
+			}
+		}
+	}
+
+	events := make([]string, 0, len(seen))
+	for e := range seen {
+		events = append(events, e)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// union of a and b, with b's States numbered after a's
+func union(a *lts, b *lts) *lts {
+	u := &lts{
+		final: append(append([]bool{}, a.final...), b.final...),
+		next:  append([]map[string][]int{}, a.next...),
+	}
+	for _, next := range b.next {
+		offset := map[string][]int{}
+		for e, states := range next {
+			for _, s := range states {
+				offset[e] = append(offset[e], s+len(a.final))
+			}
+		}
+		u.next = append(u.next, offset)
+	}
+	return u
+}
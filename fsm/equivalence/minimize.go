@@ -0,0 +1,167 @@
+package equivalence
+
+import (
+	"fmt"
+	"sort"
+
+	"ojkelly.dev/fsm"
+)
+
+// Minimize merges the States of a Definition that can't be told apart, as
+// they're both Final or not, and every Event leads to merged States again.
+// Each group of States is merged into its lowest State, which keeps its
+// StateNode and name, the others are removed.
+//
+// It returns the minimal Definition, and the State each State was merged
+// into. It returns an error if the Definition has a guard, as Minimize
+// doesn't know what they depend on.
+func Minimize(d fsm.Definition) (fsm.Definition, map[fsm.State]fsm.State, error) {
+	m := d.New("minimize")
+	for _, e := range d.Edges() {
+		if e.Transition.Guard != nil || e.Transition.Condition != nil {
+			return fsm.Definition{}, nil, fmt.Errorf(
+				"equivalence: can't minimize a definition with guards, '%s' --%s--> '%s' has one",
+				m.GetNameForState(e.From),
+				m.GetNameForEvent(e.Event),
+				m.GetNameForState(e.To),
+			)
+		}
+	}
+
+	l := newLTS(d)
+	class := refine(l, alphabet(l, l))
+
+	merged := map[fsm.State]fsm.State{}
+	representative := map[int]fsm.State{}
+	for i, s := range l.states {
+		if _, ok := representative[class[i]]; !ok {
+			representative[class[i]] = s
+		}
+		merged[s] = representative[class[i]]
+	}
+
+	minimal := d
+	minimal.InitialState = merged[d.InitialState]
+	minimal.States = fsm.States{}
+	for s, node := range d.States {
+		if merged[s] != s {
+			continue
+		}
+		minimal.States[s] = redirect(node, merged)
+	}
+
+	if d.Wildcards != nil {
+		minimal.Wildcards = fsm.EventToTransition{}
+		for e, t := range d.Wildcards {
+			t.State = merged[t.State]
+			minimal.Wildcards[e] = t
+		}
+	}
+	if d.StateNames != nil {
+		minimal.StateNames = fsm.StateNames{}
+		for s, name := range d.StateNames {
+			if merged[s] == s {
+				minimal.StateNames[s] = name
+			}
+		}
+	}
+	return minimal, merged, nil
+}
+
+// redirect a StateNode's Transitions to the States they were merged into
+func redirect(node fsm.StateNode, merged map[fsm.State]fsm.State) fsm.StateNode {
+	if node.Events != nil {
+		events := fsm.EventToTransition{}
+		for e, t := range node.Events {
+			t.State = merged[t.State]
+			events[e] = t
+		}
+		node.Events = events
+	}
+	if node.Choices != nil {
+		choices := fsm.EventToTransitions{}
+		for e, candidates := range node.Choices {
+			redirected := make([]fsm.Transition, 0, len(candidates))
+			for _, t := range candidates {
+				t.State = merged[t.State]
+				redirected = append(redirected, t)
+			}
+			choices[e] = redirected
+		}
+		node.Choices = choices
+	}
+	return node
+}
+
+// refine numbers the States of l so that States with the same number are
+// bisimilar, by splitting them on their Final flag, then on where each Event
+// leads, until nothing changes. When each Event leads to one State this is
+// Moore's minimization algorithm.
+func refine(l *lts, events []string) []int {
+	rounds := refinements(l, events)
+	return rounds[len(rounds)-1]
+}
+
+// refinements returns the numbering after each round, the first by Final
+// flag only
+func refinements(l *lts, events []string) [][]int {
+	class := make([]int, len(l.final))
+	for i := range l.final {
+		if l.final[i] {
+			class[i] = 1
+		}
+	}
+	class, count := renumber(l, class, func(i int) string {
+		return fmt.Sprintf("%d", class[i])
+	})
+	rounds := [][]int{class}
+
+	for {
+		previous := rounds[len(rounds)-1]
+		next, n := renumber(l, previous, func(i int) string {
+			return signature(l, previous, events, i)
+		})
+		if n == count {
+			return rounds
+		}
+		count = n
+		rounds = append(rounds, next)
+	}
+}
+
+// renumber States by their key, in order of first appearance
+func renumber(l *lts, class []int, key func(i int) string) ([]int, int) {
+	numbers := map[string]int{}
+	next := make([]int, len(l.final))
+	for i := range l.final {
+		k := key(i)
+		if _, ok := numbers[k]; !ok {
+			numbers[k] = len(numbers)
+		}
+		next[i] = numbers[k]
+	}
+	return next, len(numbers)
+}
+
+// signature of a State is its class, and the classes each Event can lead to
+func signature(l *lts, class []int, events []string, i int) string {
+	s := fmt.Sprintf("%d", class[i])
+	for _, e := range events {
+		s += fmt.Sprintf("|%v", classes(class, l.next[i][e]))
+	}
+	return s
+}
+
+// classes of some States, sorted without duplicates
+func classes(class []int, states []int) []int {
+	seen := map[int]bool{}
+	result := []int{}
+	for _, s := range states {
+		if !seen[class[s]] {
+			seen[class[s]] = true
+			result = append(result, class[s])
+		}
+	}
+	sort.Ints(result)
+	return result
+}
@@ -1,5 +1,10 @@
 package fsm
 
+import (
+	"fmt"
+	"strings"
+)
+
 type MachineError string
 
 const (
@@ -13,21 +18,39 @@ const (
 	// MachineErrorEventNotFoundForState occurs when you m.SendEvent() that
 	// the current State has no definition for.
 	MachineErrorEventNotFoundForState MachineError = "MachineErrorEventNotFoundForState"
+
+	// MachineErrorEventNotRegistered occurs when you m.SendEvent() an Event
+	// that was not registered in fsm.New()
+	MachineErrorEventNotRegistered MachineError = "MachineErrorEventNotRegistered"
+
+	// MachineErrorChild occurs when a spawned child fails with
+	// fsm.SupervisionEscalate
+	MachineErrorChild MachineError = "MachineErrorChild"
 )
 
+// MachineErrorHandler is called while the Event that caused the error is
+// being handled, so it must not send Events to the same Machine. Use
+// m.LastError() for details.
 type MachineErrorHandler func(m *Machine, current State, next State, machineError MachineError)
 
 func (m *Machine) handleError(e error, machineError MachineError) {
-	currentState := m.state
+	currentState, ctx := m.current()
 	node := m.states[currentState]
 
+	defer m.notifySupervisor(e)
+
+	m.logMachineError(ctx, currentState, e, machineError)
+	m.countMachineError(currentState, machineError)
+
 	handler := node.Error
 	if handler != nil {
 		handler(m, currentState, currentState, machineError)
 		return
 	}
 
-	m.errorHandler(m, currentState, currentState, machineError)
+	if m.errorHandler != nil {
+		m.errorHandler(m, currentState, currentState, machineError)
+	}
 }
 
 // Error is called by you when a state encounters an error
@@ -39,3 +62,88 @@ func (m *Machine) Error(e error) {
 
 	m.handleError(e, MachineErrorExternal)
 }
+
+// TransitionError describes why an Event sent to a Machine did not cause a
+// Transition.
+type TransitionError struct {
+	MachineId string
+	State     State
+	Event     Event
+	Kind      MachineError
+
+	// Tried lists every Guard that was evaluated, in order
+	Tried []GuardAttempt
+
+	m *Machine
+}
+
+// GuardAttempt is the outcome of one candidate Transition's Guard
+type GuardAttempt struct {
+	// Candidate is the index of the Transition in the list of candidates
+	Candidate int
+	// State the candidate would have transitioned to
+	State  State
+	Passed bool
+	// Result explains the outcome of the Transition's Condition, it's nil if
+	// the Transition only has a Guard
+	Result *GuardResult
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf(
+		"[%s] %s: event '%s' in state '%s'",
+		e.MachineId,
+		e.Kind,
+		e.m.GetNameForEvent(e.Event),
+		e.m.GetNameForState(e.State),
+	)
+
+	if len(e.Tried) == 0 {
+		return msg
+	}
+
+	tried := make([]string, 0, len(e.Tried))
+	for _, g := range e.Tried {
+		attempt := fmt.Sprintf(
+			"#%d -> '%s' passed=%t",
+			g.Candidate,
+			e.m.GetNameForState(g.State),
+			g.Passed,
+		)
+		if g.Result != nil {
+			attempt = fmt.Sprintf("%s (%s)", attempt, g.Result)
+		}
+		tried = append(tried, attempt)
+	}
+	return fmt.Sprintf("%s, guards tried: %s", msg, strings.Join(tried, ", "))
+}
+
+func (m *Machine) newTransitionError(s State, e Event, kind MachineError) *TransitionError {
+	return &TransitionError{
+		MachineId: m.id,
+		State:     s,
+		Event:     e,
+		Kind:      kind,
+		m:         m,
+	}
+}
+
+func (m *Machine) setLastError(e *TransitionError) {
+	m.lastErrorMtx.Lock()
+	defer m.lastErrorMtx.Unlock()
+	m.lastError = e
+}
+
+// LastError returns a *TransitionError describing why the most recent
+// m.SendEvent() failed, or nil if it succeeded. It's safe to call from inside
+// an error handler.
+func (m *Machine) LastError() error {
+	m.checkIfCreatedCorrectly()
+	m.lastErrorMtx.Lock()
+	defer m.lastErrorMtx.Unlock()
+
+	if m.lastError == nil {
+		return nil
+	}
+	return m.lastError
+}
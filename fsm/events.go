@@ -1,9 +1,21 @@
 package fsm
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 type Event int
 
+// EventPtr returns a pointer to e, for optional Events like Service.Done
+//
+//	Done: fsm.EventPtr(JobFinished),
+func EventPtr(e Event) *Event {
+	return &e
+}
+
 // EventNames are optional, but useful for debugging and will print out in
 // error messages
 type EventNames map[Event]string
@@ -29,77 +41,220 @@ func (m *Machine) GetNameForEvent(s Event) string {
 	return fmt.Sprintf("%d", s)
 }
 
+// EventByName returns the Event registered with a name in m.AddEventNames()
+func (m *Machine) EventByName(name string) (Event, bool) {
+	m.checkIfCreatedCorrectly()
+
+	for e, n := range m.eventNames {
+		if n == name {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
 type eventMap map[Event]bool
 type EventToTransition map[Event]Transition
 
+// EventToTransitions maps an Event to an ordered list of candidate
+// Transitions. The first candidate whose Guard passes is taken, a candidate
+// without a Guard is used as the fallback when none of the others pass.
+type EventToTransitions map[Event][]Transition
+
+// AddWildcardTransitions will add Transitions that apply in every State.
+// A State that defines a Transition for the same Event overrides it.
+// This can only be called once.
+func (m *Machine) AddWildcardTransitions(e EventToTransition) {
+	m.checkIfCreatedCorrectly()
+
+	if m.hasSetWildcards {
+		return
+	}
+
+	m.hasSetWildcards = true
+	m.wildcards = e
+}
+
 // SendEvent to the fsm.Machine to change States
 // blocks until the state transition has completed or failed
 func (m *Machine) SendEvent(e Event) bool {
+	return m.SendEventContext(context.Background(), e) == nil
+}
+
+// SendEventContext sends an Event like m.SendEvent(), but gives up with
+// ctx.Err() if ctx is done while waiting for another Event to finish, before
+// anything happened.
+//
+// If ctx is done after the Transition was committed, while its StateChange
+// is waiting to be received, the Machine is in the new State and the error
+// is ErrStateChangeNotDelivered, which also matches ctx.Err() with
+// errors.Is().
+//
+// It returns a *TransitionError if the Event didn't cause a Transition.
+// ctx is available to guards and handlers with m.EventContext().
+func (m *Machine) SendEventContext(ctx context.Context, e Event) error {
 	m.checkIfCreatedCorrectly()
 
-	m.stateChangeMtx.Lock()
+	if err := m.stateChangeMtx.LockContext(ctx); err != nil {
+		return err
+	}
 	defer m.stateChangeMtx.Unlock()
-	// validate event
-	found := m.events[e]
-	if !found {
-		panic(
-			fmt.Sprintf("[%s] fsm.Machine.Event() called with unregistered Event. All events must be registered in fsm.New()", m.id))
+
+	// ctx may have been done just as we got the lock
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
+	return m.handleEvent(ctx, e)
+}
+
+// handleEvent must be called while holding m.stateChangeMtx
+func (m *Machine) handleEvent(ctx context.Context, e Event) (err error) {
+	m.setEventContext(ctx)
+	defer m.setEventContext(nil)
+
 	// get current state node
 	currentState := m.state
 
-	node := m.states[currentState]
+	now := time.Now()
+	entry := HistoryEntry{
+		From:       currentState,
+		To:         currentState,
+		Event:      e,
+		Time:       now,
+		InPrevious: now.Sub(m.enteredAt),
+	}
+	defer func() {
+		entry.Err = err
+		m.recordHistory(entry)
+	}()
 
-	var transition Transition
-	var foundEvent bool
+	m.logEventReceived(ctx, currentState, e)
 
-	for ev, t := range node.Events {
-		if e == ev {
-			transition = t
-			foundEvent = true
-		}
-	}
+	span := m.startSpan(
+		SpanEvent,
+		"Event "+m.GetNameForEvent(e),
+		Attribute{Key: AttributeState, Value: m.GetNameForState(currentState)},
+		Attribute{Key: AttributeEvent, Value: m.GetNameForEvent(e)},
+	)
+	defer func() {
+		span.SetAttributes(Attribute{Key: AttributeNextState, Value: m.GetNameForState(entry.To)})
+		span.End(err)
+	}()
 
-	if !foundEvent {
-		if m.errorHandler != nil {
-			m.errorHandler(m, currentState, currentState, MachineErrorEventNotFoundForState)
+	// validate event
+	found := m.events[e]
+	if !found {
+		tErr := m.newTransitionError(currentState, e, MachineErrorEventNotRegistered)
+		m.setLastError(tErr)
+		m.logMachineError(ctx, currentState, tErr, tErr.Kind)
+		m.countMachineError(currentState, tErr.Kind)
+		if err := m.applyEventPolicy(ctx, m.unregisteredEventPolicy, tErr); err != nil {
+			return err
 		}
-		return false
+		return tErr
 	}
 
-	if transition.Guard != nil {
-		guardPass := transition.Guard(m, currentState, transition.State)
+	node := m.states[currentState]
+
+	taken, tried, tErr := m.selectTransition(currentState, node, e)
+	m.setLastError(tErr)
+	entry.Guards = tried
+	m.logGuards(ctx, currentState, e, tried)
+	m.countGuards(currentState, e, tried)
+	m.recordCoverage(currentState, e, tried, taken, tErr)
 
-		if !guardPass {
+	if tErr != nil {
+		m.logMachineError(ctx, currentState, tErr, tErr.Kind)
+		m.countMachineError(currentState, tErr.Kind)
+
+		if tErr.Kind != MachineErrorEventNotFoundForState {
 			if m.errorHandler != nil {
-				m.errorHandler(m, currentState, currentState, MachineErrorGuardFail)
+				m.errorHandler(m, currentState, currentState, tErr.Kind)
 			}
-			return false
+			return tErr
 		}
-	}
 
-	// Transition hooks only run if the state changes to a different one
-	if currentState != transition.State {
-		if ev := node.Events[e]; ev.Exit != nil {
-			ev.Exit(m, currentState, transition.State, TransitionEventEntry)
+		if err := m.applyEventPolicy(ctx, m.unhandledEventPolicy, tErr); err != nil {
+			return err
 		}
+		return tErr
+	}
 
-		if transition.Entry != nil {
-			transition.Entry(m, currentState, transition.State, TransitionEventEntry)
-		}
+	m.runTransition(currentState, node, taken.Transition)
+	entry.To = taken.To
+	entry.Committed = true
+	m.assertInvariants(taken.To, e)
+
+	change := StateChange{
+		From:  currentState,
+		To:    taken.To,
+		Cause: e,
 	}
 
-	if transition.UpdateContext != nil {
-		m.handleUpdateContext(transition, currentState)
+	m.logTransition(ctx, change)
+	m.countTransition(change, entry.InPrevious)
+
+	for _, o := range m.observers {
+		o.observer(ctx, change)
 	}
 
-	m.state = transition.State
+	select {
+	case m.stateChangeChannel <- change:
+	case <-ctx.Done():
+		return undeliveredError{ctx.Err()}
+	}
+	return nil
+}
 
-	m.stateChangeChannel <- StateChange{
-		From:  currentState,
-		To:    transition.State,
-		Cause: e,
+// ErrStateChangeNotDelivered is returned by m.SendEventContext() when the
+// Transition was committed, but ctx was done before its StateChange was
+// received
+var ErrStateChangeNotDelivered = errors.New("fsm: the transition was committed, but its StateChange wasn't received")
+
+// undeliveredError matches both ErrStateChangeNotDelivered and ctx.Err()
+type undeliveredError struct {
+	err error
+}
+
+func (e undeliveredError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrStateChangeNotDelivered, e.err)
+}
+
+func (e undeliveredError) Unwrap() error {
+	return e.err
+}
+
+func (e undeliveredError) Is(target error) bool {
+	return target == ErrStateChangeNotDelivered
+}
+
+// EventContext returns the context.Context passed to m.SendEventContext()
+// for the Event being handled. It's meant to be called from guards and
+// handlers, outside of them it returns context.Background().
+func (m *Machine) EventContext() context.Context {
+	_, ctx := m.current()
+	return ctx
+}
+
+// setEventContext must be called while holding m.stateChangeMtx, it returns
+// the previous one
+func (m *Machine) setEventContext(ctx context.Context) context.Context {
+	m.currentMtx.Lock()
+	defer m.currentMtx.Unlock()
+
+	prev := m.eventCtx
+	m.eventCtx = ctx
+	return prev
+}
+
+// current State and Event context.Context, without holding m.stateChangeMtx
+func (m *Machine) current() (State, context.Context) {
+	m.currentMtx.Lock()
+	defer m.currentMtx.Unlock()
+
+	if m.eventCtx == nil {
+		return m.state, context.Background()
 	}
-	return true
+	return m.state, m.eventCtx
 }
@@ -0,0 +1,136 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+type ctxKey struct{}
+
+func Test_SendEventContext(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Busy
+	)
+
+	const (
+		Work fsm.Event = iota
+		Rest
+	)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var guardValue interface{}
+
+	// unbuffered, so every StateChange waits until it's received
+	m := fsm.New(
+		"sendEventContext",
+		0,
+		Idle,
+		fsm.Context{},
+		[]fsm.Event{Work, Rest},
+		fsm.States{
+			Idle: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Work: fsm.Transition{
+						State: Busy,
+						Guard: func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+							guardValue = m.EventContext().Value(ctxKey{})
+							return true
+						},
+						Actions: []fsm.TransitionEventHandler{
+							func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+								close(entered)
+								<-release
+							},
+						},
+					},
+				},
+			},
+			Busy: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Rest: fsm.Transition{State: Idle},
+				},
+			},
+		},
+		nil,
+	)
+
+	workCtx := context.WithValue(context.Background(), ctxKey{}, "request")
+	workDone := make(chan error)
+	go func() {
+		workDone <- m.SendEventContext(workCtx, Work)
+	}()
+	<-entered
+
+	// waiting for the lock
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := m.SendEventContext(ctx, Rest)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, fsm.ErrStateChangeNotDelivered), "nothing happened")
+
+	close(release)
+	receive := <-m.StateChangeChannel()
+	assert.Equal(t, Busy, receive.To)
+	assert.NoError(t, <-workDone)
+	assert.Equal(t, "request", guardValue)
+
+	// waiting for the StateChange to be received
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = m.SendEventContext(ctx, Rest)
+	assert.True(t, errors.Is(err, fsm.ErrStateChangeNotDelivered))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, Idle, m.State(), "the transition was still committed")
+
+	// already cancelled
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.True(t, errors.Is(m.SendEventContext(ctx, Work), context.Canceled))
+	assert.Equal(t, Idle, m.State())
+}
+
+func Test_ErrorWhileSending(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Busy
+	)
+	const Toggle fsm.Event = 0
+
+	m := fsm.New(
+		"concurrent",
+		100,
+		Idle,
+		fsm.Context{},
+		[]fsm.Event{Toggle},
+		fsm.States{
+			Idle: fsm.StateNode{Events: fsm.EventToTransition{Toggle: fsm.Transition{State: Busy}}},
+			Busy: fsm.StateNode{Events: fsm.EventToTransition{Toggle: fsm.Transition{State: Idle}}},
+		},
+		nil,
+	)
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+
+	// m.Error() can be called from any goroutine
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			m.Error(errors.New("external"))
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, m.SendEventContext(context.WithValue(context.Background(), ctxKey{}, i), Toggle))
+	}
+	<-done
+	m.Stop()
+}
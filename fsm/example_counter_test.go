@@ -49,8 +49,9 @@ func Example_counter() {
 
 	// Event Handlers ------------------------------------------------------------
 	errorHandler := func(m *fsm.Machine, current fsm.State, next fsm.State, machineError fsm.MachineError) {
+		// It's called while the Machine handles an Event, so it can't send
+		// one to the same Machine
 		fmt.Println("Error: Left", m.GetNameForState(current), "entered", m.GetNameForState(next), machineError)
-		m.SendEvent(Deactivate)
 	}
 
 	logEvent := func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
@@ -225,6 +226,7 @@ func Example_counter() {
 	// Output:
 	// Initial state is 0
 	// StateName for the current state is Inactive
+	// Error: Left Inactive entered Inactive MachineErrorEventNotFoundForState
 	// Before incrementing Counter it's 0
 	// Before setting the KeyIsReady it's false
 	// Context KeyIsReady is true
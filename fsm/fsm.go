@@ -1,27 +1,90 @@
 package fsm
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // Machine
 type Machine struct {
 	// internals
 	initWithNew        bool
+	initialState       State
+	initialContext     Context
+	eventList          []Event
 	context            internalContext
 	events             eventMap
 	state              State
+	enteredAt          time.Time
 	states             States
 	id                 string
 	errorHandler       MachineErrorHandler
 	stateChangeChannel chan StateChange
 
-	stateChangeMtx   sync.Mutex
+	stateChangeMtx   lock
 	contextChangeMtx sync.Mutex
 
 	lockPublicSet sync.Mutex
 
+	// currentMtx guards state and eventCtx, which are written while holding
+	// both locks, so m.Error() can read them from any goroutine
+	currentMtx sync.Mutex
+	// eventCtx is the context.Context of the Event being handled
+	eventCtx context.Context
+
+	lastErrorMtx sync.Mutex
+	lastError    *TransitionError
+
+	hasSetWildcards bool
+	wildcards       EventToTransition
+
+	unhandledEventPolicy    EventPolicy
+	unregisteredEventPolicy EventPolicy
+	deadLetterChannel       chan<- DeadLetter
+
+	// lifetime is cancelled by m.Stop()
+	lifetime       context.Context
+	cancelLifetime context.CancelFunc
+	started        bool
+	stopped        bool
+
+	services       []*runningService
+	servicesWg     sync.WaitGroup
+	serviceMtx     sync.Mutex
+	serviceResults map[string]ServiceResult
+
+	// hooksMtx guards the optional hooks below
+	hooksMtx        sync.RWMutex
+	logger          Logger
+	instrumentation Instrumentation
+	metrics         Metrics
+	coverage        CoverageRecorder
+
+	// invariants are checked after every Transition in debug builds
+	invariants []Invariant
+
+	historyMtx sync.Mutex
+	history    *historyBuffer
+
+	// observers are called with every committed StateChange
+	observers      []registeredObserver
+	nextObserverId int
+
+	parent      *Machine
+	failures    chan<- error
+	children    map[string]*child
+	childrenMtx sync.Mutex
+	childrenWg  sync.WaitGroup
+
+	// mailbox holds Events from children, in the order they were sent. It's
+	// read from once the first child is spawned.
+	mailboxMtx     sync.Mutex
+	mailbox        []Event
+	mailboxReady   chan struct{}
+	mailboxStarted bool
+
 	// Debug / Optional
 	hasSetStateNames      bool
 	stateNames            StateNames
@@ -59,15 +122,32 @@ func New(
 		}
 	}
 
+	lifetime, cancelLifetime := newLifetime()
+
 	return &Machine{
 		initWithNew:        true,
+		initialState:       initialState,
+		initialContext:     context,
+		eventList:          events,
 		events:             eMap,
 		state:              initialState,
+		enteredAt:          time.Now(),
 		states:             states,
+		errorHandler:       errorHandler,
 		context:            cMap,
 		id:                 id,
 		stateChangeChannel: make(chan StateChange, stateChangeChannelSize),
+		stateChangeMtx:     newLock(),
+		lifetime:           lifetime,
+		cancelLifetime:     cancelLifetime,
+		serviceResults:     map[string]ServiceResult{},
+		children:           map[string]*child{},
+		mailboxReady:       make(chan struct{}, 1),
 		lockPublicSet:      sync.Mutex{},
+		// Events no State handles go to the error handler, and unregistered
+		// Events are a developer error
+		unhandledEventPolicy:    EventPolicyError,
+		unregisteredEventPolicy: EventPolicyPanic,
 		// Debug
 		hasSetStateNames:      false,
 		stateNames:            StateNames{},
@@ -94,11 +174,102 @@ func (m *Machine) checkIfCreatedCorrectly() {
 	}
 }
 
+// Start enters the initial State, running its OnEntry handlers and starting
+// its invoked Services. It's only needed if the initial State has either, and
+// does nothing if called more than once.
+func (m *Machine) Start() {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	if m.started || m.stopped {
+		return
+	}
+	m.started = true
+
+	node := m.states[m.state]
+	m.runHandlers("OnEntry", node.OnEntry, m.state, m.state, TransitionEventEntry)
+	m.startServices(m.state, node)
+}
+
 // Stop the machine, and signalt to all watching for changes, that it's done
+//
+// Any running Services are cancelled, and Stop waits for them to return.
+// Spawned children are stopped too. Calling Stop more than once does nothing.
+//
+// Stop waits for the Event being handled, and for Services, so calling it
+// from a guard, handler or Service of the same Machine deadlocks. Call
+// go m.Stop() there instead, it stops the Machine once they've returned.
 func (m *Machine) Stop() {
-	m.stateChangeChannel <- StateChange{
+	m.checkIfCreatedCorrectly()
+
+	// cancelled first, so a Service waiting to send its StateChange lets go
+	// of the lock
+	m.cancelLifetime()
+
+	m.stateChangeMtx.Lock()
+	if m.stopped {
+		m.stateChangeMtx.Unlock()
+		return
+	}
+	m.stopped = true
+	m.stopServices()
+	m.countStopped()
+	last := StateChange{
 		From:   m.state,
 		To:     m.state,
 		IsLast: true,
 	}
+	for _, o := range m.observers {
+		o.observer(context.Background(), last)
+	}
+	m.stateChangeMtx.Unlock()
+
+	m.servicesWg.Wait()
+	m.stopChildren()
+
+	m.stateChangeChannel <- last
+}
+
+func newLifetime() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+// lock is a mutex that can be given up on while waiting for it
+type lock chan struct{}
+
+func newLock() lock {
+	return make(lock, 1)
+}
+
+func (l lock) Lock() {
+	l <- struct{}{}
+}
+
+// LockContext waits for the lock, unless ctx is done first
+func (l lock) LockContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryLock takes the lock only if it's free
+func (l lock) TryLock() bool {
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l lock) Unlock() {
+	<-l
 }
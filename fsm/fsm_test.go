@@ -1,217 +1,582 @@
 package fsm_test
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmtest"
 )
 
-// func Test_Counter(t *testing.T) {
-
-// 	// States a machine can be in ------------------------------------------------
-// 	const (
-// 		// the first value (your zero-value) should be the default
-// 		Inactive fsm.State = iota
-// 		Active
-// 	)
-
-// 	stateNames := fsm.StateNames{
-// 		Inactive: "Inactive",
-// 		Active:   "Active",
-// 	}
-
-// 	// Events that can change state ----------------------------------------------
-// 	const (
-// 		Activate fsm.Event = iota
-// 		Deactivate
-// 		Increment
-// 		Decrement
-// 	)
-
-// 	eventNames := fsm.EventNames{
-// 		Activate:   "Activate",
-// 		Deactivate: "Deactivate",
-// 		Increment:  "Increment",
-// 		Decrement:  "Decrement",
-// 	}
-
-// 	// ContextKeys for storing extra state ---------------------------------------
-// 	const (
-// 		KeyCounter fsm.ContextKey = iota
-// 		KeyIsReady
-// 	)
-
-// 	contextKeyNames := fsm.ContextKeyNames{
-// 		KeyCounter: "Counter",
-// 		KeyIsReady: "IsReady",
-// 	}
-
-// 	// Event Handlers ------------------------------------------------------------
-// 	errorHandler := func(m *fsm.Machine, current fsm.State, next fsm.State, machineError fsm.MachineError) {
-// 		fmt.Println("Error: Left", m.GetNameForState(current), "entered", m.GetNameForState(next), machineError)
-// 		m.SendEvent(Deactivate)
-// 	}
-
-// 	successHandler := func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
-// 		fmt.Println("Success: Left", m.GetNameForState(current), "entered", m.GetNameForState(next))
-// 	}
-
-// 	logEvent := func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
-// 		fmt.Println("Left", m.GetNameForState(current), "entered", m.GetNameForState(next), event)
-// 	}
-
-// 	guardActive := func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
-// 		if v := m.GetContext(KeyIsReady); v != nil {
-// 			ready := v.(bool)
-// 			return ready
-// 		}
-// 		return false
-// 	}
-
-// 	// Machine Creator -----------------------------------------------------------
-// 	machine := fsm.New(
-// 		// machine ID
-// 		"counterExample",
-// 		1,
-// 		// initial state
-// 		Inactive,
-
-// 		// Context Keys
-// 		fsm.Context{
-// 			KeyIsReady: fsm.ContextMeta{
-// 				Protected: false,
-// 				Inital:    false,
-// 			},
-// 			KeyCounter: fsm.ContextMeta{
-// 				Protected: false, // this can only be changed by events
-// 				Inital:    0,
-// 			},
-// 		},
-
-// 		// Possible events
-// 		[]fsm.Event{Activate, Deactivate, Increment, Decrement},
-
-// 		// State Map
-// 		fsm.States{
-// 			// Inactive state
-// 			Inactive: fsm.StateNode{
-// 				// Events that Inactive will transition on
-// 				Events: fsm.EventToTransition{
-// 					// On Activate event tranisition to Active
-// 					Activate: fsm.Transition{
-// 						State: Active,
-// 						Guard: guardActive,
-// 						Entry: logEvent,
-// 						Exit: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
-// 							m.SetContext(KeyIsReady, false)
-// 						},
-// 					},
-// 				},
-// 			},
-
-// 			// Active state
-// 			Active: fsm.StateNode{
-// 				Error:   errorHandler,
-// 				Success: successHandler,
-// 				Events: fsm.EventToTransition{
-// 					Increment: fsm.Transition{
-// 						State: Active,
-// 						UpdateContext: func(
-// 							m *fsm.Machine,
-// 							current fsm.State,
-// 							next fsm.State,
-// 							event fsm.TransitionEvent,
-// 						) (
-// 							update fsm.UpdateContext,
-// 							err error,
-// 						) {
-// 							update = fsm.UpdateContext{}
-// 							if v := m.GetContext(KeyCounter); v != nil {
-// 								update[KeyCounter] = v.(int) + 1
-// 							} else {
-// 								err = fmt.Errorf("Unable to update KeyCounter")
-// 							}
-// 							return
-// 						},
-// 					},
-// 					Deactivate: fsm.Transition{
-// 						State: Inactive,
-// 					},
-// 				},
-// 			},
-// 		},
-// 		// Machine level handlers
-// 		errorHandler,
-// 	)
-
-// 	// This is optional, but useful if you want to enhance your logging, or
-// 	// you have a large number of states
-// 	machine.AddStateNames(stateNames)
-// 	machine.AddEventNames(eventNames)
-// 	machine.AddContextKeyNames(contextKeyNames)
-
-// 	assert.Equal(t, machine.State(), Inactive, "initial state should be Inactive")
-
-// 	assert.Equal(
-// 		t,
-// 		machine.GetNameForState(machine.State()),
-// 		stateNames[Inactive],
-// 		"our state names were set correctly",
-// 	)
-
-// 	// Try to increment - nothing should happen as we're Inactive at the moment
-// 	machine.SendEvent(Increment)
-// 	counter := machine.GetContext(KeyCounter).(int)
-// 	assert.Equal(t, counter, 0, "our counter should still be 0")
-
-// 	isReady := machine.GetContext(KeyIsReady).(bool)
-// 	assert.Equal(t, isReady, false, "The machine shouldn't be ready yet")
-
-// 	machine.SetContext(KeyIsReady, true)
-// 	isReady = machine.GetContext(KeyIsReady).(bool)
-
-// 	assert.Equal(t, isReady, true, "context isReady should be true now")
-
-// 	machine.SendEvent(Activate)
-// 	assert.Equal(t, machine.State(), Active, "machine state should be Active")
-
-// 	// Increment 3 times
-// 	machine.SendEvent(Increment)
-// 	machine.SendEvent(Increment)
-// 	machine.SendEvent(Increment)
-
-// 	counter = machine.GetContext(KeyCounter).(int)
-// 	assert.Equal(t, counter, 3, "our counter should now be 3")
-
-// 	machine.SendEvent(Deactivate)
-// 	assert.Equal(t, machine.State(), Inactive, "machine state should now be Inactive")
-
-// }
+func Test_Counter(t *testing.T) {
+
+	// States a machine can be in ------------------------------------------------
+	const (
+		// the first value (your zero-value) should be the default
+		Inactive fsm.State = iota
+		Active
+	)
+
+	stateNames := fsm.StateNames{
+		Inactive: "Inactive",
+		Active:   "Active",
+	}
+
+	// Events that can change state ----------------------------------------------
+	const (
+		Activate fsm.Event = iota
+		Deactivate
+		Increment
+		Decrement
+	)
+
+	eventNames := fsm.EventNames{
+		Activate:   "Activate",
+		Deactivate: "Deactivate",
+		Increment:  "Increment",
+		Decrement:  "Decrement",
+	}
+
+	// ContextKeys for storing extra state ---------------------------------------
+	const (
+		KeyCounter fsm.ContextKey = iota
+		KeyIsReady
+	)
+
+	contextKeyNames := fsm.ContextKeyNames{
+		KeyCounter: "Counter",
+		KeyIsReady: "IsReady",
+	}
+
+	// Event Handlers ------------------------------------------------------------
+	guardActive := func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+		if v := m.GetContext(KeyIsReady); v != nil {
+			ready := v.(bool)
+			return ready
+		}
+		return false
+	}
+
+	// Machine Creator -----------------------------------------------------------
+	machine := fsm.New(
+		// machine ID
+		"counterExample",
+		1,
+		// initial state
+		Inactive,
+
+		// Context Keys
+		fsm.Context{
+			KeyIsReady: fsm.ContextMeta{
+				Protected: false,
+				Inital:    false,
+			},
+			KeyCounter: fsm.ContextMeta{
+				Protected: true, // this can only be changed by events
+				Inital:    0,
+			},
+		},
+
+		// Possible events
+		[]fsm.Event{Activate, Deactivate, Increment, Decrement},
+
+		// State Map
+		fsm.States{
+			// Inactive state
+			Inactive: fsm.StateNode{
+				// Events that Inactive will transition on
+				Events: fsm.EventToTransition{
+					// On Activate event tranisition to Active
+					Activate: fsm.Transition{
+						State: Active,
+						Guard: guardActive,
+						Exit: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+							m.SetContext(KeyIsReady, false)
+						},
+					},
+				},
+			},
+
+			// Active state
+			Active: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Increment: fsm.Transition{
+						State: Active,
+						UpdateContext: func(
+							m *fsm.Machine,
+							current fsm.State,
+							next fsm.State,
+							event fsm.TransitionEvent,
+						) (
+							update fsm.UpdateContext,
+							err error,
+						) {
+							update = fsm.UpdateContext{}
+							if v := m.GetContext(KeyCounter); v != nil {
+								update[KeyCounter] = v.(int) + 1
+							} else {
+								err = fmt.Errorf("Unable to update KeyCounter")
+							}
+							return
+						},
+					},
+					Deactivate: fsm.Transition{
+						State: Inactive,
+					},
+				},
+			},
+		},
+		// Machine level handlers
+		nil,
+	)
+
+	// This is optional, but useful if you want to enhance your logging, or
+	// you have a large number of states
+	machine.AddStateNames(stateNames)
+	machine.AddEventNames(eventNames)
+	machine.AddContextKeyNames(contextKeyNames)
+
+	r := fsmtest.Record(t, machine)
+	assert.Equal(t, machine.State(), Inactive, "initial state should be Inactive")
+
+	// nothing should happen as we're Inactive at the moment
+	r.ExpectRejected(Increment, fsm.MachineErrorEventNotFoundForState)
+	r.ExpectContext(KeyCounter, 0)
+
+	// and we can't Activate until we're ready
+	r.ExpectRejected(Activate, fsm.MachineErrorGuardFail)
+
+	r.Set(KeyIsReady, true)
+	r.Send(Activate, Increment, Increment, Increment, Deactivate)
+
+	r.ExpectTransitions(
+		fsm.StateChange{From: Inactive, To: Active, Cause: Activate},
+		fsm.StateChange{From: Active, To: Active, Cause: Increment},
+		fsm.StateChange{From: Active, To: Active, Cause: Increment},
+		fsm.StateChange{From: Active, To: Active, Cause: Increment},
+		fsm.StateChange{From: Active, To: Inactive, Cause: Deactivate},
+	)
+	r.ExpectContext(KeyCounter, 3)
+	r.ExpectContext(KeyIsReady, false)
+	r.ExpectGolden("counter")
+}
 
 func Test_TCPMachine(t *testing.T) {
+	// A mock TCP handshake
+	const (
+		NoConnection fsm.State = iota
+		Listening
+		SynReceived
+		ConnectionEstablished
+	)
+
+	const (
+		Listen fsm.Event = iota
+		ReceiveSYN
+		ReceiveACK
+		Close
+	)
+
+	const (
+		RemoteIp fsm.ContextKey = iota
+	)
+
+	hasRemoteIp := func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+		return m.GetContext(RemoteIp) != ""
+	}
+
+	machine := fsm.Definition{
+		InitialState: NoConnection,
+		Context:      fsm.Context{RemoteIp: fsm.ContextMeta{Inital: ""}},
+		Events:       []fsm.Event{Listen, ReceiveSYN, ReceiveACK, Close},
+		States: fsm.States{
+			NoConnection: fsm.StateNode{Events: fsm.EventToTransition{
+				Listen: fsm.Transition{State: Listening},
+			}},
+			Listening: fsm.StateNode{Events: fsm.EventToTransition{
+				ReceiveSYN: fsm.Transition{State: SynReceived, Guard: hasRemoteIp},
+			}},
+			SynReceived: fsm.StateNode{Events: fsm.EventToTransition{
+				ReceiveACK: fsm.Transition{State: ConnectionEstablished},
+			}},
+			ConnectionEstablished: fsm.StateNode{},
+		},
+		Wildcards: fsm.EventToTransition{
+			Close: fsm.Transition{State: NoConnection},
+		},
+		StateNames: fsm.StateNames{
+			NoConnection:          "NoConnection",
+			Listening:             "Listening",
+			SynReceived:           "SynReceived",
+			ConnectionEstablished: "ConnectionEstablished",
+		},
+		EventNames: fsm.EventNames{
+			Listen:     "Listen",
+			ReceiveSYN: "ReceiveSYN",
+			ReceiveACK: "ReceiveACK",
+			Close:      "Close",
+		},
+		ContextKeyNames: fsm.ContextKeyNames{RemoteIp: "RemoteIp"},
+	}.New("tcp")
+
+	r := fsmtest.Record(t, machine)
+
+	r.Send(Listen)
+	r.ExpectRejected(ReceiveSYN, fsm.MachineErrorGuardFail)
+	r.ExpectRejected(ReceiveACK, fsm.MachineErrorEventNotFoundForState)
+
+	r.Set(RemoteIp, "0.0.0.0")
+	r.Send(ReceiveSYN, ReceiveACK)
+	assert.Equal(t, ConnectionEstablished, machine.State())
+
+	r.Send(Close)
+	r.ExpectTransitions(
+		fsm.StateChange{From: NoConnection, To: Listening, Cause: Listen},
+		fsm.StateChange{From: Listening, To: SynReceived, Cause: ReceiveSYN},
+		fsm.StateChange{From: SynReceived, To: ConnectionEstablished, Cause: ReceiveACK},
+		fsm.StateChange{From: ConnectionEstablished, To: NoConnection, Cause: Close},
+	)
+	r.ExpectGolden("tcp")
+}
 
-	// All the possible states for this FSM
-	// const (
-	// 	NoConnection          fsm.State = "NoConnection"
-	// 	ConnectionEstablished fsm.State = "ConnectionEstablished"
-	// 	// A mock TCP handshake
-	// 	SendACK    fsm.State = "SendACK"
-	// 	RecieveSYN fsm.State = "RecieveSYN"
-	// 	SendSYNACK fsm.State = "SendSYNACK"
-	// )
-
-	// const (
-	// 	RemoteIp fsm.ContextKey = "RemoteIp"
-	// )
+func Test_EntryExitOrder(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Running
+	)
+
+	const (
+		Start fsm.Event = iota
+		Tick
+	)
+
+	calls := []string{}
+	record := func(name string) fsm.TransitionEventHandler {
+		return func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+			calls = append(calls, fmt.Sprintf("%s:%s", name, event))
+		}
+	}
+
+	machine := fsm.New(
+		"entryExitOrder",
+		10,
+		Idle,
+		fsm.Context{},
+		[]fsm.Event{Start, Tick},
+		fsm.States{
+			Idle: fsm.StateNode{
+				OnExit:  []fsm.TransitionEventHandler{record("Idle.OnExit")},
+				Success: record("Idle.Success"),
+				Events: fsm.EventToTransition{
+					Start: fsm.Transition{
+						State:   Running,
+						Exit:    record("Start.Exit"),
+						Entry:   record("Start.Entry"),
+						Actions: []fsm.TransitionEventHandler{record("Start.Action")},
+					},
+				},
+			},
+			Running: fsm.StateNode{
+				OnEntry: []fsm.TransitionEventHandler{record("Running.OnEntry")},
+				OnExit:  []fsm.TransitionEventHandler{record("Running.OnExit")},
+				Success: record("Running.Success"),
+				Events: fsm.EventToTransition{
+					Tick: fsm.Transition{
+						State:   Running,
+						Actions: []fsm.TransitionEventHandler{record("Tick.Action")},
+					},
+				},
+			},
+		},
+		nil,
+	)
+
+	assert.True(t, machine.SendEvent(Start))
+	assert.Equal(t, []string{
+		"Idle.OnExit:Exit",
+		"Start.Exit:Exit",
+		"Start.Action:Action",
+		"Start.Entry:Entry",
+		"Running.OnEntry:Entry",
+		"Idle.Success:Success",
+	}, calls)
+
+	// self transitions skip Entry and Exit handlers
+	calls = []string{}
+	assert.True(t, machine.SendEvent(Tick))
+	assert.Equal(t, []string{
+		"Tick.Action:Action",
+		"Running.Success:Success",
+	}, calls)
+}
 
-	// machine := fsm.New(NoConnection,
-	// 	fsm.NewContextKeys(
-	// 		RemoteIp,
-	// 	))
+func Test_Choices(t *testing.T) {
+	const (
+		Draft fsm.State = iota
+		Approved
+		NeedsReview
+	)
+
+	const (
+		Submit fsm.Event = iota
+	)
+
+	const (
+		KeyAmount fsm.ContextKey = iota
+	)
+
+	amountUnder := func(limit int) fsm.Guard {
+		return func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+			return m.GetContext(KeyAmount).(int) < limit
+		}
+	}
+
+	newMachine := func(amount int, fallback bool) *fsm.Machine {
+		candidates := []fsm.Transition{
+			{State: Approved, Guard: amountUnder(100)},
+			{State: NeedsReview, Guard: amountUnder(1000)},
+		}
+		if fallback {
+			// the fallback is tried last, wherever it is in the list
+			candidates = append([]fsm.Transition{{State: Draft}}, candidates...)
+		}
+
+		m := fsm.New(
+			"choices",
+			1,
+			Draft,
+			fsm.Context{KeyAmount: fsm.ContextMeta{Inital: amount}},
+			[]fsm.Event{Submit},
+			fsm.States{
+				Draft: fsm.StateNode{
+					Choices: fsm.EventToTransitions{Submit: candidates},
+				},
+			},
+			nil,
+		)
+		m.AddStateNames(fsm.StateNames{Draft: "Draft", Approved: "Approved", NeedsReview: "NeedsReview"})
+		m.AddEventNames(fsm.EventNames{Submit: "Submit"})
+		go func() {
+			for range m.StateChangeChannel() {
+			}
+		}()
+		return m
+	}
+
+	m := newMachine(50, false)
+	assert.True(t, m.SendEvent(Submit))
+	assert.Equal(t, Approved, m.State())
+	assert.Nil(t, m.LastError())
+
+	m = newMachine(500, false)
+	assert.True(t, m.SendEvent(Submit))
+	assert.Equal(t, NeedsReview, m.State())
+
+	m = newMachine(5000, true)
+	assert.True(t, m.SendEvent(Submit))
+	assert.Equal(t, Draft, m.State())
+
+	m = newMachine(5000, false)
+	assert.False(t, m.SendEvent(Submit))
+
+	var tErr *fsm.TransitionError
+	assert.True(t, errors.As(m.LastError(), &tErr))
+	assert.Equal(t, fsm.MachineErrorGuardFail, tErr.Kind)
+	assert.Equal(t, []fsm.GuardAttempt{
+		{Candidate: 0, State: Approved, Passed: false},
+		{Candidate: 1, State: NeedsReview, Passed: false},
+	}, tErr.Tried)
+	assert.Equal(
+		t,
+		"[choices] MachineErrorGuardFail: event 'Submit' in state 'Draft', guards tried: #0 -> 'Approved' passed=false, #1 -> 'NeedsReview' passed=false",
+		tErr.Error(),
+	)
+}
 
-	// machine.Set("remoteIp", "0.0.0.0")
+func Test_WildcardsAndEventPolicy(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Running
+		Stopped
+	)
+
+	const (
+		Start fsm.Event = iota
+		Cancel
+		Pause
+		Unregistered
+	)
+
+	m := fsm.New(
+		"wildcards",
+		10,
+		Idle,
+		fsm.Context{},
+		[]fsm.Event{Start, Cancel, Pause},
+		fsm.States{
+			Idle: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Start: fsm.Transition{State: Running},
+					// overrides the wildcard
+					Cancel: fsm.Transition{State: Idle},
+				},
+			},
+			Running: fsm.StateNode{},
+		},
+		nil,
+	)
+	m.AddWildcardTransitions(fsm.EventToTransition{
+		Cancel: fsm.Transition{State: Stopped},
+	})
+
+	assert.True(t, m.SendEvent(Cancel))
+	assert.Equal(t, Idle, m.State())
+
+	assert.True(t, m.SendEvent(Start))
+	assert.True(t, m.SendEvent(Cancel))
+	assert.Equal(t, Stopped, m.State())
+
+	assert.Panics(t, func() { m.SendEvent(Unregistered) })
+
+	m.SetUnregisteredEventPolicy(fsm.EventPolicyIgnore)
+	assert.False(t, m.SendEvent(Unregistered))
+
+	deadLetters := make(chan fsm.DeadLetter, 1)
+	m.SetDeadLetterChannel(deadLetters)
+	m.SetUnhandledEventPolicy(fsm.EventPolicyDeadLetter)
+	assert.False(t, m.SendEvent(Pause))
+	assert.Equal(t, fsm.DeadLetter{
+		MachineId: "wildcards",
+		State:     Stopped,
+		Event:     Pause,
+		Reason:    fsm.MachineErrorEventNotFoundForState,
+	}, <-deadLetters)
+
+	m.SetUnhandledEventPolicy(fsm.EventPolicyPanic)
+	assert.Panics(t, func() { m.SendEvent(Pause) })
+}
 
-	// fmt.Printf("Current State: %v \n", machine.State())
+func Test_ErrorHandler(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Running
+	)
+
+	const (
+		Start fsm.Event = iota
+		Pause
+	)
+
+	type call struct {
+		current fsm.State
+		next    fsm.State
+		kind    fsm.MachineError
+		err     error
+	}
+	calls := []call{}
+
+	m := fsm.New(
+		"errors",
+		10,
+		Idle,
+		fsm.Context{},
+		[]fsm.Event{Start, Pause},
+		fsm.States{
+			Idle: fsm.StateNode{Events: fsm.EventToTransition{
+				Start: fsm.Transition{State: Running},
+			}},
+		},
+		func(m *fsm.Machine, current fsm.State, next fsm.State, kind fsm.MachineError) {
+			calls = append(calls, call{current, next, kind, m.LastError()})
+		},
+	)
+
+	// EventPolicyError is the default
+	assert.False(t, m.SendEvent(Pause))
+	assert.Len(t, calls, 1)
+	assert.Equal(t, Idle, calls[0].current)
+	assert.Equal(t, Idle, calls[0].next)
+	assert.Equal(t, fsm.MachineErrorEventNotFoundForState, calls[0].kind)
+
+	var tErr *fsm.TransitionError
+	assert.True(t, errors.As(calls[0].err, &tErr))
+	assert.Equal(t, Pause, tErr.Event)
+	assert.Equal(t, fsm.MachineErrorEventNotFoundForState, tErr.Kind)
+
+	m.SetUnhandledEventPolicy(fsm.EventPolicyIgnore)
+	assert.False(t, m.SendEvent(Pause))
+	assert.Len(t, calls, 1)
+
+	m.Error(errors.New("external"))
+	assert.Len(t, calls, 2)
+	assert.Equal(t, fsm.MachineErrorExternal, calls[1].kind)
+}
 
-	// fmt.Printf("end of test %v\n", machine.Get("remoteIp").(string))
+func Test_ErrorHandlerReadsContext(t *testing.T) {
+	const (
+		KeyReady fsm.ContextKey = iota
+		KeyMissing
+	)
+
+	kinds := []fsm.MachineError{}
+	m := fsm.New(
+		"errors",
+		10,
+		0,
+		fsm.Context{KeyReady: fsm.ContextMeta{Inital: true}},
+		[]fsm.Event{},
+		fsm.States{0: fsm.StateNode{}},
+		func(m *fsm.Machine, current fsm.State, next fsm.State, kind fsm.MachineError) {
+			// the Context isn't locked while the handler runs
+			if m.GetContext(KeyReady) == true {
+				kinds = append(kinds, kind)
+			}
+		},
+	)
+
+	assert.Nil(t, m.GetContext(KeyMissing))
+	assert.Equal(t, []fsm.MachineError{fsm.MachineErrorGuardFail}, kinds)
+}
 
-	// t.Fail()
+func Test_Watch(t *testing.T) {
+	const (
+		Idle fsm.State = iota
+		Running
+	)
+
+	const (
+		Start fsm.Event = iota
+	)
+
+	m := fsm.New("watch", 10, Idle, fsm.Context{}, []fsm.Event{Start}, fsm.States{
+		Idle: fsm.StateNode{Events: fsm.EventToTransition{Start: fsm.Transition{State: Running}}},
+	}, nil)
+
+	changes, cancel := m.Watch(10)
+	defer cancel()
+	assert.True(t, m.SendEvent(Start))
+	assert.Equal(t, fsm.StateChange{From: Idle, To: Running, Cause: Start}, <-changes)
+
+	// stopping sends the last StateChange, and closes the channel
+	m.Stop()
+	assert.Equal(t, fsm.StateChange{From: Running, To: Running, IsLast: true}, <-changes)
+	_, ok := <-changes
+	assert.False(t, ok)
+
+	// even when it's full
+	m = fsm.New("watch", 10, Idle, fsm.Context{}, []fsm.Event{Start}, fsm.States{}, nil)
+	changes, cancel = m.Watch(0)
+	defer cancel()
+	m.Stop()
+	_, ok = <-changes
+	assert.False(t, ok)
+
+	// or already stopped
+	changes, cancel = m.Watch(1)
+	defer cancel()
+	assert.True(t, (<-changes).IsLast)
+	_, ok = <-changes
+	assert.False(t, ok)
 }
@@ -0,0 +1,25 @@
+/*
+Package fsmcover records which Transitions and guards tests take, the way
+go test -cover records which lines run.
+
+Set a Recorder on the Definition, or on each Machine, and write its profile
+when the tests are done.
+
+	var coverage = fsmcover.New("order", orderDefinition)
+
+	func TestMain(m *testing.M) {
+		orderDefinition.CoverageRecorder = coverage
+		code := m.Run()
+		if err := coverage.WriteFile("order.fsmcov"); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(code)
+	}
+
+Profiles from several test packages are merged by name, and reported as text
+or HTML listing the Transitions that were never taken, and the guards that
+didn't both pass and fail.
+
+	go run ojkelly.dev/fsm/cmd/fsmctl cover -html coverage.html api/order.fsmcov worker/order.fsmcov
+*/
+package fsmcover // import "ojkelly.dev/fsm/fsmcover"
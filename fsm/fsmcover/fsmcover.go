@@ -0,0 +1,120 @@
+package fsmcover
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+
+	"ojkelly.dev/fsm"
+)
+
+// Point is one candidate Transition in a Definition, and how often it was
+// tried
+type Point struct {
+	State string
+	Event string
+	// Candidate is the position of the Transition in the candidates for the
+	// Event, or -1 for a wildcard
+	Candidate int
+	Target    string
+	// Label describes the Transition and its guards, as in a diagram
+	Label   string
+	Guarded bool
+
+	Taken       int
+	GuardPassed int
+	GuardFailed int
+}
+
+// Covered is true if the Transition was taken, and its guards, if any, both
+// passed and failed
+func (p Point) Covered() bool {
+	return p.Taken > 0 && (!p.Guarded || (p.GuardPassed > 0 && p.GuardFailed > 0))
+}
+
+type pointKey struct {
+	state     fsm.State
+	event     fsm.Event
+	candidate int
+}
+
+// Recorder is an fsm.CoverageRecorder for every Machine created from a
+// Definition
+type Recorder struct {
+	name string
+
+	mtx    sync.Mutex
+	points []Point
+	index  map[pointKey]int
+}
+
+// New Recorder for the Transitions in d. name identifies the Definition when
+// profiles are merged.
+func New(name string, d fsm.Definition) *Recorder {
+	r := &Recorder{name: name, index: map[pointKey]int{}}
+
+	m := d.New("fsmcover")
+	for _, e := range d.Edges() {
+		r.index[pointKey{e.From, e.Event, e.Candidate}] = len(r.points)
+		r.points = append(r.points, Point{
+			State:     m.GetNameForState(e.From),
+			Event:     m.GetNameForEvent(e.Event),
+			Candidate: e.Candidate,
+			Target:    m.GetNameForState(e.To),
+			Label:     d.EdgeLabel(e),
+			Guarded:   e.Transition.Guard != nil || e.Transition.Condition != nil,
+		})
+	}
+	return r
+}
+
+// point for a candidate, which is a wildcard if the State has no candidates
+// of its own
+func (r *Recorder) point(state fsm.State, e fsm.Event, candidate int) *Point {
+	if i, ok := r.index[pointKey{state, e, candidate}]; ok {
+		return &r.points[i]
+	}
+	if i, ok := r.index[pointKey{state, e, -1}]; ok {
+		return &r.points[i]
+	}
+	return nil
+}
+
+// Record implements fsm.CoverageRecorder
+func (r *Recorder) Record(machineId string, state fsm.State, e fsm.Event, tried []fsm.GuardAttempt, taken *fsm.Edge) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for _, g := range tried {
+		if p := r.point(state, e, g.Candidate); p != nil {
+			if g.Passed {
+				p.GuardPassed++
+			} else {
+				p.GuardFailed++
+			}
+		}
+	}
+
+	if taken != nil {
+		if p := r.point(state, e, taken.Candidate); p != nil {
+			p.Taken++
+		}
+	}
+}
+
+// Profile returns a copy of what's been recorded so far
+func (r *Recorder) Profile() *Profile {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return &Profile{Name: r.name, Points: append([]Point{}, r.points...)}
+}
+
+// WriteFile writes the profile to path
+func (r *Recorder) WriteFile(path string) error {
+	b := &bytes.Buffer{}
+	if err := WriteProfiles(b, r.Profile()); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
@@ -0,0 +1,126 @@
+package fsmcover_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmcover"
+)
+
+const (
+	Inactive fsm.State = iota
+	Active
+	Off
+)
+
+const (
+	Activate fsm.Event = iota
+	Deactivate
+	Reset
+)
+
+const (
+	KeyIsReady fsm.ContextKey = iota
+)
+
+func definition() fsm.Definition {
+	return fsm.Definition{
+		StateChangeChannelSize: 1,
+		InitialState:           Inactive,
+		Context:                fsm.Context{KeyIsReady: fsm.ContextMeta{Inital: false}},
+		Events:                 []fsm.Event{Activate, Deactivate, Reset},
+		States: fsm.States{
+			Inactive: fsm.StateNode{Events: fsm.EventToTransition{
+				Activate: fsm.Transition{State: Active, Condition: fsm.ContextIsTrue(KeyIsReady)},
+			}},
+			Active: fsm.StateNode{Events: fsm.EventToTransition{
+				Deactivate: fsm.Transition{State: Inactive},
+			}},
+			Off: fsm.StateNode{Final: true},
+		},
+		Wildcards:       fsm.EventToTransition{Reset: fsm.Transition{State: Off}},
+		StateNames:      fsm.StateNames{Inactive: "Inactive", Active: "Active", Off: "Off"},
+		EventNames:      fsm.EventNames{Activate: "Activate", Deactivate: "Deactivate", Reset: "Reset"},
+		ContextKeyNames: fsm.ContextKeyNames{KeyIsReady: "IsReady"},
+	}
+}
+
+func newMachine(d fsm.Definition) *fsm.Machine {
+	m := d.New("light")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	return m
+}
+
+func Test_Recorder(t *testing.T) {
+	// two test packages, each with their own Recorder
+	d := definition()
+	first := fsmcover.New("light", d)
+	d.CoverageRecorder = first
+	m := newMachine(d)
+	m.SendEvent(Activate)
+	m.SetContext(KeyIsReady, true)
+	m.SendEvent(Activate)
+	m.SendEvent(Deactivate)
+
+	d = definition()
+	second := fsmcover.New("light", d)
+	m = newMachine(d)
+	m.SetCoverageRecorder(second)
+	m.SendEvent(Reset)
+
+	// Deactivate isn't handled in Inactive
+	m.SendEvent(Deactivate)
+
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "first.fsmcov"), filepath.Join(dir, "second.fsmcov")}
+	assert.Nil(t, first.WriteFile(paths[0]))
+	assert.Nil(t, second.WriteFile(paths[1]))
+
+	profiles := []*fsmcover.Profile{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		assert.Nil(t, err)
+		p, err := fsmcover.ReadProfiles(f)
+		f.Close()
+		assert.Nil(t, err)
+		profiles = append(profiles, p...)
+	}
+	assert.Equal(t, first.Profile(), profiles[0])
+
+	merged := fsmcover.Merge(profiles...)
+	assert.Len(t, merged, 1)
+
+	taken, total := merged[0].Taken()
+	assert.Equal(t, 3, taken)
+	assert.Equal(t, 5, total)
+
+	text := &bytes.Buffer{}
+	assert.Nil(t, fsmcover.WriteText(text, merged...))
+	assert.Equal(t, strings.Join([]string{
+		"light: 3 of 5 transitions taken (60.0%), 1 of 1 guards passed and failed (100.0%)",
+		"    not taken: Active --Reset--> Off",
+		"    not taken: Off --Reset--> Off",
+		"",
+	}, "\n"), text.String())
+
+	html := &bytes.Buffer{}
+	assert.Nil(t, fsmcover.WriteHTML(html, merged...))
+	assert.Contains(t, html.String(), "<h2>light</h2>")
+	assert.Contains(t, html.String(), "Activate [IsReady is true]")
+}
+
+func Test_ReadProfilesErrors(t *testing.T) {
+	_, err := fsmcover.ReadProfiles(strings.NewReader("Inactive\tActivate\n"))
+	assert.EqualError(t, err, "fsmcover: line 1: expected 'fsmcover: <name>'")
+
+	_, err = fsmcover.ReadProfiles(strings.NewReader("fsmcover: light\nInactive\tActivate\n"))
+	assert.EqualError(t, err, "fsmcover: line 2: expected 9 fields, got 2")
+}
@@ -0,0 +1,250 @@
+package fsmcover
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile is the coverage of one Definition
+type Profile struct {
+	Name   string
+	Points []Point
+}
+
+// Taken returns how many of the Points were taken, out of all of them
+func (p *Profile) Taken() (taken int, total int) {
+	for _, pt := range p.Points {
+		if pt.Taken > 0 {
+			taken++
+		}
+	}
+	return taken, len(p.Points)
+}
+
+// Guards returns how many guarded Points both passed and failed, out of all
+// the guarded Points
+func (p *Profile) Guards() (both int, total int) {
+	for _, pt := range p.Points {
+		if !pt.Guarded {
+			continue
+		}
+		total++
+		if pt.GuardPassed > 0 && pt.GuardFailed > 0 {
+			both++
+		}
+	}
+	return both, total
+}
+
+// header starts each Profile in a file
+const header = "fsmcover: "
+
+// WriteProfiles in the text format read by ReadProfiles. Each Point is a line
+// of tab separated fields: state, event, candidate, target, guarded, taken,
+// guard passed, guard failed and label.
+func WriteProfiles(w io.Writer, profiles ...*Profile) error {
+	b := bufio.NewWriter(w)
+	for _, p := range profiles {
+		fmt.Fprintf(b, "%s%s\n", header, p.Name)
+		for _, pt := range p.Points {
+			fmt.Fprintf(
+				b,
+				"%s\t%s\t%d\t%s\t%t\t%d\t%d\t%d\t%s\n",
+				pt.State,
+				pt.Event,
+				pt.Candidate,
+				pt.Target,
+				pt.Guarded,
+				pt.Taken,
+				pt.GuardPassed,
+				pt.GuardFailed,
+				pt.Label,
+			)
+		}
+	}
+	return b.Flush()
+}
+
+// ReadProfiles written by WriteProfiles
+func ReadProfiles(r io.Reader) ([]*Profile, error) {
+	profiles := []*Profile{}
+	lines := bufio.NewScanner(r)
+	n := 0
+	for lines.Scan() {
+		n++
+		line := lines.Text()
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, header) {
+			profiles = append(profiles, &Profile{Name: strings.TrimPrefix(line, header)})
+			continue
+		}
+		if len(profiles) == 0 {
+			return nil, fmt.Errorf("fsmcover: line %d: expected '%s<name>'", n, header)
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("fsmcover: line %d: expected 9 fields, got %d", n, len(fields))
+		}
+
+		pt := Point{State: fields[0], Event: fields[1], Target: fields[3], Label: fields[8]}
+		counts := []*int{&pt.Candidate, &pt.Taken, &pt.GuardPassed, &pt.GuardFailed}
+		var err error
+		for i, field := range []string{fields[2], fields[5], fields[6], fields[7]} {
+			if *counts[i], err = strconv.Atoi(field); err != nil {
+				return nil, fmt.Errorf("fsmcover: line %d: %w", n, err)
+			}
+		}
+		if pt.Guarded, err = strconv.ParseBool(fields[4]); err != nil {
+			return nil, fmt.Errorf("fsmcover: line %d: %w", n, err)
+		}
+
+		p := profiles[len(profiles)-1]
+		p.Points = append(p.Points, pt)
+	}
+	return profiles, lines.Err()
+}
+
+// Merge Profiles with the same name, adding up their counts. The Profiles are
+// returned sorted by name.
+func Merge(profiles ...*Profile) []*Profile {
+	type key struct {
+		state     string
+		event     string
+		candidate int
+		target    string
+	}
+
+	byName := map[string]*Profile{}
+	indexes := map[string]map[key]int{}
+	for _, p := range profiles {
+		merged, ok := byName[p.Name]
+		if !ok {
+			merged = &Profile{Name: p.Name}
+			byName[p.Name] = merged
+			indexes[p.Name] = map[key]int{}
+		}
+		index := indexes[p.Name]
+
+		for _, pt := range p.Points {
+			k := key{pt.State, pt.Event, pt.Candidate, pt.Target}
+			i, ok := index[k]
+			if !ok {
+				index[k] = len(merged.Points)
+				merged.Points = append(merged.Points, pt)
+				continue
+			}
+			merged.Points[i].Taken += pt.Taken
+			merged.Points[i].GuardPassed += pt.GuardPassed
+			merged.Points[i].GuardFailed += pt.GuardFailed
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	merged := make([]*Profile, 0, len(names))
+	for _, name := range names {
+		merged = append(merged, byName[name])
+	}
+	return merged
+}
+
+func percent(n int, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(n) / float64(total)
+}
+
+// WriteText writes a summary of each Profile, and lists the Transitions
+// that were never taken and the guards that didn't both pass and fail
+func WriteText(w io.Writer, profiles ...*Profile) error {
+	b := bufio.NewWriter(w)
+	for _, p := range profiles {
+		taken, total := p.Taken()
+		both, guarded := p.Guards()
+		fmt.Fprintf(
+			b,
+			"%s: %d of %d transitions taken (%.1f%%), %d of %d guards passed and failed (%.1f%%)\n",
+			p.Name, taken, total, percent(taken, total), both, guarded, percent(both, guarded),
+		)
+
+		for _, pt := range p.Points {
+			if pt.Taken == 0 {
+				fmt.Fprintf(b, "    not taken: %s --%s--> %s\n", pt.State, pt.Label, pt.Target)
+			}
+		}
+		for _, pt := range p.Points {
+			if pt.Guarded && (pt.GuardPassed == 0 || pt.GuardFailed == 0) {
+				fmt.Fprintf(
+					b,
+					"    guard: %s --%s--> %s passed %d, failed %d\n",
+					pt.State, pt.Label, pt.Target, pt.GuardPassed, pt.GuardFailed,
+				)
+			}
+		}
+	}
+	return b.Flush()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"taken": func(p *Profile) string {
+		taken, total := p.Taken()
+		return fmt.Sprintf("%d of %d transitions taken (%.1f%%)", taken, total, percent(taken, total))
+	},
+	"guards": func(p *Profile) string {
+		both, total := p.Guards()
+		return fmt.Sprintf("%d of %d guards passed and failed (%.1f%%)", both, total, percent(both, total))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>fsm coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.2em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+td.n { text-align: right; }
+tr.uncovered { background: #fdd; }
+tr.partial { background: #ffd; }
+tr.covered { background: #dfd; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2>{{.Name}}</h2>
+<p>{{taken .}}, {{guards .}}</p>
+<table>
+<tr><th>State</th><th>Transition</th><th>Target</th><th>Taken</th><th>Guard passed</th><th>Guard failed</th></tr>
+{{range .Points}}
+<tr class="{{if eq .Taken 0}}uncovered{{else if .Covered}}covered{{else}}partial{{end}}">
+<td>{{.State}}</td><td>{{.Label}}</td><td>{{.Target}}</td>
+<td class="n">{{.Taken}}</td>
+<td class="n">{{if .Guarded}}{{.GuardPassed}}{{end}}</td>
+<td class="n">{{if .Guarded}}{{.GuardFailed}}{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes a report of every Point in each Profile, highlighting
+// the ones not covered
+func WriteHTML(w io.Writer, profiles ...*Profile) error {
+	return reportTemplate.Execute(w, profiles)
+}
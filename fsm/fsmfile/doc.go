@@ -0,0 +1,32 @@
+/*
+Package fsmfile reads fsm.Definitions from JSON files, so tools like fsmctl can
+validate, render and simulate them without the Go code that runs them.
+
+	{
+		"initial": "Inactive",
+		"events": ["Activate", "Deactivate", "Increment"],
+		"context": [
+			{"name": "Counter", "protected": true, "initial": 0},
+			{"name": "IsReady", "initial": false}
+		],
+		"states": [
+			{"name": "Inactive", "on": [
+				{"event": "Activate", "target": "Active", "when": {"IsReady": true}}
+			]},
+			{"name": "Active", "on": [
+				{"event": "Deactivate", "target": "Inactive"},
+				{"event": "Increment", "target": "Active", "guard": "BelowLimit", "set": {"Counter": 1}}
+			]}
+		]
+	}
+
+States, Events and ContextKeys are numbered in the order they are listed, and
+their names are added to the Definition. Several Transitions for the same
+Event on a State become fsm.Choices, tried in the order they are listed.
+
+A Transition can only pass if its "when" Context values are equal, and its
+"guard" passes. Guards are Go functions, so they are looked up by name in the
+Guards given to File.Definition(), and a guard that isn't found is stubbed to
+pass. "set" updates Context values when the Transition is taken.
+*/
+package fsmfile // import "ojkelly.dev/fsm/fsmfile"
@@ -0,0 +1,316 @@
+package fsmfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"ojkelly.dev/fsm"
+)
+
+// File is a Definition as it's written in a JSON file
+type File struct {
+	Initial   string       `json:"initial"`
+	Events    []string     `json:"events"`
+	Context   []ContextKey `json:"context,omitempty"`
+	States    []State      `json:"states"`
+	Wildcards []Transition `json:"wildcards,omitempty"`
+}
+
+// ContextKey is one key in the Context
+type ContextKey struct {
+	Name      string      `json:"name"`
+	Protected bool        `json:"protected,omitempty"`
+	Initial   interface{} `json:"initial"`
+}
+
+// State is a StateNode and its Transitions
+type State struct {
+	Name  string       `json:"name"`
+	Final bool         `json:"final,omitempty"`
+	On    []Transition `json:"on,omitempty"`
+}
+
+// Transition from a State on an Event
+type Transition struct {
+	Event  string                 `json:"event"`
+	Target string                 `json:"target"`
+	Guard  string                 `json:"guard,omitempty"`
+	When   map[string]interface{} `json:"when,omitempty"`
+	Set    map[string]interface{} `json:"set,omitempty"`
+}
+
+// Guards are looked up by the name used in a Transition's "guard"
+type Guards map[string]fsm.Guard
+
+// Errors are all the problems found in a File
+type Errors []error
+
+func (e Errors) Error() string {
+	lines := make([]string, 0, len(e))
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Parse a File from JSON. Unknown fields are an error, to catch typos.
+func Parse(r io.Reader) (*File, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	f := &File{}
+	if err := dec.Decode(f); err != nil {
+		return nil, fmt.Errorf("fsmfile: %w", err)
+	}
+	return f, nil
+}
+
+// Load a File from path
+func Load(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// GuardNames returns the name of every guard used, sorted
+func (f *File) GuardNames() []string {
+	seen := map[string]bool{}
+	for _, t := range f.transitions() {
+		if t.Guard != "" {
+			seen[t.Guard] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// transitions returns every Transition, including wildcards
+func (f *File) transitions() []Transition {
+	all := append([]Transition{}, f.Wildcards...)
+	for _, s := range f.States {
+		all = append(all, s.On...)
+	}
+	return all
+}
+
+// Validate returns every problem with the File, or nil
+func (f *File) Validate() error {
+	errs := Errors{}
+	add := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	states := map[string]bool{}
+	for _, s := range f.States {
+		if s.Name == "" {
+			add("a state has no name")
+		} else if states[s.Name] {
+			add("state '%s' is declared twice", s.Name)
+		}
+		states[s.Name] = true
+	}
+
+	events := map[string]bool{}
+	for _, e := range f.Events {
+		if e == "" {
+			add("an event has no name")
+		} else if events[e] {
+			add("event '%s' is declared twice", e)
+		}
+		events[e] = true
+	}
+
+	keys := map[string]bool{}
+	for _, k := range f.Context {
+		if k.Name == "" {
+			add("a context key has no name")
+		} else if keys[k.Name] {
+			add("context key '%s' is declared twice", k.Name)
+		}
+		keys[k.Name] = true
+	}
+
+	if f.Initial == "" {
+		add("there is no initial state")
+	} else if !states[f.Initial] {
+		add("initial state '%s' is not declared", f.Initial)
+	}
+
+	checkTransition := func(from string, t Transition) {
+		if !events[t.Event] {
+			add("%s: event '%s' is not declared", from, t.Event)
+		}
+		if !states[t.Target] {
+			add("%s on '%s': target state '%s' is not declared", from, t.Event, t.Target)
+		}
+		for _, k := range sortedKeys(t.When) {
+			if !keys[k] {
+				add("%s on '%s': context key '%s' in when is not declared", from, t.Event, k)
+			}
+		}
+		for _, k := range sortedKeys(t.Set) {
+			if !keys[k] {
+				add("%s on '%s': context key '%s' in set is not declared", from, t.Event, k)
+			}
+		}
+	}
+
+	for _, s := range f.States {
+		unguarded := map[string]bool{}
+		for _, t := range s.On {
+			checkTransition(fmt.Sprintf("state '%s'", s.Name), t)
+
+			if t.Guard == "" && len(t.When) == 0 {
+				if unguarded[t.Event] {
+					add("state '%s' on '%s': more than one transition has no guard, only the first is ever taken", s.Name, t.Event)
+				}
+				unguarded[t.Event] = true
+			}
+		}
+	}
+
+	wildcards := map[string]bool{}
+	for _, t := range f.Wildcards {
+		checkTransition("wildcard", t)
+		if wildcards[t.Event] {
+			add("wildcard on '%s' is declared twice", t.Event)
+		}
+		wildcards[t.Event] = true
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Definition creates an fsm.Definition from the File, with guards looked up
+// in guards. A guard that isn't found passes.
+func (f *File) Definition(guards Guards) (fsm.Definition, error) {
+	if err := f.Validate(); err != nil {
+		return fsm.Definition{}, err
+	}
+
+	d := fsm.Definition{
+		StateChangeChannelSize: 1,
+		Context:                fsm.Context{},
+		States:                 fsm.States{},
+		StateNames:             fsm.StateNames{},
+		EventNames:             fsm.EventNames{},
+		ContextKeyNames:        fsm.ContextKeyNames{},
+	}
+
+	states := map[string]fsm.State{}
+	for i, s := range f.States {
+		states[s.Name] = fsm.State(i)
+		d.StateNames[fsm.State(i)] = s.Name
+	}
+	d.InitialState = states[f.Initial]
+
+	events := map[string]fsm.Event{}
+	for i, e := range f.Events {
+		events[e] = fsm.Event(i)
+		d.EventNames[fsm.Event(i)] = e
+		d.Events = append(d.Events, fsm.Event(i))
+	}
+
+	keys := map[string]fsm.ContextKey{}
+	for i, k := range f.Context {
+		keys[k.Name] = fsm.ContextKey(i)
+		d.ContextKeyNames[fsm.ContextKey(i)] = k.Name
+		d.Context[fsm.ContextKey(i)] = fsm.ContextMeta{Protected: k.Protected, Inital: k.Initial}
+	}
+
+	transition := func(t Transition) fsm.Transition {
+		ft := fsm.Transition{State: states[t.Target]}
+
+		conditions := []fsm.Condition{}
+		for _, k := range sortedKeys(t.When) {
+			conditions = append(conditions, fsm.ContextEquals(keys[k], t.When[k]))
+		}
+		if t.Guard != "" {
+			g, ok := guards[t.Guard]
+			if !ok {
+				g = func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return true }
+			}
+			conditions = append(conditions, fsm.NamedGuard(t.Guard, g))
+		}
+		switch len(conditions) {
+		case 0:
+		case 1:
+			ft.Condition = conditions[0]
+		default:
+			ft.Condition = fsm.And(conditions...)
+		}
+
+		if len(t.Set) > 0 {
+			update := fsm.UpdateContext{}
+			for k, v := range t.Set {
+				update[keys[k]] = v
+			}
+			ft.UpdateContext = func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+				return update, nil
+			}
+		}
+		return ft
+	}
+
+	for _, s := range f.States {
+		node := fsm.StateNode{Final: s.Final}
+
+		candidates := map[string][]fsm.Transition{}
+		for _, t := range s.On {
+			candidates[t.Event] = append(candidates[t.Event], transition(t))
+		}
+		for e, ts := range candidates {
+			if len(ts) == 1 {
+				if node.Events == nil {
+					node.Events = fsm.EventToTransition{}
+				}
+				node.Events[events[e]] = ts[0]
+				continue
+			}
+			if node.Choices == nil {
+				node.Choices = fsm.EventToTransitions{}
+			}
+			node.Choices[events[e]] = ts
+		}
+
+		d.States[states[s.Name]] = node
+	}
+
+	if len(f.Wildcards) > 0 {
+		d.Wildcards = fsm.EventToTransition{}
+		for _, t := range f.Wildcards {
+			d.Wildcards[events[t.Event]] = transition(t)
+		}
+	}
+
+	return d, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package fsmfile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmfile"
+)
+
+const counter = `{
+	"initial": "Inactive",
+	"events": ["Activate", "Deactivate", "Increment"],
+	"context": [
+		{"name": "Counter", "protected": true, "initial": 0},
+		{"name": "IsReady", "initial": false}
+	],
+	"states": [
+		{"name": "Inactive", "on": [
+			{"event": "Activate", "target": "Active", "when": {"IsReady": true}}
+		]},
+		{"name": "Active", "on": [
+			{"event": "Deactivate", "target": "Inactive"},
+			{"event": "Increment", "target": "Active", "guard": "BelowLimit", "set": {"Counter": 1}},
+			{"event": "Increment", "target": "Inactive"}
+		]}
+	]
+}`
+
+func Test_Definition(t *testing.T) {
+	f, err := fsmfile.Parse(strings.NewReader(counter))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"BelowLimit"}, f.GuardNames())
+
+	belowLimit := true
+	d, err := f.Definition(fsmfile.Guards{
+		"BelowLimit": func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return belowLimit },
+	})
+	assert.Nil(t, err)
+
+	m := d.New("counter")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	assert.Equal(t, "Inactive", m.GetNameForState(m.State()))
+
+	activate, _ := m.EventByName("Activate")
+	increment, _ := m.EventByName("Increment")
+	isReady, _ := m.ContextKeyByName("IsReady")
+	counter, _ := m.ContextKeyByName("Counter")
+
+	assert.False(t, m.SendEvent(activate))
+	m.SetContext(isReady, true)
+	assert.True(t, m.SendEvent(activate))
+	assert.Equal(t, "Active", m.GetNameForState(m.State()))
+
+	assert.True(t, m.SendEvent(increment))
+	assert.Equal(t, float64(1), m.GetContext(counter))
+	assert.Equal(t, "Active", m.GetNameForState(m.State()))
+
+	// the second candidate is the fallback
+	belowLimit = false
+	assert.True(t, m.SendEvent(increment))
+	assert.Equal(t, "Inactive", m.GetNameForState(m.State()))
+}
+
+func Test_Validate(t *testing.T) {
+	f, err := fsmfile.Parse(strings.NewReader(`{
+		"initial": "Missing",
+		"events": ["Go"],
+		"context": [{"name": "Ready"}, {"name": "Ready"}],
+		"states": [
+			{"name": "Start", "on": [
+				{"event": "Go", "target": "Start"},
+				{"event": "Go", "target": "Start"},
+				{"event": "Jump", "target": "Start", "when": {"Steady": true}}
+			]}
+		]
+	}`))
+	assert.Nil(t, err)
+
+	err = f.Validate()
+	assert.Equal(t, strings.Join([]string{
+		"context key 'Ready' is declared twice",
+		"initial state 'Missing' is not declared",
+		"state 'Start' on 'Go': more than one transition has no guard, only the first is ever taken",
+		"state 'Start': event 'Jump' is not declared",
+		"state 'Start' on 'Jump': context key 'Steady' in when is not declared",
+	}, "\n"), err.Error())
+
+	_, err = f.Definition(nil)
+	assert.NotNil(t, err)
+
+	_, err = fsmfile.Parse(strings.NewReader(`{"initial": "Start", "state": []}`))
+	assert.NotNil(t, err)
+}
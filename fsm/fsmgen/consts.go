@@ -0,0 +1,250 @@
+package fsmgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// constant is one name in an annotated const block
+type constant struct {
+	ident string
+	name  string
+	value int64
+	// typ is from an fsm:type comment, for ContextKeys
+	typ string
+}
+
+// annotations on const blocks, and the fsm type their constants must have
+var annotations = map[string]string{
+	"fsm:states":  "State",
+	"fsm:events":  "Event",
+	"fsm:context": "ContextKey",
+}
+
+// FromConsts generates the names maps for the const blocks annotated with
+// fsm:states, fsm:events and fsm:context in a Go source file. If
+// o.Package is empty, the file's package is used.
+func FromConsts(filename string, src []byte, o Options) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Package == "" {
+		o.Package = file.Name.Name
+	}
+	if o.Source == "" {
+		o.Source = filename
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	g := newGenerator(o, "ojkelly.dev/fsm")
+	blocks := map[string][]constant{}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST || gen.Doc == nil {
+			continue
+		}
+
+		kind := ""
+		for _, c := range gen.Doc.List {
+			if t, ok := annotations[strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))]; ok {
+				kind = t
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		at := fset.Position(gen.Pos())
+		if len(blocks[kind]) > 0 {
+			g.errorf("%s: a second const block of fsm.%s", at, kind)
+			continue
+		}
+
+		constants, err := readConsts(fset, gen, kind)
+		if err != nil {
+			g.errorf("%s", err)
+			continue
+		}
+		blocks[kind] = constants
+	}
+
+	if len(g.errs) > 0 {
+		return nil, g.errs
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%s: no const blocks annotated with fsm:states, fsm:events or fsm:context", filename)
+	}
+
+	maps := []struct {
+		kind  string
+		names string
+	}{
+		{"State", g.declare(o.Name+"StateNames", "the State names")},
+		{"Event", g.declare(o.Name+"EventNames", "the Event names")},
+		{"ContextKey", g.declare(o.Name+"ContextKeyNames", "the ContextKey names")},
+	}
+	add := g.declare("Add"+o.Name+"Names", "adding names")
+
+	for _, m := range maps {
+		if _, ok := blocks[m.kind]; !ok {
+			continue
+		}
+		g.printf("var %s = fsm.%sNames{\n", m.names, m.kind)
+		for _, c := range blocks[m.kind] {
+			g.printf("%s: %q,\n", c.ident, c.name)
+		}
+		g.printf("}\n\n")
+	}
+
+	g.printf("// %s adds the names of the %s States, Events and ContextKeys to m\n", add, o.Name)
+	g.printf("func %s(m *fsm.Machine) {\n", add)
+	for _, m := range maps {
+		if _, ok := blocks[m.kind]; ok {
+			g.printf("m.Add%sNames(%s)\n", m.kind, m.names)
+		}
+	}
+	g.printf("}\n")
+
+	for _, c := range blocks["ContextKey"] {
+		if c.typ == "" {
+			continue
+		}
+		getter := g.declare(o.Name+exported(c.name), fmt.Sprintf("the %s getter", c.ident))
+		g.printf("\n// %s returns the %s Context value of m\n", getter, c.name)
+		g.printf("func %s(m *fsm.Machine) %s {\n", getter, c.typ)
+		if c.typ == "interface{}" {
+			g.printf("return m.GetContext(%s)\n", c.ident)
+		} else {
+			g.printf("v, _ := m.GetContext(%s).(%s)\n", c.ident, c.typ)
+			g.printf("return v\n")
+		}
+		g.printf("}\n")
+	}
+
+	return g.source()
+}
+
+// readConsts from a const block of fsm.<kind>, following iota
+func readConsts(fset *token.FileSet, gen *ast.GenDecl, kind string) ([]constant, error) {
+	constants := []constant{}
+	values := map[int64]string{}
+	var last ast.Expr
+
+	for iota, spec := range gen.Specs {
+		v := spec.(*ast.ValueSpec)
+		at := fset.Position(v.Pos())
+
+		if iota == 0 && !isFsmType(v.Type, kind) {
+			return nil, fmt.Errorf("%s: the first constant must be of type fsm.%s", at, kind)
+		}
+		if v.Type != nil && !isFsmType(v.Type, kind) {
+			return nil, fmt.Errorf("%s: every constant must be of type fsm.%s", at, kind)
+		}
+		if len(v.Values) > 1 || len(v.Names) > 1 {
+			return nil, fmt.Errorf("%s: declare one constant per line", at)
+		}
+		if len(v.Values) == 1 {
+			last = v.Values[0]
+		}
+		if last == nil {
+			return nil, fmt.Errorf("%s: the first constant needs a value", at)
+		}
+
+		value, err := evalConst(last, int64(iota))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", at, err)
+		}
+
+		ident := v.Names[0].Name
+		if ident == "_" {
+			continue
+		}
+		if other, ok := values[value]; ok {
+			return nil, fmt.Errorf("%s: %s has the same value as %s", at, ident, other)
+		}
+		values[value] = ident
+
+		c := constant{ident: ident, name: ident, value: value}
+		if kind == "ContextKey" {
+			c.name = trimKey(ident)
+			if v.Comment != nil {
+				for _, comment := range v.Comment.List {
+					text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+					if strings.HasPrefix(text, "fsm:type ") {
+						c.typ = strings.TrimSpace(strings.TrimPrefix(text, "fsm:type "))
+						if _, err := parser.ParseExpr(c.typ); err != nil {
+							return nil, fmt.Errorf("%s: fsm:type '%s' is not a Go type", at, c.typ)
+						}
+					}
+				}
+			}
+		}
+		constants = append(constants, c)
+	}
+
+	sort.Slice(constants, func(i, j int) bool { return constants[i].value < constants[j].value })
+	return constants, nil
+}
+
+func isFsmType(expr ast.Expr, kind string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "fsm" && sel.Sel.Name == kind
+}
+
+// evalConst evaluates the small subset of constant expressions used for
+// States, Events and ContextKeys: iota, integers, + and -
+func evalConst(expr ast.Expr, iota int64) (int64, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Name == "iota" {
+			return iota, nil
+		}
+	case *ast.BasicLit:
+		if e.Kind == token.INT {
+			return strconv.ParseInt(e.Value, 0, 64)
+		}
+	case *ast.ParenExpr:
+		return evalConst(e.X, iota)
+	case *ast.BinaryExpr:
+		x, err := evalConst(e.X, iota)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evalConst(e.Y, iota)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		}
+	}
+	return 0, fmt.Errorf("only iota, integers, + and - are supported in constants")
+}
+
+// trimKey trims the Key prefix from KeyCounter
+func trimKey(ident string) string {
+	rest := strings.TrimPrefix(ident, "Key")
+	if rest == ident || rest == "" || !unicode.IsUpper([]rune(rest)[0]) {
+		return ident
+	}
+	return rest
+}
@@ -0,0 +1,43 @@
+/*
+Package fsmgen generates Go code for a Machine, so its States, Events and
+names are written once. It's run by the fsmgen command from go:generate.
+
+From a definition file, see ojkelly.dev/fsm/fsmfile, it generates typed
+constants with String() methods, the names maps, a struct of the guards the
+definition needs, and a constructor with the definition wired in.
+
+	//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -file order.json -name Order
+
+	order := NewOrder("order-1", OrderGuards{InStock: inStock})
+	order.Send(Pay)
+	order.Paid() // typed Context accessor
+	order.SetExpress(true)
+
+An invalid definition is reported and nothing is generated, so go generate
+fails rather than producing a Machine that doesn't work.
+
+For Machines written in Go, annotate the const blocks instead, and the names
+maps and a function to add them are generated.
+
+	//fsm:states
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	//fsm:context
+	const (
+		KeyCounter fsm.ContextKey = iota //fsm:type int
+		KeyIsReady                       //fsm:type bool
+	)
+
+	//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -consts counter.go -name Counter
+
+	AddCounterNames(machine)
+	CounterIsReady(machine) // typed Context accessor, for keys with a fsm:type
+
+A "Key" prefix is trimmed from the names of ContextKeys. The constants are of
+fsm's own types, which can't have methods added, so the names maps are
+generated instead of String() methods.
+*/
+package fsmgen // import "ojkelly.dev/fsm/fsmgen"
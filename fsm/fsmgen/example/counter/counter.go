@@ -0,0 +1,68 @@
+// Package counter is an example of names generated by fsmgen from annotated
+// const blocks
+package counter // import "ojkelly.dev/fsm/fsmgen/example/counter"
+
+import "ojkelly.dev/fsm"
+
+//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -consts counter.go -name Counter
+
+//fsm:states
+const (
+	Inactive fsm.State = iota
+	Active
+)
+
+//fsm:events
+const (
+	Activate fsm.Event = iota
+	Deactivate
+	Increment
+	Decrement
+)
+
+//fsm:context
+const (
+	KeyCounter fsm.ContextKey = iota //fsm:type int
+	KeyIsReady                       //fsm:type bool
+)
+
+// New counter Machine, with its names added
+func New(id string) *fsm.Machine {
+	m := fsm.New(
+		id,
+		1,
+		Inactive,
+		fsm.Context{
+			KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0},
+			KeyIsReady: fsm.ContextMeta{Inital: false},
+		},
+		[]fsm.Event{Activate, Deactivate, Increment, Decrement},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{State: Active, Condition: fsm.ContextIsTrue(KeyIsReady)},
+				},
+			},
+			Active: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Deactivate: fsm.Transition{State: Inactive},
+					Increment: fsm.Transition{
+						State: Active,
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{KeyCounter: CounterCounter(m) + 1}, nil
+						},
+					},
+					Decrement: fsm.Transition{
+						State: Active,
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{KeyCounter: CounterCounter(m) - 1}, nil
+						},
+					},
+				},
+			},
+		},
+		nil,
+	)
+	AddCounterNames(m)
+	return m
+}
@@ -0,0 +1,43 @@
+// Code generated by fsmgen from counter.go. DO NOT EDIT.
+
+package counter
+
+import (
+	"ojkelly.dev/fsm"
+)
+
+var CounterStateNames = fsm.StateNames{
+	Inactive: "Inactive",
+	Active:   "Active",
+}
+
+var CounterEventNames = fsm.EventNames{
+	Activate:   "Activate",
+	Deactivate: "Deactivate",
+	Increment:  "Increment",
+	Decrement:  "Decrement",
+}
+
+var CounterContextKeyNames = fsm.ContextKeyNames{
+	KeyCounter: "Counter",
+	KeyIsReady: "IsReady",
+}
+
+// AddCounterNames adds the names of the Counter States, Events and ContextKeys to m
+func AddCounterNames(m *fsm.Machine) {
+	m.AddStateNames(CounterStateNames)
+	m.AddEventNames(CounterEventNames)
+	m.AddContextKeyNames(CounterContextKeyNames)
+}
+
+// CounterCounter returns the Counter Context value of m
+func CounterCounter(m *fsm.Machine) int {
+	v, _ := m.GetContext(KeyCounter).(int)
+	return v
+}
+
+// CounterIsReady returns the IsReady Context value of m
+func CounterIsReady(m *fsm.Machine) bool {
+	v, _ := m.GetContext(KeyIsReady).(bool)
+	return v
+}
@@ -0,0 +1,27 @@
+package counter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm/fsmgen/example/counter"
+)
+
+func Test_Counter(t *testing.T) {
+	m := counter.New("counter")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+
+	assert.Equal(t, "Inactive", m.GetNameForState(m.State()))
+	m.SetContext(counter.KeyIsReady, true)
+	assert.True(t, counter.CounterIsReady(m))
+
+	assert.True(t, m.SendEvent(counter.Activate))
+	assert.True(t, m.SendEvent(counter.Increment))
+	assert.True(t, m.SendEvent(counter.Increment))
+	assert.Equal(t, 2, counter.CounterCounter(m))
+	assert.Equal(t, "Active", m.GetNameForState(m.State()))
+	assert.Equal(t, "Counter", m.GetNameForContextKey(counter.KeyCounter))
+}
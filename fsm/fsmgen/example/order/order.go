@@ -0,0 +1,5 @@
+// Package order is an example of a Machine generated by fsmgen from a
+// definition file
+package order // import "ojkelly.dev/fsm/fsmgen/example/order"
+
+//go:generate go run ojkelly.dev/fsm/cmd/fsmgen -file order.json -name Order
@@ -0,0 +1,193 @@
+// Code generated by fsmgen from order.json. DO NOT EDIT.
+
+package order
+
+import (
+	"context"
+	"ojkelly.dev/fsm"
+)
+
+// OrderState is a State of the Order Machine
+type OrderState fsm.State
+
+const (
+	Created OrderState = iota
+	Paid
+	Shipped
+	Delivered
+	Refunded
+	Cancelled
+)
+
+var OrderStateNames = fsm.StateNames{
+	fsm.State(Created):   "Created",
+	fsm.State(Paid):      "Paid",
+	fsm.State(Shipped):   "Shipped",
+	fsm.State(Delivered): "Delivered",
+	fsm.State(Refunded):  "Refunded",
+	fsm.State(Cancelled): "Cancelled",
+}
+
+func (s OrderState) String() string { return OrderStateNames[fsm.State(s)] }
+
+// State converts s for use with fsm
+func (s OrderState) State() fsm.State { return fsm.State(s) }
+
+// OrderEvent is an Event of the Order Machine
+type OrderEvent fsm.Event
+
+const (
+	Pay OrderEvent = iota
+	Ship
+	Deliver
+	Refund
+	Cancel
+)
+
+var OrderEventNames = fsm.EventNames{
+	fsm.Event(Pay):     "Pay",
+	fsm.Event(Ship):    "Ship",
+	fsm.Event(Deliver): "Deliver",
+	fsm.Event(Refund):  "Refund",
+	fsm.Event(Cancel):  "Cancel",
+}
+
+func (e OrderEvent) String() string { return OrderEventNames[fsm.Event(e)] }
+
+// Event converts e for use with fsm
+func (e OrderEvent) Event() fsm.Event { return fsm.Event(e) }
+
+// OrderContextKey is a ContextKey of the Order Machine
+type OrderContextKey fsm.ContextKey
+
+const (
+	KeyPaid OrderContextKey = iota
+	KeyExpress
+)
+
+var OrderContextKeyNames = fsm.ContextKeyNames{
+	fsm.ContextKey(KeyPaid):    "Paid",
+	fsm.ContextKey(KeyExpress): "Express",
+}
+
+func (k OrderContextKey) String() string { return OrderContextKeyNames[fsm.ContextKey(k)] }
+
+// ContextKey converts k for use with fsm
+func (k OrderContextKey) ContextKey() fsm.ContextKey { return fsm.ContextKey(k) }
+
+// OrderGuards are the guards used by the Order Machine, none can be nil
+type OrderGuards struct {
+	InStock fsm.Guard
+}
+
+// OrderDefinition returns the Order Definition, it panics if a guard is nil
+func OrderDefinition(guards OrderGuards) fsm.Definition {
+	if guards.InStock == nil {
+		panic("OrderGuards.InStock is nil")
+	}
+
+	return fsm.Definition{
+		StateChangeChannelSize: 1,
+		InitialState:           fsm.State(Created),
+		Context: fsm.Context{
+			fsm.ContextKey(KeyPaid):    {Protected: true, Inital: false},
+			fsm.ContextKey(KeyExpress): {Protected: false, Inital: false},
+		},
+		Events: []fsm.Event{
+			fsm.Event(Pay),
+			fsm.Event(Ship),
+			fsm.Event(Deliver),
+			fsm.Event(Refund),
+			fsm.Event(Cancel),
+		},
+		States: fsm.States{
+			fsm.State(Created): {
+				Events: fsm.EventToTransition{
+					fsm.Event(Pay): {
+						State: fsm.State(Paid),
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{
+								fsm.ContextKey(KeyPaid): true,
+							}, nil
+						},
+					},
+					fsm.Event(Cancel): {
+						State: fsm.State(Cancelled),
+					},
+				},
+			},
+			fsm.State(Paid): {
+				Events: fsm.EventToTransition{
+					fsm.Event(Ship): {
+						State:     fsm.State(Shipped),
+						Condition: fsm.NamedGuard("InStock", guards.InStock),
+					},
+					fsm.Event(Refund): {
+						State: fsm.State(Refunded),
+					},
+				},
+			},
+			fsm.State(Shipped): {
+				Events: fsm.EventToTransition{
+					fsm.Event(Deliver): {
+						State: fsm.State(Delivered),
+					},
+				},
+			},
+			fsm.State(Delivered): {
+				Final: true,
+				Events: fsm.EventToTransition{
+					fsm.Event(Refund): {
+						State: fsm.State(Refunded),
+					},
+				},
+			},
+			fsm.State(Refunded): {
+				Final: true,
+			},
+			fsm.State(Cancelled): {
+				Final: true,
+			},
+		},
+		StateNames:      OrderStateNames,
+		EventNames:      OrderEventNames,
+		ContextKeyNames: OrderContextKeyNames,
+	}
+}
+
+// Order is a Machine created from OrderDefinition, with typed States, Events and
+// Context
+type Order struct {
+	*fsm.Machine
+}
+
+// NewOrder creates a Machine from OrderDefinition, it panics if a guard is nil
+func NewOrder(id string, guards OrderGuards) Order {
+	return Order{OrderDefinition(guards).New(id)}
+}
+
+// Current returns the State the Machine is in
+func (m Order) Current() OrderState { return OrderState(m.Machine.State()) }
+
+// Send an Event, see fsm.Machine.SendEvent
+func (m Order) Send(e OrderEvent) bool { return m.SendEvent(fsm.Event(e)) }
+
+// SendContext sends an Event, see fsm.Machine.SendEventContext
+func (m Order) SendContext(ctx context.Context, e OrderEvent) error {
+	return m.SendEventContext(ctx, fsm.Event(e))
+}
+
+// Paid returns the Paid Context value
+func (m Order) Paid() bool {
+	v, _ := m.GetContext(fsm.ContextKey(KeyPaid)).(bool)
+	return v
+}
+
+// Express returns the Express Context value
+func (m Order) Express() bool {
+	v, _ := m.GetContext(fsm.ContextKey(KeyExpress)).(bool)
+	return v
+}
+
+// SetExpress sets the Express Context value
+func (m Order) SetExpress(v bool) { m.SetContext(fsm.ContextKey(KeyExpress), v) }
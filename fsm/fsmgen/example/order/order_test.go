@@ -0,0 +1,37 @@
+package order_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmgen/example/order"
+)
+
+func Test_Order(t *testing.T) {
+	inStock := false
+	m := order.NewOrder("order-1", order.OrderGuards{
+		InStock: func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return inStock },
+	})
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+
+	assert.Equal(t, order.Created, m.Current())
+	assert.Equal(t, "Created", m.Current().String())
+	assert.Equal(t, "Ship", order.Ship.String())
+	assert.Equal(t, "Express", order.KeyExpress.String())
+
+	assert.True(t, m.Send(order.Pay))
+	assert.True(t, m.Paid())
+	assert.False(t, m.Send(order.Ship))
+
+	inStock = true
+	m.SetExpress(true)
+	assert.True(t, m.Express())
+	assert.True(t, m.Send(order.Ship))
+	assert.Equal(t, order.Shipped, m.Current())
+
+	assert.Panics(t, func() { order.NewOrder("order-2", order.OrderGuards{}) })
+}
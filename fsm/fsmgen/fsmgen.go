@@ -0,0 +1,471 @@
+package fsmgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmfile"
+)
+
+// Options for the generated code
+type Options struct {
+	// Package of the generated file
+	Package string
+	// Name of the Machine, used as a prefix for the generated types and
+	// functions
+	Name string
+	// Prefix for the generated constants
+	Prefix string
+	// Source is named in the generated header
+	Source string
+}
+
+func (o Options) validate() error {
+	if o.Package == "" {
+		return fmt.Errorf("fsmgen: no package name")
+	}
+	if !isExported(o.Name) {
+		return fmt.Errorf("fsmgen: name '%s' must be an exported Go identifier", o.Name)
+	}
+	return nil
+}
+
+// generator writes Go source, and checks the identifiers it declares don't
+// clash
+type generator struct {
+	buf      bytes.Buffer
+	declared map[string]string
+	errs     fsmfile.Errors
+}
+
+func newGenerator(o Options, imports ...string) *generator {
+	g := &generator{declared: map[string]string{}}
+	g.printf("// Code generated by fsmgen from %s. DO NOT EDIT.\n\n", o.Source)
+	g.printf("package %s\n\n", o.Package)
+	g.printf("import (\n")
+	for _, i := range imports {
+		g.printf("%q\n", i)
+	}
+	g.printf(")\n\n")
+	return g
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&g.buf, format, a...)
+}
+
+func (g *generator) errorf(format string, a ...interface{}) {
+	g.errs = append(g.errs, fmt.Errorf(format, a...))
+}
+
+// declare an identifier, for what it's generated from
+func (g *generator) declare(ident string, from string) string {
+	switch {
+	case !token.IsIdentifier(ident):
+		g.errorf("%s: '%s' is not a Go identifier", from, ident)
+	case g.declared[ident] != "":
+		g.errorf("%s: '%s' is already generated for %s", from, ident, g.declared[ident])
+	default:
+		g.declared[ident] = from
+	}
+	return ident
+}
+
+func (g *generator) source() ([]byte, error) {
+	if len(g.errs) > 0 {
+		return nil, g.errs
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("fsmgen: generated invalid Go: %w\n%s", err, g.buf.String())
+	}
+	return src, nil
+}
+
+// machineMethods can't be used for Context accessors on a struct embedding
+// *fsm.Machine
+var machineMethods = func() map[string]bool {
+	methods := map[string]bool{}
+	t := reflect.TypeOf(&fsm.Machine{})
+	for i := 0; i < t.NumMethod(); i++ {
+		methods[t.Method(i).Name] = true
+	}
+	return methods
+}()
+
+// FromFile generates a Machine from a definition file
+func FromFile(f *fsmfile.File, o Options) ([]byte, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := newGenerator(o, "context", "ojkelly.dev/fsm")
+
+	stateType := g.declare(o.Name+"State", "the State type")
+	eventType := g.declare(o.Name+"Event", "the Event type")
+	keyType := g.declare(o.Name+"ContextKey", "the ContextKey type")
+	guardsType := g.declare(o.Name+"Guards", "the guards")
+	machineType := g.declare(o.Name, "the Machine")
+	stateNames := g.declare(o.Name+"StateNames", "the State names")
+	eventNames := g.declare(o.Name+"EventNames", "the Event names")
+	keyNames := g.declare(o.Name+"ContextKeyNames", "the ContextKey names")
+	definition := g.declare(o.Name+"Definition", "the Definition")
+	constructor := g.declare("New"+o.Name, "the constructor")
+
+	states := map[string]string{}
+	for _, s := range f.States {
+		states[s.Name] = g.declare(o.Prefix+exported(s.Name), fmt.Sprintf("state '%s'", s.Name))
+	}
+	events := map[string]string{}
+	for _, e := range f.Events {
+		events[e] = g.declare(o.Prefix+exported(e), fmt.Sprintf("event '%s'", e))
+	}
+	keys := map[string]string{}
+	types := map[string]string{}
+	for _, k := range f.Context {
+		keys[k.Name] = g.declare(o.Prefix+"Key"+exported(k.Name), fmt.Sprintf("context key '%s'", k.Name))
+		typ, err := goType(k.Initial)
+		if err != nil {
+			g.errorf("context key '%s': %s", k.Name, err)
+		}
+		types[k.Name] = typ
+
+		accessors := map[string]string{exported(k.Name): "getter"}
+		if !k.Protected {
+			accessors["Set"+exported(k.Name)] = "setter"
+		}
+		for method, kind := range accessors {
+			if machineMethods[method] || method == "Current" || method == "Send" || method == "SendContext" {
+				g.errorf("context key '%s': the %s %s() clashes with a method of %s", k.Name, kind, method, machineType)
+			}
+		}
+	}
+
+	value := func(from string, key string, v interface{}) string {
+		lit, err := goValue(v, types[key])
+		if err != nil {
+			g.errorf("%s: '%s': %s", from, key, err)
+		}
+		return lit
+	}
+
+	// States
+	g.printf("// %s is a State of the %s Machine\n", stateType, o.Name)
+	g.printf("type %s fsm.State\n\n", stateType)
+	g.printf("const (\n")
+	for i, s := range f.States {
+		if i == 0 {
+			g.printf("%s %s = iota\n", states[s.Name], stateType)
+		} else {
+			g.printf("%s\n", states[s.Name])
+		}
+	}
+	g.printf(")\n\n")
+	g.printf("var %s = fsm.StateNames{\n", stateNames)
+	for _, s := range f.States {
+		g.printf("fsm.State(%s): %q,\n", states[s.Name], s.Name)
+	}
+	g.printf("}\n\n")
+	g.printf("func (s %s) String() string { return %s[fsm.State(s)] }\n\n", stateType, stateNames)
+	g.printf("// State converts s for use with fsm\n")
+	g.printf("func (s %s) State() fsm.State { return fsm.State(s) }\n\n", stateType)
+
+	// Events
+	g.printf("// %s is an Event of the %s Machine\n", eventType, o.Name)
+	g.printf("type %s fsm.Event\n\n", eventType)
+	g.printf("const (\n")
+	for i, e := range f.Events {
+		if i == 0 {
+			g.printf("%s %s = iota\n", events[e], eventType)
+		} else {
+			g.printf("%s\n", events[e])
+		}
+	}
+	g.printf(")\n\n")
+	g.printf("var %s = fsm.EventNames{\n", eventNames)
+	for _, e := range f.Events {
+		g.printf("fsm.Event(%s): %q,\n", events[e], e)
+	}
+	g.printf("}\n\n")
+	g.printf("func (e %s) String() string { return %s[fsm.Event(e)] }\n\n", eventType, eventNames)
+	g.printf("// Event converts e for use with fsm\n")
+	g.printf("func (e %s) Event() fsm.Event { return fsm.Event(e) }\n\n", eventType)
+
+	// ContextKeys
+	g.printf("// %s is a ContextKey of the %s Machine\n", keyType, o.Name)
+	g.printf("type %s fsm.ContextKey\n\n", keyType)
+	if len(f.Context) > 0 {
+		g.printf("const (\n")
+		for i, k := range f.Context {
+			if i == 0 {
+				g.printf("%s %s = iota\n", keys[k.Name], keyType)
+			} else {
+				g.printf("%s\n", keys[k.Name])
+			}
+		}
+		g.printf(")\n\n")
+	}
+	g.printf("var %s = fsm.ContextKeyNames{\n", keyNames)
+	for _, k := range f.Context {
+		g.printf("fsm.ContextKey(%s): %q,\n", keys[k.Name], k.Name)
+	}
+	g.printf("}\n\n")
+	g.printf("func (k %s) String() string { return %s[fsm.ContextKey(k)] }\n\n", keyType, keyNames)
+	g.printf("// ContextKey converts k for use with fsm\n")
+	g.printf("func (k %s) ContextKey() fsm.ContextKey { return fsm.ContextKey(k) }\n\n", keyType)
+
+	// Guards
+	guards := f.GuardNames()
+	g.printf("// %s are the guards used by the %s Machine, none can be nil\n", guardsType, o.Name)
+	g.printf("type %s struct {\n", guardsType)
+	for _, name := range guards {
+		if !token.IsIdentifier(exported(name)) {
+			g.errorf("guard '%s' is not a Go identifier", name)
+		}
+		g.printf("%s fsm.Guard\n", exported(name))
+	}
+	g.printf("}\n\n")
+
+	// Definition
+	transition := func(from string, t fsmfile.Transition) {
+		from = fmt.Sprintf("%s on '%s'", from, t.Event)
+		g.printf("{\n")
+		g.printf("State: fsm.State(%s),\n", states[t.Target])
+
+		conditions := []string{}
+		for _, k := range sortedKeys(t.When) {
+			conditions = append(conditions, fmt.Sprintf("fsm.ContextEquals(fsm.ContextKey(%s), %s)", keys[k], value(from, k, t.When[k])))
+		}
+		if t.Guard != "" {
+			conditions = append(conditions, fmt.Sprintf("fsm.NamedGuard(%q, guards.%s)", t.Guard, exported(t.Guard)))
+		}
+		switch len(conditions) {
+		case 0:
+		case 1:
+			g.printf("Condition: %s,\n", conditions[0])
+		default:
+			g.printf("Condition: fsm.And(\n%s,\n),\n", strings.Join(conditions, ",\n"))
+		}
+
+		if len(t.Set) > 0 {
+			g.printf("UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {\n")
+			g.printf("return fsm.UpdateContext{\n")
+			for _, k := range sortedKeys(t.Set) {
+				g.printf("fsm.ContextKey(%s): %s,\n", keys[k], value(from, k, t.Set[k]))
+			}
+			g.printf("}, nil\n")
+			g.printf("},\n")
+		}
+		g.printf("}")
+	}
+
+	g.printf("// %s returns the %s Definition, it panics if a guard is nil\n", definition, o.Name)
+	g.printf("func %s(guards %s) fsm.Definition {\n", definition, guardsType)
+	for _, name := range guards {
+		g.printf("if guards.%s == nil {\n", exported(name))
+		g.printf("panic(%q)\n", fmt.Sprintf("%s.%s is nil", guardsType, exported(name)))
+		g.printf("}\n")
+	}
+	g.printf("\nreturn fsm.Definition{\n")
+	g.printf("StateChangeChannelSize: 1,\n")
+	g.printf("InitialState: fsm.State(%s),\n", states[f.Initial])
+	g.printf("Context: fsm.Context{\n")
+	for _, k := range f.Context {
+		g.printf("fsm.ContextKey(%s): {Protected: %t, Inital: %s},\n", keys[k.Name], k.Protected, value("context", k.Name, k.Initial))
+	}
+	g.printf("},\n")
+	g.printf("Events: []fsm.Event{\n")
+	for _, e := range f.Events {
+		g.printf("fsm.Event(%s),\n", events[e])
+	}
+	g.printf("},\n")
+	g.printf("States: fsm.States{\n")
+	for _, s := range f.States {
+		from := fmt.Sprintf("state '%s'", s.Name)
+		g.printf("fsm.State(%s): {\n", states[s.Name])
+		if s.Final {
+			g.printf("Final: true,\n")
+		}
+
+		order := []string{}
+		candidates := map[string][]fsmfile.Transition{}
+		for _, t := range s.On {
+			if _, ok := candidates[t.Event]; !ok {
+				order = append(order, t.Event)
+			}
+			candidates[t.Event] = append(candidates[t.Event], t)
+		}
+
+		single, several := []string{}, []string{}
+		for _, e := range order {
+			if len(candidates[e]) == 1 {
+				single = append(single, e)
+			} else {
+				several = append(several, e)
+			}
+		}
+
+		if len(single) > 0 {
+			g.printf("Events: fsm.EventToTransition{\n")
+			for _, e := range single {
+				g.printf("fsm.Event(%s): ", events[e])
+				transition(from, candidates[e][0])
+				g.printf(",\n")
+			}
+			g.printf("},\n")
+		}
+		if len(several) > 0 {
+			g.printf("Choices: fsm.EventToTransitions{\n")
+			for _, e := range several {
+				g.printf("fsm.Event(%s): {\n", events[e])
+				for _, t := range candidates[e] {
+					transition(from, t)
+					g.printf(",\n")
+				}
+				g.printf("},\n")
+			}
+			g.printf("},\n")
+		}
+		g.printf("},\n")
+	}
+	g.printf("},\n")
+	if len(f.Wildcards) > 0 {
+		g.printf("Wildcards: fsm.EventToTransition{\n")
+		for _, t := range f.Wildcards {
+			g.printf("fsm.Event(%s): ", events[t.Event])
+			transition("wildcard", t)
+			g.printf(",\n")
+		}
+		g.printf("},\n")
+	}
+	g.printf("StateNames: %s,\n", stateNames)
+	g.printf("EventNames: %s,\n", eventNames)
+	g.printf("ContextKeyNames: %s,\n", keyNames)
+	g.printf("}\n")
+	g.printf("}\n\n")
+
+	// Machine
+	g.printf("// %s is a Machine created from %s, with typed States, Events and\n", machineType, definition)
+	g.printf("// Context\n")
+	g.printf("type %s struct {\n*fsm.Machine\n}\n\n", machineType)
+	g.printf("// %s creates a Machine from %s, it panics if a guard is nil\n", constructor, definition)
+	g.printf("func %s(id string, guards %s) %s {\n", constructor, guardsType, machineType)
+	g.printf("return %s{%s(guards).New(id)}\n", machineType, definition)
+	g.printf("}\n\n")
+	g.printf("// Current returns the State the Machine is in\n")
+	g.printf("func (m %s) Current() %s { return %s(m.Machine.State()) }\n\n", machineType, stateType, stateType)
+	g.printf("// Send an Event, see fsm.Machine.SendEvent\n")
+	g.printf("func (m %s) Send(e %s) bool { return m.SendEvent(fsm.Event(e)) }\n\n", machineType, eventType)
+	g.printf("// SendContext sends an Event, see fsm.Machine.SendEventContext\n")
+	g.printf("func (m %s) SendContext(ctx context.Context, e %s) error {\n", machineType, eventType)
+	g.printf("return m.SendEventContext(ctx, fsm.Event(e))\n")
+	g.printf("}\n")
+
+	for _, k := range f.Context {
+		name := exported(k.Name)
+		typ := types[k.Name]
+		g.printf("\n// %s returns the %s Context value\n", name, k.Name)
+		g.printf("func (m %s) %s() %s {\n", machineType, name, typ)
+		if typ == "interface{}" {
+			g.printf("return m.GetContext(fsm.ContextKey(%s))\n", keys[k.Name])
+		} else {
+			g.printf("v, _ := m.GetContext(fsm.ContextKey(%s)).(%s)\n", keys[k.Name], typ)
+			g.printf("return v\n")
+		}
+		g.printf("}\n")
+
+		if !k.Protected {
+			g.printf("\n// Set%s sets the %s Context value\n", name, k.Name)
+			g.printf("func (m %s) Set%s(v %s) { m.SetContext(fsm.ContextKey(%s), v) }\n", machineType, name, typ, keys[k.Name])
+		}
+	}
+
+	return g.source()
+}
+
+// goType for a value decoded from JSON
+func goType(v interface{}) (string, error) {
+	switch n := v.(type) {
+	case nil:
+		return "interface{}", nil
+	case bool:
+		return "bool", nil
+	case string:
+		return "string", nil
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return "int", nil
+		}
+		return "float64", nil
+	default:
+		return "", fmt.Errorf("unsupported value %v, only null, bools, numbers and strings are", v)
+	}
+}
+
+// goValue writes a value decoded from JSON as a Go literal of typ
+func goValue(v interface{}, typ string) (string, error) {
+	if typ == "interface{}" {
+		if v == nil {
+			return "nil", nil
+		}
+		t, err := goType(v)
+		if err != nil {
+			return "", err
+		}
+		return goValue(v, t)
+	}
+
+	switch n := v.(type) {
+	case bool:
+		if typ == "bool" {
+			return strconv.FormatBool(n), nil
+		}
+	case string:
+		if typ == "string" {
+			return strconv.Quote(n), nil
+		}
+	case float64:
+		if typ == "int" && n == math.Trunc(n) {
+			return strconv.FormatInt(int64(n), 10), nil
+		}
+		if typ == "float64" {
+			return fmt.Sprintf("float64(%s)", strconv.FormatFloat(n, 'g', -1, 64)), nil
+		}
+	}
+	return "", fmt.Errorf("value %v is not of type %s", v, typ)
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func isExported(name string) bool {
+	return token.IsIdentifier(name) && token.IsExported(name)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package fsmgen_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm/fsmfile"
+	"ojkelly.dev/fsm/fsmgen"
+)
+
+// the examples are generated by go generate, and must be up to date
+func Test_ExamplesAreGenerated(t *testing.T) {
+	f, err := fsmfile.Load("example/order/order.json")
+	assert.Nil(t, err)
+	src, err := fsmgen.FromFile(f, fsmgen.Options{Package: "order", Name: "Order", Source: "order.json"})
+	assert.Nil(t, err)
+	generated, err := ioutil.ReadFile("example/order/order_fsm.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(generated), string(src))
+
+	input, err := ioutil.ReadFile("example/counter/counter.go")
+	assert.Nil(t, err)
+	src, err = fsmgen.FromConsts("counter.go", input, fsmgen.Options{Name: "Counter"})
+	assert.Nil(t, err)
+	generated, err = ioutil.ReadFile("example/counter/counter_fsm.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(generated), string(src))
+}
+
+func Test_FromFileErrors(t *testing.T) {
+	generate := func(definition string, o fsmgen.Options) error {
+		f, err := fsmfile.Parse(strings.NewReader(definition))
+		assert.Nil(t, err)
+		_, err = fsmgen.FromFile(f, o)
+		return err
+	}
+	o := fsmgen.Options{Package: "light", Name: "Light"}
+
+	err := generate(`{"initial": "Off", "events": ["On"], "states": [{"name": "Off", "on": [{"event": "On", "target": "Missing"}]}]}`, o)
+	assert.EqualError(t, err, "state 'Off' on 'On': target state 'Missing' is not declared")
+
+	err = generate(`{"initial": "On", "events": ["On"], "states": [{"name": "On"}]}`, o)
+	assert.EqualError(t, err, "event 'On': 'On' is already generated for state 'On'")
+
+	err = generate(`{
+		"initial": "Off",
+		"events": ["Dim"],
+		"context": [{"name": "Level", "initial": 0}, {"name": "Id", "initial": ""}],
+		"states": [{"name": "Off", "on": [{"event": "Dim", "target": "Off", "set": {"Level": 0.5}}]}]
+	}`, o)
+	assert.EqualError(t, err, strings.Join([]string{
+		"context key 'Id': the getter Id() clashes with a method of Light",
+		"state 'Off' on 'Dim': 'Level': value 0.5 is not of type int",
+	}, "\n"))
+
+	err = generate(`{"initial": "Off", "events": [], "states": [{"name": "Off"}]}`, fsmgen.Options{Package: "light", Name: "light"})
+	assert.EqualError(t, err, "fsmgen: name 'light' must be an exported Go identifier")
+}
+
+func Test_FromConstsErrors(t *testing.T) {
+	generate := func(src string) error {
+		_, err := fsmgen.FromConsts("light.go", []byte(src), fsmgen.Options{Name: "Light"})
+		return err
+	}
+
+	err := generate("package light\n\nconst On = 1\n")
+	assert.EqualError(t, err, "light.go: no const blocks annotated with fsm:states, fsm:events or fsm:context")
+
+	err = generate("package light\n\n//fsm:states\nconst (\n\tOff fsm.State = iota\n\tOn = 0\n)\n")
+	assert.EqualError(t, err, "light.go:6:2: On has the same value as Off")
+
+	err = generate("package light\n\n//fsm:events\nconst (\n\tFlick fsm.State = iota\n)\n")
+	assert.EqualError(t, err, "light.go:5:2: the first constant must be of type fsm.Event")
+
+	err = generate("package light\n\n//fsm:states\nconst (\n\tOff fsm.State = iota * 2\n)\n")
+	assert.EqualError(t, err, "light.go:5:2: only iota, integers, + and - are supported in constants")
+}
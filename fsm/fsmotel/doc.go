@@ -0,0 +1,21 @@
+/*
+Package fsmotel turns fsm.Instrumentation into spans on a Tracer, so every
+step of handling an Event shows up in your traces.
+
+It doesn't import OpenTelemetry. Tracer and Span are its own interfaces,
+shaped like trace.Tracer and trace.Span from go.opentelemetry.io/otel/trace,
+so using an OpenTelemetry tracer means writing a small wrapper that converts
+the KeyValues and StatusCodes.
+
+	machine.SetInstrumentation(fsmotel.New(tracer))
+
+For tests, TracerProvider and InMemoryExporter record finished spans without
+needing a collector.
+
+	exporter := fsmotel.NewInMemoryExporter()
+	provider := fsmotel.NewTracerProvider(exporter)
+	machine.SetInstrumentation(fsmotel.New(provider.Tracer("orders")))
+	// ...
+	spans := exporter.GetSpans()
+*/
+package fsmotel // import "ojkelly.dev/fsm/fsmotel"
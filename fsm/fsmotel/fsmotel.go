@@ -0,0 +1,87 @@
+package fsmotel
+
+import (
+	"context"
+
+	"ojkelly.dev/fsm"
+)
+
+// KeyValue mirrors attribute.KeyValue
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// StatusCode mirrors codes.Code
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusError
+	StatusOk
+)
+
+// Tracer mirrors trace.Tracer
+type Tracer interface {
+	Start(ctx context.Context, spanName string, attributes ...KeyValue) (context.Context, Span)
+}
+
+// Span mirrors trace.Span
+type Span interface {
+	SetAttributes(kv ...KeyValue)
+	RecordError(err error)
+	SetStatus(code StatusCode, description string)
+	End()
+}
+
+// AttributeSpanKind is set on every span to the fsm.SpanKind
+const AttributeSpanKind = "fsm.span.kind"
+
+type instrumentation struct {
+	tracer Tracer
+}
+
+// New adapts a Tracer to fsm.Instrumentation
+func New(t Tracer) fsm.Instrumentation {
+	return instrumentation{tracer: t}
+}
+
+func (i instrumentation) Start(
+	ctx context.Context,
+	kind fsm.SpanKind,
+	name string,
+	attrs []fsm.Attribute,
+) (context.Context, fsm.Span) {
+	kv := append(
+		[]KeyValue{{Key: AttributeSpanKind, Value: string(kind)}},
+		toKeyValues(attrs)...,
+	)
+
+	ctx, span := i.tracer.Start(ctx, "fsm "+name, kv...)
+	return ctx, fsmSpan{span: span}
+}
+
+type fsmSpan struct {
+	span Span
+}
+
+func (s fsmSpan) SetAttributes(attrs ...fsm.Attribute) {
+	s.span.SetAttributes(toKeyValues(attrs)...)
+}
+
+func (s fsmSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(StatusError, err.Error())
+}
+
+func (s fsmSpan) End() {
+	s.span.End()
+}
+
+func toKeyValues(attrs []fsm.Attribute) []KeyValue {
+	kv := make([]KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kv = append(kv, KeyValue{Key: a.Key, Value: a.Value})
+	}
+	return kv
+}
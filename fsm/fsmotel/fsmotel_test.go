@@ -0,0 +1,105 @@
+package fsmotel_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmotel"
+)
+
+func Test_Instrumentation(t *testing.T) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+		Deactivate
+	)
+
+	const (
+		KeyCounter fsm.ContextKey = iota
+	)
+
+	exporter := fsmotel.NewInMemoryExporter()
+	tracer := fsmotel.NewTracerProvider(exporter).Tracer("test")
+
+	m := fsm.New(
+		"traced",
+		10,
+		Inactive,
+		fsm.Context{KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0}},
+		[]fsm.Event{Activate, Deactivate},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				OnExit: []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+					// handlers can trace their own work under the handler span
+					_, span := tracer.Start(m.EventContext(), "lookup")
+					span.End()
+				}},
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{
+						State: Active,
+						Guard: func(m *fsm.Machine, current fsm.State, next fsm.State) bool { return true },
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{KeyCounter: 1}, nil
+						},
+					},
+				},
+			},
+			Active: fsm.StateNode{
+				OnEntry: []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {}},
+			},
+		},
+		nil,
+	)
+	m.AddStateNames(fsm.StateNames{Inactive: "Inactive", Active: "Active"})
+	m.AddEventNames(fsm.EventNames{Activate: "Activate", Deactivate: "Deactivate"})
+	m.SetInstrumentation(fsmotel.New(tracer))
+
+	assert.True(t, m.SendEvent(Activate))
+
+	spans := exporter.GetSpans()
+	names := []string{}
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{
+		"fsm Guard Active",
+		"lookup",
+		"fsm Handler OnExit",
+		"fsm ContextUpdate",
+		"fsm Handler OnEntry",
+		"fsm Event Activate",
+	}, names)
+
+	event := spans[5]
+	assert.False(t, event.Parent.IsValid())
+	for _, s := range spans[:5] {
+		assert.Equal(t, event.SpanContext.TraceID, s.SpanContext.TraceID)
+	}
+	assert.Equal(t, event.SpanContext, spans[0].Parent)
+	assert.Equal(t, spans[2].SpanContext, spans[1].Parent, "nested under the handler")
+	assert.Equal(t, event.SpanContext, spans[2].Parent)
+
+	kind, _ := event.Attribute(fsmotel.AttributeSpanKind)
+	assert.Equal(t, string(fsm.SpanEvent), kind)
+	id, _ := event.Attribute(fsm.AttributeMachineId)
+	assert.Equal(t, "traced", id)
+	next, _ := event.Attribute(fsm.AttributeNextState)
+	assert.Equal(t, "Active", next)
+	passed, _ := spans[0].Attribute(fsm.AttributePassed)
+	assert.Equal(t, "true", passed)
+	handler, _ := spans[4].Attribute(fsm.AttributeHandler)
+	assert.Equal(t, "OnEntry", handler)
+
+	// failures are recorded on the Event span
+	exporter.Reset()
+	assert.False(t, m.SendEvent(Activate))
+	spans = exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, fsmotel.StatusError, spans[0].StatusCode)
+	assert.Len(t, spans[0].Errors, 1)
+}
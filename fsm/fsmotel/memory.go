@@ -0,0 +1,179 @@
+package fsmotel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span, like trace.SpanContext
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid is false for the parent of a root span
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != "" && sc.SpanID != ""
+}
+
+// SpanStub is a finished span, like tracetest.SpanStub
+type SpanStub struct {
+	Name                string
+	SpanContext         SpanContext
+	Parent              SpanContext
+	Attributes          []KeyValue
+	Errors              []error
+	StatusCode          StatusCode
+	StatusDescription   string
+	StartTime           time.Time
+	EndTime             time.Time
+	InstrumentationName string
+}
+
+// Attribute returns the value of the last attribute set with key
+func (s SpanStub) Attribute(key string) (string, bool) {
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == key {
+			return s.Attributes[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// SpanExporter receives finished spans, like trace.SpanExporter
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []SpanStub) error
+}
+
+// InMemoryExporter keeps finished spans in memory, for tests
+type InMemoryExporter struct {
+	mtx   sync.Mutex
+	spans []SpanStub
+}
+
+// NewInMemoryExporter creates an InMemoryExporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans implements SpanExporter
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []SpanStub) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// GetSpans returns the spans exported so far, in the order they ended
+func (e *InMemoryExporter) GetSpans() []SpanStub {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	return append([]SpanStub{}, e.spans...)
+}
+
+// Reset forgets the spans exported so far
+func (e *InMemoryExporter) Reset() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.spans = nil
+}
+
+// TracerProvider creates Tracers that export each span as it ends. It's a
+// minimal stand in for the OpenTelemetry SDK, meant for tests.
+type TracerProvider struct {
+	exporter SpanExporter
+}
+
+// NewTracerProvider creates a TracerProvider
+func NewTracerProvider(e SpanExporter) *TracerProvider {
+	return &TracerProvider{exporter: e}
+}
+
+// Tracer returns a Tracer for the instrumentation name
+func (tp *TracerProvider) Tracer(name string) Tracer {
+	return tracer{provider: tp, name: name}
+}
+
+type tracer struct {
+	provider *TracerProvider
+	name     string
+}
+
+type spanContextKey struct{}
+
+func (t tracer) Start(ctx context.Context, spanName string, attributes ...KeyValue) (context.Context, Span) {
+	parent, _ := ctx.Value(spanContextKey{}).(SpanContext)
+
+	sc := SpanContext{TraceID: parent.TraceID, SpanID: newID(8)}
+	if !parent.IsValid() {
+		sc.TraceID = newID(16)
+	}
+
+	s := &span{
+		provider: t.provider,
+		stub: SpanStub{
+			Name:                spanName,
+			SpanContext:         sc,
+			Parent:              parent,
+			Attributes:          append([]KeyValue{}, attributes...),
+			StartTime:           time.Now(),
+			InstrumentationName: t.name,
+		},
+	}
+	return context.WithValue(ctx, spanContextKey{}, sc), s
+}
+
+type span struct {
+	mtx      sync.Mutex
+	provider *TracerProvider
+	stub     SpanStub
+	ended    bool
+}
+
+func (s *span) SetAttributes(kv ...KeyValue) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stub.Attributes = append(s.stub.Attributes, kv...)
+}
+
+func (s *span) RecordError(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stub.Errors = append(s.stub.Errors, err)
+}
+
+func (s *span) SetStatus(code StatusCode, description string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stub.StatusCode = code
+	s.stub.StatusDescription = description
+}
+
+func (s *span) End() {
+	s.mtx.Lock()
+	if s.ended {
+		s.mtx.Unlock()
+		return
+	}
+	s.ended = true
+	s.stub.EndTime = time.Now()
+	stub := s.stub
+	s.mtx.Unlock()
+
+	s.provider.exporter.ExportSpans(context.Background(), []SpanStub{stub})
+}
+
+func newID(bytes int) string {
+	b := make([]byte, bytes)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,118 @@
+package fsmprom
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ojkelly.dev/fsm"
+)
+
+// DefaultBuckets for the time spent in a State, in seconds
+var DefaultBuckets = []float64{0.01, 0.1, 1, 10, 60, 600, 3600, 86400}
+
+// Collector implements fsm.Metrics, and is safe to share between Machines
+type Collector struct {
+	name    string
+	buckets []float64
+
+	mtx         sync.Mutex
+	machines    map[string]float64
+	transitions map[string]float64
+	rejections  map[string]float64
+	errors      map[string]float64
+	dwell       map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewCollector creates a Collector, name is the value of the machine label
+func NewCollector(name string, buckets []float64) *Collector {
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+
+	return &Collector{
+		name:        name,
+		buckets:     b,
+		machines:    map[string]float64{},
+		transitions: map[string]float64{},
+		rejections:  map[string]float64{},
+		errors:      map[string]float64{},
+		dwell:       map[string]*histogram{},
+	}
+}
+
+// labels are kept as a joined key so they can be used in maps
+const labelSeparator = "\x00"
+
+func key(values ...string) string {
+	return strings.Join(values, labelSeparator)
+}
+
+// StateEntered implements fsm.Metrics
+func (c *Collector) StateEntered(machineId string, state string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.machines[state]++
+}
+
+// StateExited implements fsm.Metrics
+func (c *Collector) StateExited(machineId string, state string, dwell time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.machines[state]--
+
+	h, ok := c.dwell[state]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.dwell[state] = h
+	}
+
+	seconds := dwell.Seconds()
+	for i, upper := range c.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// Transition implements fsm.Metrics
+func (c *Collector) Transition(machineId string, from string, event string, to string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.transitions[key(from, event, to)]++
+}
+
+// GuardRejected implements fsm.Metrics
+func (c *Collector) GuardRejected(machineId string, state string, event string, next string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.rejections[key(state, event, next)]++
+}
+
+// MachineError implements fsm.Metrics
+func (c *Collector) MachineError(machineId string, state string, machineError fsm.MachineError) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.errors[key(state, string(machineError))]++
+}
+
+// InState returns how many Machines are currently in a State
+func (c *Collector) InState(state string) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return int(c.machines[state])
+}
@@ -0,0 +1,20 @@
+/*
+Package fsmprom collects fsm.Metrics across many Machines, and serves them in
+the Prometheus text format using only the standard library.
+
+	collector := fsmprom.NewCollector("orders", fsmprom.DefaultBuckets)
+	for _, order := range orders {
+		order.SetMetrics(collector)
+	}
+	http.Handle("/metrics", fsmprom.Handler(collector))
+
+Machines sharing a Collector are counted together, labelled by the name given
+to NewCollector rather than by each Machine's id. The metrics are
+
+	fsm_machines{machine, state}                       gauge
+	fsm_transitions_total{machine, from, event, to}    counter
+	fsm_guard_rejections_total{machine, state, event, next} counter
+	fsm_machine_errors_total{machine, state, error}    counter
+	fsm_state_dwell_seconds{machine, state}            histogram
+*/
+package fsmprom // import "ojkelly.dev/fsm/fsmprom"
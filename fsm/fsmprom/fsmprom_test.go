@@ -0,0 +1,129 @@
+package fsmprom_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmprom"
+)
+
+func Test_Collector(t *testing.T) {
+	const (
+		Placed fsm.State = iota
+		Paid
+	)
+
+	const (
+		Pay fsm.Event = iota
+		Refund
+	)
+
+	const (
+		KeyFunds fsm.ContextKey = iota
+	)
+
+	collector := fsmprom.NewCollector("orders", []float64{1, 60})
+
+	orders := []*fsm.Machine{}
+	for _, id := range []string{"order-1", "order-2", "order-3"} {
+		m := fsm.New(
+			id,
+			10,
+			Placed,
+			fsm.Context{KeyFunds: fsm.ContextMeta{Inital: id != "order-3"}},
+			[]fsm.Event{Pay, Refund},
+			fsm.States{
+				Placed: fsm.StateNode{
+					Events: fsm.EventToTransition{
+						Pay: fsm.Transition{State: Paid, Condition: fsm.ContextIsTrue(KeyFunds)},
+					},
+				},
+				Paid: fsm.StateNode{},
+			},
+			nil,
+		)
+		m.AddStateNames(fsm.StateNames{Placed: "Placed", Paid: "Paid"})
+		m.AddEventNames(fsm.EventNames{Pay: "Pay", Refund: "Refund"})
+		m.SetMetrics(collector)
+		orders = append(orders, m)
+	}
+	assert.Equal(t, 3, collector.InState("Placed"))
+
+	for _, m := range orders {
+		m.SendEvent(Pay)
+	}
+	orders[0].SendEvent(Refund)
+
+	assert.Equal(t, 1, collector.InState("Placed"))
+	assert.Equal(t, 2, collector.InState("Paid"))
+
+	orders[1].Stop()
+	assert.Equal(t, 1, collector.InState("Paid"))
+
+	rec := httptest.NewRecorder()
+	fsmprom.Handler(collector).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body, _ := io.ReadAll(rec.Body)
+	assert.Equal(t, `# HELP fsm_machines Machines currently in each state.
+# TYPE fsm_machines gauge
+fsm_machines{machine="orders",state="Paid"} 1
+fsm_machines{machine="orders",state="Placed"} 1
+# HELP fsm_transitions_total Committed transitions.
+# TYPE fsm_transitions_total counter
+fsm_transitions_total{machine="orders",from="Placed",event="Pay",to="Paid"} 2
+# HELP fsm_guard_rejections_total Candidate transitions rejected by their guards.
+# TYPE fsm_guard_rejections_total counter
+fsm_guard_rejections_total{machine="orders",state="Placed",event="Pay",next="Paid"} 1
+# HELP fsm_machine_errors_total MachineErrors by kind.
+# TYPE fsm_machine_errors_total counter
+fsm_machine_errors_total{machine="orders",state="Paid",error="MachineErrorEventNotFoundForState"} 1
+fsm_machine_errors_total{machine="orders",state="Placed",error="MachineErrorGuardFail"} 1
+# HELP fsm_state_dwell_seconds Time spent in a state before leaving it.
+# TYPE fsm_state_dwell_seconds histogram
+fsm_state_dwell_seconds_bucket{machine="orders",state="Paid",le="1"} 1
+fsm_state_dwell_seconds_bucket{machine="orders",state="Paid",le="60"} 1
+fsm_state_dwell_seconds_bucket{machine="orders",state="Paid",le="+Inf"} 1
+fsm_state_dwell_seconds_sum{machine="orders",state="Paid"} `+dwellSum(t, string(body), "Paid")+`
+fsm_state_dwell_seconds_count{machine="orders",state="Paid"} 1
+fsm_state_dwell_seconds_bucket{machine="orders",state="Placed",le="1"} 2
+fsm_state_dwell_seconds_bucket{machine="orders",state="Placed",le="60"} 2
+fsm_state_dwell_seconds_bucket{machine="orders",state="Placed",le="+Inf"} 2
+fsm_state_dwell_seconds_sum{machine="orders",state="Placed"} `+dwellSum(t, string(body), "Placed")+`
+fsm_state_dwell_seconds_count{machine="orders",state="Placed"} 2
+`, string(body))
+}
+
+// dwellSum pulls the sum out of the output, as it depends on timing
+func dwellSum(t *testing.T, body string, state string) string {
+	prefix := `fsm_state_dwell_seconds_sum{machine="orders",state="` + state + `"} `
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	t.Fatalf("no dwell sum for %s", state)
+	return ""
+}
+
+func Test_DuplicateCollectors(t *testing.T) {
+	first := fsmprom.NewCollector("orders", nil)
+	second := fsmprom.NewCollector("orders", nil)
+
+	var buf bytes.Buffer
+	assert.EqualError(t, fsmprom.WriteText(&buf, first, second), "fsmprom: more than one Collector is named 'orders'")
+	assert.Empty(t, buf.String())
+
+	rec := httptest.NewRecorder()
+	fsmprom.Handler(first, second).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "fsmprom: more than one Collector is named 'orders'\n", rec.Body.String())
+
+	assert.NoError(t, fsmprom.WriteText(&buf, first, fsmprom.NewCollector("payments", nil)))
+}
@@ -0,0 +1,140 @@
+package fsmprom
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Handler serves the metrics of every Collector in the Prometheus text
+// format
+func Handler(collectors ...*Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// written in full first, so an error can still be the response
+		var buf bytes.Buffer
+		if err := WriteText(&buf, collectors...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+// WriteText writes the metrics of every Collector in the Prometheus text
+// format. Each Collector must have a different name, as Prometheus rejects
+// duplicate series.
+func WriteText(w io.Writer, collectors ...*Collector) error {
+	names := map[string]bool{}
+	for _, c := range collectors {
+		if names[c.name] {
+			return fmt.Errorf("fsmprom: more than one Collector is named '%s'", c.name)
+		}
+		names[c.name] = true
+	}
+
+	b := bufio.NewWriter(w)
+
+	family(b, "fsm_machines", "gauge", "Machines currently in each state.")
+	for _, c := range collectors {
+		c.mtx.Lock()
+		for _, k := range sortedKeys(c.machines) {
+			sample(b, "fsm_machines", c.labels(k, "state"), c.machines[k])
+		}
+		c.mtx.Unlock()
+	}
+
+	family(b, "fsm_transitions_total", "counter", "Committed transitions.")
+	for _, c := range collectors {
+		c.mtx.Lock()
+		for _, k := range sortedKeys(c.transitions) {
+			sample(b, "fsm_transitions_total", c.labels(k, "from", "event", "to"), c.transitions[k])
+		}
+		c.mtx.Unlock()
+	}
+
+	family(b, "fsm_guard_rejections_total", "counter", "Candidate transitions rejected by their guards.")
+	for _, c := range collectors {
+		c.mtx.Lock()
+		for _, k := range sortedKeys(c.rejections) {
+			sample(b, "fsm_guard_rejections_total", c.labels(k, "state", "event", "next"), c.rejections[k])
+		}
+		c.mtx.Unlock()
+	}
+
+	family(b, "fsm_machine_errors_total", "counter", "MachineErrors by kind.")
+	for _, c := range collectors {
+		c.mtx.Lock()
+		for _, k := range sortedKeys(c.errors) {
+			sample(b, "fsm_machine_errors_total", c.labels(k, "state", "error"), c.errors[k])
+		}
+		c.mtx.Unlock()
+	}
+
+	family(b, "fsm_state_dwell_seconds", "histogram", "Time spent in a state before leaving it.")
+	for _, c := range collectors {
+		c.mtx.Lock()
+		states := make([]string, 0, len(c.dwell))
+		for s := range c.dwell {
+			states = append(states, s)
+		}
+		sort.Strings(states)
+
+		for _, s := range states {
+			h := c.dwell[s]
+			labels := c.labels(s, "state")
+			for i, upper := range c.buckets {
+				sample(b, "fsm_state_dwell_seconds_bucket", labels+`,le="`+formatFloat(upper)+`"`, float64(h.counts[i]))
+			}
+			sample(b, "fsm_state_dwell_seconds_bucket", labels+`,le="+Inf"`, float64(h.count))
+			sample(b, "fsm_state_dwell_seconds_sum", labels, h.sum)
+			sample(b, "fsm_state_dwell_seconds_count", labels, float64(h.count))
+		}
+		c.mtx.Unlock()
+	}
+
+	return b.Flush()
+}
+
+// labels formats the machine label, followed by the names for a joined key
+func (c *Collector) labels(k string, names ...string) string {
+	values := strings.Split(k, labelSeparator)
+	pairs := []string{`machine="` + escape(c.name) + `"`}
+	for i, n := range names {
+		pairs = append(pairs, n+`="`+escape(values[i])+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func family(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sample(w io.Writer, name string, labels string, value float64) {
+	fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(value))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,12 @@
+/*
+Package fsmslog adapts a *slog.Logger to fsm.Logger, with a level for each
+kind of LogRecord. It needs Go 1.21 or later, the fsm package doesn't.
+
+	machine.SetLogger(fsmslog.New(slog.Default(), fsmslog.Levels{
+		fsm.LogGuard: slog.LevelInfo,
+	}))
+
+LogKinds missing from the Levels use DefaultLevels, which log Transitions and
+MachineErrors, and everything else as debug.
+*/
+package fsmslog // import "ojkelly.dev/fsm/fsmslog"
@@ -0,0 +1,80 @@
+//go:build go1.21
+// +build go1.21
+
+package fsmslog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"ojkelly.dev/fsm"
+)
+
+// Levels sets the slog.Level for each LogKind
+type Levels map[fsm.LogKind]slog.Level
+
+// DefaultLevels logs Transitions and MachineErrors, everything else is debug
+var DefaultLevels = Levels{
+	fsm.LogEventReceived: slog.LevelDebug,
+	fsm.LogGuard:         slog.LevelDebug,
+	fsm.LogTransition:    slog.LevelInfo,
+	fsm.LogContextUpdate: slog.LevelDebug,
+	fsm.LogMachineError:  slog.LevelError,
+}
+
+type logger struct {
+	logger *slog.Logger
+	levels Levels
+}
+
+// New adapts a *slog.Logger to an fsm.Logger. LogKinds missing from levels
+// use DefaultLevels.
+func New(l *slog.Logger, levels Levels) fsm.Logger {
+	merged := Levels{}
+	for k, v := range DefaultLevels {
+		merged[k] = v
+	}
+	for k, v := range levels {
+		merged[k] = v
+	}
+	return logger{logger: l, levels: merged}
+}
+
+func (l logger) Log(ctx context.Context, r fsm.LogRecord) {
+	level := l.levels[r.Kind]
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("kind", string(r.Kind)),
+		slog.String("machine", r.MachineId),
+		slog.String("state", r.State),
+	}
+	if r.Event != "" {
+		attrs = append(attrs, slog.String("event", r.Event))
+	}
+
+	switch r.Kind {
+	case fsm.LogGuard:
+		attrs = append(attrs, slog.String("next", r.Next), slog.Bool("passed", r.Passed))
+		if r.Explanation != "" {
+			attrs = append(attrs, slog.String("explanation", r.Explanation))
+		}
+	case fsm.LogTransition:
+		attrs = append(attrs, slog.String("next", r.Next))
+	case fsm.LogContextUpdate:
+		attrs = append(attrs,
+			slog.String("key", r.ContextKey),
+			slog.String("value", fmt.Sprintf("%v", r.Value)),
+		)
+	case fsm.LogMachineError:
+		attrs = append(attrs, slog.String("machineError", string(r.MachineError)))
+		if r.Error != nil {
+			attrs = append(attrs, slog.String("error", r.Error.Error()))
+		}
+	}
+
+	l.logger.LogAttrs(ctx, level, r.Message, attrs...)
+}
@@ -0,0 +1,87 @@
+//go:build go1.21
+// +build go1.21
+
+package fsmslog_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmslog"
+)
+
+func Test_Logger(t *testing.T) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+		Increment
+	)
+
+	const (
+		KeyIsReady fsm.ContextKey = iota
+		KeyCounter
+	)
+
+	m := fsm.New(
+		"logged",
+		10,
+		Inactive,
+		fsm.Context{
+			KeyIsReady: fsm.ContextMeta{Inital: false},
+			KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0},
+		},
+		[]fsm.Event{Activate, Increment},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{State: Active, Condition: fsm.ContextIsTrue(KeyIsReady)},
+				},
+			},
+			Active: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Increment: fsm.Transition{
+						State: Active,
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{KeyCounter: m.GetContext(KeyCounter).(int) + 1}, nil
+						},
+					},
+				},
+			},
+		},
+		nil,
+	)
+	m.AddStateNames(fsm.StateNames{Inactive: "Inactive", Active: "Active"})
+	m.AddEventNames(fsm.EventNames{Activate: "Activate", Increment: "Increment"})
+	m.AddContextKeyNames(fsm.ContextKeyNames{KeyIsReady: "IsReady", KeyCounter: "Counter"})
+
+	out := &bytes.Buffer{}
+	handler := slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	// guards are debug by default, so only show up when raised
+	m.SetLogger(fsmslog.New(slog.New(handler), fsmslog.Levels{
+		fsm.LogGuard: slog.LevelWarn,
+	}))
+
+	m.SendEvent(Increment)
+	m.SendEvent(Activate)
+	m.SetContext(KeyIsReady, true)
+	m.SendEvent(Activate)
+	m.SendEvent(Increment)
+	m.Error(errors.New("something broke"))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 7)
+	assert.Contains(t, lines[0], `level=ERROR msg="machine error" kind=MachineError machine=logged state=Inactive event=Increment machineError=MachineErrorEventNotFoundForState`)
+	assert.Contains(t, lines[1], `level=WARN msg="guard evaluated" kind=Guard machine=logged state=Inactive event=Activate next=Active passed=false explanation="IsReady is true: fail"`)
+	assert.Contains(t, lines[4], `level=INFO msg=transition kind=Transition machine=logged state=Inactive event=Activate next=Active`)
+	assert.Contains(t, lines[6], `error="something broke"`)
+}
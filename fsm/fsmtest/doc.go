@@ -0,0 +1,34 @@
+/*
+Package fsmtest helps test Machines.
+
+Rather than writing sequences of Events by hand, generate them from the
+graph of a Definition to cover every State, every Transition, or every pair
+of Transitions, and run each one against a real Machine.
+
+	func Test_Order(t *testing.T) {
+		fsmtest.Run(t, orderDefinition, fsmtest.AllTransitions, fsmtest.Options{
+			StubGuards: true,
+		})
+	}
+
+With StubGuards, each guard passes only when the sequence expects its
+Transition to be taken. Otherwise set a Fixture to change the Context before
+each step, so the real guards choose it. A step fails if its Event is
+rejected, a handler panics, or the Machine ends up in another State, and it's
+reported with the shortest sequence of Events that reproduces it.
+
+To write a test by hand, Record a Machine. The Recorder receives its
+StateChanges, and keeps a transcript that can be compared with a golden file
+in testdata, which go test -update writes.
+
+	r := fsmtest.Record(t, machine)
+	r.ExpectRejected(Ship, fsm.MachineErrorEventNotFoundForState)
+	r.Send(Pay, Ship)
+	r.ExpectTransitions(
+		fsm.StateChange{From: Created, To: Paid, Cause: Pay},
+		fsm.StateChange{From: Paid, To: Shipped, Cause: Ship},
+	)
+	r.ExpectContext(KeyInStock, true)
+	r.ExpectGolden("order")
+*/
+package fsmtest // import "ojkelly.dev/fsm/fsmtest"
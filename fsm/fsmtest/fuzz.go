@@ -0,0 +1,153 @@
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"ojkelly.dev/fsm"
+)
+
+// Events decodes fuzz input into Events of d, one for each byte
+func Events(d fsm.Definition, data []byte) []fsm.Event {
+	if len(d.Events) == 0 {
+		return nil
+	}
+
+	events := make([]fsm.Event, 0, len(data))
+	for _, b := range data {
+		events = append(events, d.Events[int(b)%len(d.Events)])
+	}
+	return events
+}
+
+// Counterexample is a sequence of Events that breaks an Invariant, or makes
+// a guard or handler panic
+type Counterexample struct {
+	Events []fsm.Event
+	Err    error
+
+	definition fsm.Definition
+}
+
+func (c *Counterexample) Error() string {
+	m := c.definition.New("counterexample")
+	names := make([]string, 0, len(c.Events))
+	for _, e := range c.Events {
+		names = append(names, m.GetNameForEvent(e))
+	}
+	if len(names) == 0 {
+		names = append(names, "(no events)")
+	}
+	return fmt.Sprintf("%s\nevents: %s", c.Err, strings.Join(names, ", "))
+}
+
+// Replay sends Events to a new Machine from d, and returns the first
+// *fsm.InvariantError or panic. Events that are rejected are skipped.
+func Replay(d fsm.Definition, events []fsm.Event) error {
+	m := d.New("fsmtest")
+	m.SetUnregisteredEventPolicy(fsm.EventPolicyError)
+	m.SetUnhandledEventPolicy(fsm.EventPolicyError)
+	go drain(m)
+	defer m.Stop()
+
+	if err := m.CheckInvariants(); err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		panicked, err := sendEvent(m, e)
+		if !panicked {
+			err = m.CheckInvariants()
+		}
+		if err == nil {
+			continue
+		}
+
+		// builds with the fsmdebug tag panic on a broken Invariant, so it's
+		// reported the same way in either build
+		var invErr *fsm.InvariantError
+		if errors.As(err, &invErr) {
+			invErr.Event = e
+			invErr.HasEvent = true
+			return invErr
+		}
+		return err
+	}
+	return nil
+}
+
+func sendEvent(m *fsm.Machine, e fsm.Event) (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rErr, ok := r.(error); ok {
+				err = fmt.Errorf("panic: %w", rErr)
+			} else {
+				err = fmt.Errorf("panic: %v", r)
+			}
+			panicked = true
+		}
+	}()
+
+	// rejected Events are part of fuzzing, not a failure
+	m.SendEventContext(context.Background(), e)
+	return false, nil
+}
+
+// Minimize returns the shortest Counterexample found by removing Events from
+// events while the same failure still happens, or nil if events don't fail
+func Minimize(d fsm.Definition, events []fsm.Event) *Counterexample {
+	err := Replay(d, events)
+	if err == nil {
+		return nil
+	}
+
+	events = append([]fsm.Event{}, events...)
+	for removed := true; removed; {
+		removed = false
+		for i := 0; i < len(events); i++ {
+			shorter := append(append([]fsm.Event{}, events[:i]...), events[i+1:]...)
+			if shorterErr := Replay(d, shorter); shorterErr != nil && sameFailure(err, shorterErr) {
+				events = shorter
+				err = shorterErr
+				removed = true
+				i--
+			}
+		}
+	}
+
+	return &Counterexample{Events: events, Err: err, definition: d}
+}
+
+// sameFailure is true if both broke the same Invariant, or both panicked
+// with the same value
+func sameFailure(a error, b error) bool {
+	var ai, bi *fsm.InvariantError
+	if errors.As(a, &ai) != errors.As(b, &bi) {
+		return false
+	}
+	if ai != nil {
+		return ai.Invariant == bi.Invariant
+	}
+	return a.Error() == b.Error()
+}
+
+// Fuzz decodes fuzz input into Events for a new Machine from d, and fails t
+// with a minimized Counterexample if one of d.Invariants doesn't hold, or a
+// guard or handler panics. Call it from a fuzz target, which needs Go 1.18:
+//
+//	func FuzzOrder(f *testing.F) {
+//		f.Add([]byte{0, 1, 2})
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			fsmtest.Fuzz(t, orderDefinition, data)
+//		})
+//	}
+func Fuzz(t testing.TB, d fsm.Definition, data []byte) {
+	t.Helper()
+
+	if c := Minimize(d, Events(d, data)); c != nil {
+		t.Fatalf("fsmtest: %s", c)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build go1.18
+// +build go1.18
+
+package fsmtest_test
+
+import (
+	"testing"
+
+	"ojkelly.dev/fsm/fsmtest"
+)
+
+func FuzzCounter(f *testing.F) {
+	f.Add([]byte{0, 2, 3, 3, 1})
+	f.Add([]byte{0, 3})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fsmtest.Fuzz(t, counterDefinition(true), data)
+	})
+}
@@ -0,0 +1,122 @@
+package fsmtest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmtest"
+)
+
+const (
+	Inactive fsm.State = iota + 100
+	Active
+)
+
+const (
+	Activate fsm.Event = iota + 100
+	Deactivate
+	Increment
+	Decrement
+)
+
+const (
+	KeyCounter fsm.ContextKey = iota + 100
+)
+
+// counterDefinition lets the Counter go below zero, unless guarded
+func counterDefinition(guarded bool) fsm.Definition {
+	add := func(n int) fsm.UpdateContextHandler {
+		return func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+			return fsm.UpdateContext{KeyCounter: m.GetContext(KeyCounter).(int) + n}, nil
+		}
+	}
+
+	decrement := fsm.Transition{State: Active, UpdateContext: add(-1)}
+	if guarded {
+		decrement.Condition = fsm.ContextInRange(KeyCounter, 1, 100)
+	}
+
+	return fsm.Definition{
+		StateChangeChannelSize: 1,
+		InitialState:           Inactive,
+		Context:                fsm.Context{KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0}},
+		Events:                 []fsm.Event{Activate, Deactivate, Increment, Decrement},
+		States: fsm.States{
+			Inactive: fsm.StateNode{Events: fsm.EventToTransition{
+				Activate: fsm.Transition{State: Active},
+			}},
+			Active: fsm.StateNode{Events: fsm.EventToTransition{
+				Deactivate: fsm.Transition{State: Inactive},
+				Increment:  fsm.Transition{State: Active, UpdateContext: add(1)},
+				Decrement:  decrement,
+			}},
+		},
+		StateNames: fsm.StateNames{Inactive: "Inactive", Active: "Active"},
+		EventNames: fsm.EventNames{
+			Activate:   "Activate",
+			Deactivate: "Deactivate",
+			Increment:  "Increment",
+			Decrement:  "Decrement",
+		},
+		Invariants: []fsm.Invariant{{
+			Name: "Counter >= 0",
+			Check: func(m *fsm.Machine, s fsm.State) bool {
+				return m.GetContext(KeyCounter).(int) >= 0
+			},
+		}},
+	}
+}
+
+func Test_Minimize(t *testing.T) {
+	d := counterDefinition(false)
+	events := []fsm.Event{Increment, Activate, Increment, Deactivate, Activate, Decrement, Decrement, Increment}
+
+	c := fsmtest.Minimize(d, events)
+	assert.NotNil(t, c)
+	assert.Equal(t, []fsm.Event{Activate, Decrement}, c.Events)
+	assert.EqualError(t, c, "[fsmtest] invariant 'Counter >= 0' does not hold in state 'Active' after event 'Decrement'\nevents: Activate, Decrement")
+
+	assert.Nil(t, fsmtest.Minimize(counterDefinition(true), events))
+	assert.Equal(t, []fsm.Event{Activate, Deactivate, Decrement, Activate}, fsmtest.Events(d, []byte{0, 1, 3, 4}))
+}
+
+func Test_MinimizeKeepsPanic(t *testing.T) {
+	const (
+		Early fsm.State = iota
+		Late
+	)
+
+	const (
+		Go fsm.Event = iota
+		Boom
+	)
+
+	explode := func(message string) []fsm.TransitionEventHandler {
+		return []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+			panic(message)
+		}}
+	}
+
+	d := fsm.Definition{
+		StateChangeChannelSize: 10,
+		InitialState:           Early,
+		Events:                 []fsm.Event{Go, Boom},
+		States: fsm.States{
+			Early: fsm.StateNode{Events: fsm.EventToTransition{
+				Go:   fsm.Transition{State: Late},
+				Boom: fsm.Transition{State: Early, Actions: explode("early")},
+			}},
+			Late: fsm.StateNode{Events: fsm.EventToTransition{
+				Boom: fsm.Transition{State: Late, Actions: explode("late")},
+			}},
+		},
+		EventNames: fsm.EventNames{Go: "Go", Boom: "Boom"},
+	}
+
+	// without Go it still panics, but not in the same way
+	c := fsmtest.Minimize(d, []fsm.Event{Go, Boom})
+	assert.NotNil(t, c)
+	assert.Equal(t, []fsm.Event{Go, Boom}, c.Events)
+	assert.EqualError(t, c, "panic: late\nevents: Go, Boom")
+}
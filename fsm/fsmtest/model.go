@@ -0,0 +1,350 @@
+package fsmtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"ojkelly.dev/fsm"
+)
+
+// Coverage is what the generated sequences must cover
+type Coverage int
+
+const (
+	// AllStates enters every reachable State
+	AllStates Coverage = iota
+	// AllTransitions takes every reachable Transition, including each
+	// candidate of a Choice
+	AllTransitions
+	// AllTransitionPairs takes every Transition into a State followed by
+	// every Transition out of it
+	AllTransitionPairs
+)
+
+func (c Coverage) String() string {
+	switch c {
+	case AllStates:
+		return "AllStates"
+	case AllTransitions:
+		return "AllTransitions"
+	case AllTransitionPairs:
+		return "AllTransitionPairs"
+	}
+	return fmt.Sprintf("Coverage(%d)", int(c))
+}
+
+// Sequence of Transitions to take, from the initial State
+type Sequence []fsm.Edge
+
+// Format the Sequence as its Event names, for example "Pay, Ship"
+func (s Sequence) Format(d fsm.Definition) string {
+	m := d.New("format")
+	names := make([]string, 0, len(s))
+	for _, e := range s {
+		names = append(names, m.GetNameForEvent(e.Event))
+	}
+	if len(names) == 0 {
+		return "(no events)"
+	}
+	return strings.Join(names, ", ")
+}
+
+// graph of a Definition, ignoring guards
+type graph struct {
+	initial fsm.State
+	out     map[fsm.State][]fsm.Edge
+	// paths are the shortest Sequences to each reachable State
+	paths map[fsm.State]Sequence
+}
+
+func newGraph(d fsm.Definition) graph {
+	g := graph{
+		initial: d.InitialState,
+		out:     map[fsm.State][]fsm.Edge{},
+		paths:   map[fsm.State]Sequence{d.InitialState: {}},
+	}
+	for _, e := range d.Edges() {
+		g.out[e.From] = append(g.out[e.From], e)
+	}
+
+	queue := []fsm.State{d.InitialState}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, e := range g.out[s] {
+			if _, ok := g.paths[e.To]; !ok {
+				g.paths[e.To] = append(append(Sequence{}, g.paths[s]...), e)
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	return g
+}
+
+// reachableEdges in the order of d.Edges()
+func (g graph) reachableEdges() []fsm.Edge {
+	states := make([]fsm.State, 0, len(g.paths))
+	for s := range g.paths {
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+
+	edges := []fsm.Edge{}
+	for _, s := range states {
+		edges = append(edges, g.out[s]...)
+	}
+	return edges
+}
+
+// through returns the shortest Sequence that ends with the edges
+func (g graph) through(edges ...fsm.Edge) Sequence {
+	return append(append(Sequence{}, g.paths[edges[0].From]...), edges...)
+}
+
+// Sequences generated from the Definition's graph for a Coverage. Guards are
+// ignored, and a Sequence that is the start of another is left out.
+func Sequences(d fsm.Definition, c Coverage) []Sequence {
+	g := newGraph(d)
+	sequences := []Sequence{}
+
+	switch c {
+	case AllStates:
+		for _, s := range d.SortedStates() {
+			if p, ok := g.paths[s]; ok {
+				sequences = append(sequences, p)
+			}
+		}
+	case AllTransitions:
+		for _, e := range g.reachableEdges() {
+			sequences = append(sequences, g.through(e))
+		}
+	case AllTransitionPairs:
+		for _, in := range g.reachableEdges() {
+			if len(g.out[in.To]) == 0 {
+				sequences = append(sequences, g.through(in))
+			}
+			for _, out := range g.out[in.To] {
+				sequences = append(sequences, g.through(in, out))
+			}
+		}
+	}
+
+	return withoutPrefixes(sequences)
+}
+
+// withoutPrefixes leaves out Sequences that are the start of another
+func withoutPrefixes(sequences []Sequence) []Sequence {
+	kept := []Sequence{}
+	for i, s := range sequences {
+		prefix := false
+		for j, other := range sequences {
+			if i != j && isPrefix(s, other) && (len(s) < len(other) || i > j) {
+				prefix = true
+				break
+			}
+		}
+		if !prefix {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+func isPrefix(s Sequence, of Sequence) bool {
+	if len(s) > len(of) {
+		return false
+	}
+	for i := range s {
+		if !sameEdge(s[i], of[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameEdge(a fsm.Edge, b fsm.Edge) bool {
+	return a.From == b.From && a.Event == b.Event && a.To == b.To && a.Candidate == b.Candidate
+}
+
+// Options for running Sequences
+type Options struct {
+	// StubGuards replaces every guard with one that passes only for the
+	// Transition the Sequence expects to take
+	StubGuards bool
+	// Fixture is called before each step, to set the Context so the guards
+	// choose the expected Transition
+	Fixture func(m *fsm.Machine, step fsm.Edge)
+}
+
+// Failure of a step in a Sequence
+type Failure struct {
+	// Sequence is the shortest found that reproduces the Failure, its last
+	// step is the one that failed
+	Sequence Sequence
+	// Got is the State the Machine was in after the failed step
+	Got fsm.State
+	// Err is why the Event was rejected, or the value of a panic
+	Err error
+
+	definition fsm.Definition
+}
+
+func (f Failure) Error() string {
+	m := f.definition.New("failure")
+	step := f.Sequence[len(f.Sequence)-1]
+	msg := fmt.Sprintf(
+		"after %s: expected %s --%s--> %s",
+		f.Sequence.Format(f.definition),
+		m.GetNameForState(step.From),
+		m.GetNameForEvent(step.Event),
+		m.GetNameForState(step.To),
+	)
+	if f.Err != nil {
+		return fmt.Sprintf("%s, got %s", msg, f.Err)
+	}
+	return fmt.Sprintf("%s, got %s", msg, m.GetNameForState(f.Got))
+}
+
+// Check runs the Sequences for a Coverage, and returns a Failure for each
+// Transition that failed
+func Check(d fsm.Definition, c Coverage, o Options) []Failure {
+	g := newGraph(d)
+	failures := []Failure{}
+	failed := map[string]bool{}
+
+	for _, s := range Sequences(d, c) {
+		f, ok := run(d, s, o)
+		if !ok {
+			continue
+		}
+
+		// the shortest way to the failed step may fail as well
+		step := f.Sequence[len(f.Sequence)-1]
+		if shortest, ok := run(d, g.through(step), o); ok && len(shortest.Sequence) <= len(f.Sequence) {
+			f = shortest
+		}
+
+		key := fmt.Sprint(f.Sequence)
+		if !failed[key] {
+			failed[key] = true
+			failures = append(failures, f)
+		}
+	}
+
+	sort.SliceStable(failures, func(i, j int) bool { return len(failures[i].Sequence) < len(failures[j].Sequence) })
+	return failures
+}
+
+// Run the Sequences for a Coverage, reporting each Failure as a test error
+func Run(t testing.TB, d fsm.Definition, c Coverage, o Options) {
+	t.Helper()
+
+	for _, f := range Check(d, c, o) {
+		t.Errorf("fsmtest %s: %s", c, f)
+	}
+}
+
+// run a Sequence on a new Machine, returning the first step that failed
+func run(d fsm.Definition, s Sequence, o Options) (f Failure, failed bool) {
+	var expected *fsm.Edge
+	if o.StubGuards {
+		d = stubGuards(d, &expected)
+	}
+
+	m := d.New("fsmtest")
+	m.SetUnregisteredEventPolicy(fsm.EventPolicyError)
+	go drain(m)
+	defer m.Stop()
+
+	for i := range s {
+		step := s[i]
+		expected = &step
+
+		got, err := send(m, step, o)
+		if err != nil || got != step.To {
+			return Failure{Sequence: s[:i+1], Got: got, Err: err, definition: d}, true
+		}
+	}
+	return Failure{}, false
+}
+
+// send one step, recovering from a panic in a guard or handler
+func send(m *fsm.Machine, step fsm.Edge, o Options) (got fsm.State, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if o.Fixture != nil {
+		o.Fixture(m, step)
+	}
+	err = m.SendEventContext(context.Background(), step.Event)
+	return m.State(), err
+}
+
+// drain the StateChangeChannel until the Machine is stopped
+func drain(m *fsm.Machine) {
+	for c := range m.StateChangeChannel() {
+		if c.IsLast {
+			return
+		}
+	}
+}
+
+// anyState stands for every State, for wildcard Transitions
+const anyState fsm.State = -1
+
+// stubGuards copies d, with every guard replaced by one that passes only for
+// the expected Transition
+func stubGuards(d fsm.Definition, expected **fsm.Edge) fsm.Definition {
+	// Conditions are described with the Definition's names
+	m := d.New("fsmtest")
+
+	stub := func(from fsm.State, e fsm.Event, candidate int, t fsm.Transition) fsm.Transition {
+		if t.Guard == nil && t.Condition == nil {
+			return t
+		}
+
+		name := "stub"
+		if t.Condition != nil {
+			name = t.Condition.Describe(m)
+		}
+		t.Guard = nil
+		t.Condition = fsm.NamedGuard(name, func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+			x := *expected
+			return x != nil && (x.From == from || from == anyState) && x.Event == e && x.Candidate == candidate
+		})
+		return t
+	}
+
+	states := fsm.States{}
+	for s, node := range d.States {
+		events := fsm.EventToTransition{}
+		choices := fsm.EventToTransitions{}
+		for e, candidates := range node.Choices {
+			for i, t := range candidates {
+				choices[e] = append(choices[e], stub(s, e, i, t))
+			}
+		}
+		for e, t := range node.Events {
+			events[e] = stub(s, e, len(node.Choices[e]), t)
+		}
+		node.Events = events
+		node.Choices = choices
+		states[s] = node
+	}
+	d.States = states
+
+	if d.Wildcards != nil {
+		wildcards := fsm.EventToTransition{}
+		for e, t := range d.Wildcards {
+			wildcards[e] = stub(anyState, e, -1, t)
+		}
+		d.Wildcards = wildcards
+	}
+	return d
+}
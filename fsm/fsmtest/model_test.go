@@ -0,0 +1,161 @@
+package fsmtest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmtest"
+)
+
+const (
+	Created fsm.State = iota
+	Paid
+	Shipped
+	Backordered
+	Refunded
+)
+
+const (
+	Pay fsm.Event = iota
+	Ship
+	Restock
+	Refund
+)
+
+const (
+	KeyInStock fsm.ContextKey = iota
+)
+
+func orderDefinition() fsm.Definition {
+	return fsm.Definition{
+		StateChangeChannelSize: 1,
+		InitialState:           Created,
+		Context:                fsm.Context{KeyInStock: fsm.ContextMeta{Inital: false}},
+		Events:                 []fsm.Event{Pay, Ship, Restock, Refund},
+		States: fsm.States{
+			Created: fsm.StateNode{
+				Events: fsm.EventToTransition{Pay: fsm.Transition{State: Paid}},
+			},
+			Paid: fsm.StateNode{
+				Choices: fsm.EventToTransitions{
+					Ship: {
+						{State: Shipped, Condition: fsm.ContextIsTrue(KeyInStock)},
+						{State: Backordered},
+					},
+				},
+			},
+			Backordered: fsm.StateNode{
+				Events: fsm.EventToTransition{Restock: fsm.Transition{State: Paid}},
+			},
+			Shipped:  fsm.StateNode{Final: true},
+			Refunded: fsm.StateNode{Final: true},
+		},
+		Wildcards: fsm.EventToTransition{
+			Refund: fsm.Transition{State: Refunded},
+		},
+		StateNames: fsm.StateNames{
+			Created:     "Created",
+			Paid:        "Paid",
+			Shipped:     "Shipped",
+			Backordered: "Backordered",
+			Refunded:    "Refunded",
+		},
+		EventNames: fsm.EventNames{Pay: "Pay", Ship: "Ship", Restock: "Restock", Refund: "Refund"},
+	}
+}
+
+func formatAll(d fsm.Definition, sequences []fsmtest.Sequence) []string {
+	formatted := []string{}
+	for _, s := range sequences {
+		formatted = append(formatted, s.Format(d))
+	}
+	return formatted
+}
+
+func Test_Sequences(t *testing.T) {
+	d := orderDefinition()
+
+	// Ship twice, once for each candidate
+	states := fsmtest.Sequences(d, fsmtest.AllStates)
+	assert.Equal(t, []string{
+		"Pay, Ship",
+		"Pay, Ship",
+		"Refund",
+	}, formatAll(d, states))
+	assert.Equal(t, Shipped, states[0][1].To)
+	assert.Equal(t, Backordered, states[1][1].To)
+
+	// Refund is a wildcard, so every State has it
+	assert.Equal(t, []string{
+		"Pay, Refund",
+		"Pay, Ship, Refund",
+		"Pay, Ship, Restock",
+		"Pay, Ship, Refund",
+		"Refund, Refund",
+	}, formatAll(d, fsmtest.Sequences(d, fsmtest.AllTransitions)))
+
+	pairs := fsmtest.Sequences(d, fsmtest.AllTransitionPairs)
+	assert.Len(t, pairs, 7)
+	assert.Contains(t, formatAll(d, pairs), "Pay, Ship, Restock, Ship")
+	assert.Contains(t, formatAll(d, pairs), "Pay, Ship, Restock, Refund")
+}
+
+func Test_Run(t *testing.T) {
+	d := orderDefinition()
+
+	fsmtest.Run(t, d, fsmtest.AllTransitionPairs, fsmtest.Options{StubGuards: true})
+
+	// the real guards, driven by the Context
+	fsmtest.Run(t, d, fsmtest.AllTransitions, fsmtest.Options{
+		Fixture: func(m *fsm.Machine, step fsm.Edge) {
+			m.SetContext(KeyInStock, step.From == Paid && step.To == Shipped)
+		},
+	})
+}
+
+func Test_Failures(t *testing.T) {
+	d := orderDefinition()
+	d.States[Backordered] = fsm.StateNode{
+		OnEntry: []fsm.TransitionEventHandler{func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+			panic("out of stock")
+		}},
+	}
+
+	// without stubs or fixtures, InStock is never true, so Backordered is
+	// entered even when Shipped is expected
+	failures := fsmtest.Check(d, fsmtest.AllTransitionPairs, fsmtest.Options{})
+	messages := []string{}
+	for _, f := range failures {
+		messages = append(messages, f.Error())
+	}
+	assert.Equal(t, []string{
+		"after Pay, Ship: expected Paid --Ship--> Shipped, got panic: out of stock",
+		"after Pay, Ship: expected Paid --Ship--> Backordered, got panic: out of stock",
+	}, messages)
+
+	d = orderDefinition()
+	failures = fsmtest.Check(d, fsmtest.AllTransitions, fsmtest.Options{})
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "after Pay, Ship: expected Paid --Ship--> Shipped, got Backordered", failures[0].Error())
+}
+
+// stockCondition describes itself with the Machine's names, like a
+// Condition outside of fsm would
+type stockCondition struct{}
+
+func (stockCondition) Describe(m *fsm.Machine) string {
+	return m.GetNameForContextKey(KeyInStock) + " in the warehouse"
+}
+
+func (stockCondition) Evaluate(m *fsm.Machine, current fsm.State, next fsm.State) fsm.GuardResult {
+	return fsm.GuardResult{Name: "in the warehouse", Passed: m.GetContext(KeyInStock) == true}
+}
+
+func Test_RunStubbedCondition(t *testing.T) {
+	d := orderDefinition()
+	choices := d.States[Paid].Choices
+	choices[Ship][0].Condition = stockCondition{}
+
+	assert.Empty(t, fsmtest.Check(d, fsmtest.AllTransitionPairs, fsmtest.Options{StubGuards: true}))
+}
@@ -0,0 +1,346 @@
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ojkelly.dev/fsm"
+)
+
+// updateFlag makes ExpectGolden write golden transcripts. It's only
+// registered if nothing else has, and always read with flag.Lookup, so it's
+// shared with other golden file helpers instead of clashing with them.
+const updateFlag = "update"
+
+func init() {
+	if flag.Lookup(updateFlag) == nil {
+		flag.Bool(updateFlag, false, "write golden files instead of comparing with them")
+	}
+}
+
+// updating is true if go test was run with -update
+func updating() bool {
+	f := flag.Lookup(updateFlag)
+	if f == nil {
+		return false
+	}
+	if getter, ok := f.Value.(flag.Getter); ok {
+		if update, ok := getter.Get().(bool); ok {
+			return update
+		}
+	}
+	return f.Value.String() == "true"
+}
+
+// stateChangeTimeout is how long Send waits for its StateChange to be
+// received
+const stateChangeTimeout = time.Second
+
+// Recorder wraps a Machine for a test. It receives every StateChange, so the
+// test doesn't have to, and keeps a transcript of them and of every Event and
+// Context value it's given.
+type Recorder struct {
+	t testing.TB
+	m *fsm.Machine
+
+	mtx     sync.Mutex
+	changed *sync.Cond
+	// changes are every StateChange received, checked is how many have been
+	// passed to ExpectTransitions, and written is how many are in the
+	// transcript
+	changes []fsm.StateChange
+	checked int
+	written int
+	context map[fsm.ContextKey]interface{}
+	lines   []string
+	done    chan struct{}
+}
+
+// Record starts receiving StateChanges from m, which is stopped when the
+// test finishes
+func Record(t testing.TB, m *fsm.Machine) *Recorder {
+	t.Helper()
+
+	r := &Recorder{
+		t:       t,
+		m:       m,
+		context: contextSnapshot(m),
+		done:    make(chan struct{}),
+	}
+	r.changed = sync.NewCond(&r.mtx)
+
+	go r.receive()
+	t.Cleanup(r.stop)
+	return r
+}
+
+// Machine being recorded
+func (r *Recorder) Machine() *fsm.Machine {
+	return r.m
+}
+
+func (r *Recorder) receive() {
+	defer close(r.done)
+	for c := range r.m.StateChangeChannel() {
+		if c.IsLast {
+			return
+		}
+
+		r.mtx.Lock()
+		r.changes = append(r.changes, c)
+		r.changed.Broadcast()
+		r.mtx.Unlock()
+	}
+}
+
+// stop the Machine, without waiting forever for a handler that's stuck
+func (r *Recorder) stop() {
+	go r.m.Stop()
+	select {
+	case <-r.done:
+	case <-time.After(stateChangeTimeout):
+	}
+}
+
+// Send Events to the Machine, failing the test if one is rejected
+func (r *Recorder) Send(events ...fsm.Event) {
+	r.t.Helper()
+
+	for _, e := range events {
+		if err := r.send(e); err != nil {
+			r.t.Fatalf("fsmtest: %s", err)
+		}
+	}
+}
+
+// ExpectRejected sends an Event, and fails the test unless it's rejected
+// with kind, without leaving the current State
+func (r *Recorder) ExpectRejected(e fsm.Event, kind fsm.MachineError) {
+	r.t.Helper()
+
+	before := r.m.State()
+	err := r.send(e)
+
+	var tErr *fsm.TransitionError
+	switch {
+	case err == nil:
+		r.t.Errorf(
+			"fsmtest: expected event '%s' to be rejected with %s, it was taken",
+			r.m.GetNameForEvent(e),
+			kind,
+		)
+	case !errors.As(err, &tErr):
+		r.t.Errorf("fsmtest: expected event '%s' to be rejected with %s, got %s", r.m.GetNameForEvent(e), kind, err)
+	case tErr.Kind != kind:
+		r.t.Errorf("fsmtest: expected event '%s' to be rejected with %s, got %s", r.m.GetNameForEvent(e), kind, tErr.Kind)
+	}
+
+	if after := r.m.State(); after != before {
+		r.t.Errorf(
+			"fsmtest: expected rejected event '%s' to stay in state '%s', got '%s'",
+			r.m.GetNameForEvent(e),
+			r.m.GetNameForState(before),
+			r.m.GetNameForState(after),
+		)
+	}
+}
+
+// send an Event, and wait for its StateChange to be received
+func (r *Recorder) send(e fsm.Event) error {
+	r.mtx.Lock()
+	from := len(r.changes)
+	r.mtx.Unlock()
+
+	state := r.m.State()
+	err := r.m.SendEventContext(context.Background(), e)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if err != nil {
+		r.write()
+		r.lines = append(r.lines, fmt.Sprintf(
+			"%s --%s--> rejected: %s",
+			r.m.GetNameForState(state),
+			r.m.GetNameForEvent(e),
+			rejection(err),
+		))
+		return err
+	}
+
+	if !r.waitFor(from, e) {
+		return fmt.Errorf("timed out waiting for the StateChange caused by event '%s'", r.m.GetNameForEvent(e))
+	}
+	r.write()
+	return nil
+}
+
+// waitFor a StateChange caused by e after the first n, it must be called
+// while holding r.mtx
+func (r *Recorder) waitFor(n int, e fsm.Event) bool {
+	timedOut := false
+	timer := time.AfterFunc(stateChangeTimeout, func() {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		timedOut = true
+		r.changed.Broadcast()
+	})
+	defer timer.Stop()
+
+	for {
+		for _, c := range r.changes[n:] {
+			if c.Cause == e {
+				return true
+			}
+		}
+		if timedOut {
+			return false
+		}
+		r.changed.Wait()
+	}
+}
+
+func rejection(err error) string {
+	var tErr *fsm.TransitionError
+	if errors.As(err, &tErr) {
+		return string(tErr.Kind)
+	}
+	return err.Error()
+}
+
+// write the StateChanges received since the last write to the transcript,
+// followed by any Context values that changed. It must be called while
+// holding r.mtx.
+func (r *Recorder) write() {
+	for _, c := range r.changes[r.written:] {
+		r.lines = append(r.lines, r.formatChange(c))
+	}
+	r.written = len(r.changes)
+
+	for _, v := range r.m.ContextValues() {
+		if old, ok := r.context[v.Key]; !ok || !reflect.DeepEqual(old, v.Value) {
+			r.lines = append(r.lines, fmt.Sprintf("  %s: %#v -> %#v", v.Name, old, v.Value))
+			r.context[v.Key] = v.Value
+		}
+	}
+}
+
+// Set a Context value, and record it in the transcript
+func (r *Recorder) Set(key fsm.ContextKey, value interface{}) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.write()
+	r.m.SetContext(key, value)
+	r.lines = append(r.lines, fmt.Sprintf("set %s = %#v", r.m.GetNameForContextKey(key), value))
+	r.context[key] = value
+}
+
+// ExpectTransitions fails the test unless the StateChanges received since
+// the last call are the expected ones, in order
+func (r *Recorder) ExpectTransitions(expected ...fsm.StateChange) {
+	r.t.Helper()
+
+	r.mtx.Lock()
+	got := append([]fsm.StateChange{}, r.changes[r.checked:]...)
+	r.checked = len(r.changes)
+	r.mtx.Unlock()
+
+	if reflect.DeepEqual(r.format(expected), r.format(got)) {
+		return
+	}
+	r.t.Errorf(
+		"fsmtest: expected transitions:\n\t%s\ngot:\n\t%s",
+		strings.Join(r.format(expected), "\n\t"),
+		strings.Join(r.format(got), "\n\t"),
+	)
+}
+
+func (r *Recorder) format(changes []fsm.StateChange) []string {
+	lines := make([]string, 0, len(changes))
+	for _, c := range changes {
+		lines = append(lines, r.formatChange(c))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "(none)")
+	}
+	return lines
+}
+
+func (r *Recorder) formatChange(c fsm.StateChange) string {
+	return fmt.Sprintf(
+		"%s --%s--> %s",
+		r.m.GetNameForState(c.From),
+		r.m.GetNameForEvent(c.Cause),
+		r.m.GetNameForState(c.To),
+	)
+}
+
+// ExpectContext fails the test unless the Context value for key is expected
+func (r *Recorder) ExpectContext(key fsm.ContextKey, expected interface{}) {
+	r.t.Helper()
+
+	if got := r.m.GetContext(key); !reflect.DeepEqual(expected, got) {
+		r.t.Errorf(
+			"fsmtest: expected context '%s' to be %#v, got %#v",
+			r.m.GetNameForContextKey(key),
+			expected,
+			got,
+		)
+	}
+}
+
+// Transcript of every Event and Context value given to the Recorder, and
+// every StateChange received, one per line
+func (r *Recorder) Transcript() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.write()
+	return strings.Join(r.lines, "\n") + "\n"
+}
+
+// ExpectGolden fails the test unless the transcript matches
+// testdata/<name>.golden. Run go test -update to write it instead.
+func (r *Recorder) ExpectGolden(name string) {
+	r.t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	got := r.Transcript()
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatalf("fsmtest: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			r.t.Fatalf("fsmtest: %s", err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("fsmtest: %s, run go test -update to create it", err)
+	}
+	if string(expected) != got {
+		r.t.Errorf("fsmtest: transcript doesn't match %s, run go test -update if this is expected\nexpected:\n%s\ngot:\n%s", path, expected, got)
+	}
+}
+
+func contextSnapshot(m *fsm.Machine) map[fsm.ContextKey]interface{} {
+	values := map[fsm.ContextKey]interface{}{}
+	for _, v := range m.ContextValues() {
+		values[v.Key] = v.Value
+	}
+	return values
+}
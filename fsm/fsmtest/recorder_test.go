@@ -0,0 +1,87 @@
+package fsmtest_test
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmtest"
+)
+
+// reportedErrors records failures instead of failing the test
+type reportedErrors struct {
+	testing.TB
+	messages []string
+}
+
+func (e *reportedErrors) Errorf(format string, args ...interface{}) {
+	e.messages = append(e.messages, fmt.Sprintf(format, args...))
+}
+
+func Test_Recorder(t *testing.T) {
+	r := fsmtest.Record(t, orderDefinition().New("order"))
+
+	r.ExpectRejected(Ship, fsm.MachineErrorEventNotFoundForState)
+	r.Send(Pay)
+	r.Set(KeyInStock, true)
+	r.Send(Ship)
+
+	r.ExpectTransitions(
+		fsm.StateChange{From: Created, To: Paid, Cause: Pay},
+		fsm.StateChange{From: Paid, To: Shipped, Cause: Ship},
+	)
+	r.ExpectContext(KeyInStock, true)
+	assert.Equal(t, "Created --Ship--> rejected: MachineErrorEventNotFoundForState\n"+
+		"Created --Pay--> Paid\n"+
+		"set 0 = true\n"+
+		"Paid --Ship--> Shipped\n", r.Transcript())
+
+	// failures are reported with names
+	e := &reportedErrors{TB: t}
+	r = fsmtest.Record(e, orderDefinition().New("order"))
+	r.ExpectRejected(Pay, fsm.MachineErrorEventNotFoundForState)
+	r.ExpectTransitions()
+	r.ExpectContext(KeyInStock, true)
+	assert.Equal(t, []string{
+		"fsmtest: expected event 'Pay' to be rejected with MachineErrorEventNotFoundForState, it was taken",
+		"fsmtest: expected rejected event 'Pay' to stay in state 'Created', got 'Paid'",
+		"fsmtest: expected transitions:\n\t(none)\ngot:\n\tCreated --Pay--> Paid",
+		"fsmtest: expected context '0' to be true, got false",
+	}, e.messages)
+}
+
+func Test_RecorderGolden(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	// -update writes the golden file
+	assert.NoError(t, flag.Set("update", "true"))
+	defer flag.Set("update", "false")
+	r := fsmtest.Record(t, orderDefinition().New("order"))
+	r.Send(Pay)
+	r.ExpectGolden("order")
+	assert.NoError(t, flag.Set("update", "false"))
+
+	written, err := ioutil.ReadFile(filepath.Join("testdata", "order.golden"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Created --Pay--> Paid\n", string(written))
+
+	// then compares with it
+	e := &reportedErrors{TB: t}
+	r = fsmtest.Record(e, orderDefinition().New("order"))
+	r.Send(Pay)
+	r.ExpectGolden("order")
+	assert.Empty(t, e.messages)
+
+	r.Send(Ship)
+	r.ExpectGolden("order")
+	assert.Len(t, e.messages, 1)
+	assert.Contains(t, e.messages[0], "run go test -update if this is expected")
+}
@@ -0,0 +1,251 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Condition is a named Guard that can be combined with And, Or and Not.
+// Unlike a Guard, evaluating a Condition explains which part of it failed.
+//
+//	Condition: fsm.And(
+//		fsm.ContextIsTrue(KeyIsReady),
+//		fsm.Not(fsm.ContextEquals(KeyCounter, 0)),
+//	)
+type Condition interface {
+	// Describe returns a readable name, using the Machine's debug names
+	Describe(m *Machine) string
+	// Evaluate the Condition for a Transition from current to next
+	Evaluate(m *Machine, current State, next State) GuardResult
+}
+
+// GuardResult is the explanation tree produced by evaluating a Condition
+type GuardResult struct {
+	Name     string
+	Passed   bool
+	Children []GuardResult
+}
+
+// String returns the explanation on one line, for example
+//
+//	And: fail [IsReady is true: fail, Counter == 0: pass]
+func (r GuardResult) String() string {
+	status := "fail"
+	if r.Passed {
+		status = "pass"
+	}
+
+	if len(r.Children) == 0 {
+		return fmt.Sprintf("%s: %s", r.Name, status)
+	}
+
+	children := make([]string, 0, len(r.Children))
+	for _, c := range r.Children {
+		children = append(children, c.String())
+	}
+	return fmt.Sprintf("%s: %s [%s]", r.Name, status, strings.Join(children, ", "))
+}
+
+func (t Transition) guarded() bool {
+	return t.Guard != nil || t.Condition != nil
+}
+
+// evaluateGuards runs the Guard and then the Condition of a Transition
+func (m *Machine) evaluateGuards(t Transition, current State) (bool, *GuardResult) {
+	if t.Guard != nil && !t.Guard(m, current, t.State) {
+		return false, nil
+	}
+
+	if t.Condition == nil {
+		return true, nil
+	}
+
+	result := t.Condition.Evaluate(m, current, t.State)
+	return result.Passed, &result
+}
+
+type namedGuard struct {
+	name  string
+	guard Guard
+}
+
+// NamedGuard gives a Guard a name, so it can be used as a Condition
+func NamedGuard(name string, g Guard) Condition {
+	return namedGuard{name: name, guard: g}
+}
+
+func (g namedGuard) Describe(m *Machine) string {
+	return g.name
+}
+
+func (g namedGuard) Evaluate(m *Machine, current State, next State) GuardResult {
+	return GuardResult{Name: g.name, Passed: g.guard(m, current, next)}
+}
+
+type andCondition []Condition
+
+// And passes if all Conditions pass. Every Condition is evaluated so the
+// result explains each one.
+func And(c ...Condition) Condition {
+	return andCondition(c)
+}
+
+func (c andCondition) Describe(m *Machine) string {
+	return describeAll(m, "And", c)
+}
+
+func (c andCondition) Evaluate(m *Machine, current State, next State) GuardResult {
+	result := GuardResult{Name: "And", Passed: true}
+	for _, child := range c {
+		r := child.Evaluate(m, current, next)
+		result.Passed = result.Passed && r.Passed
+		result.Children = append(result.Children, r)
+	}
+	return result
+}
+
+type orCondition []Condition
+
+// Or passes if any of the Conditions pass. Every Condition is evaluated so
+// the result explains each one.
+func Or(c ...Condition) Condition {
+	return orCondition(c)
+}
+
+func (c orCondition) Describe(m *Machine) string {
+	return describeAll(m, "Or", c)
+}
+
+func (c orCondition) Evaluate(m *Machine, current State, next State) GuardResult {
+	result := GuardResult{Name: "Or", Passed: false}
+	for _, child := range c {
+		r := child.Evaluate(m, current, next)
+		result.Passed = result.Passed || r.Passed
+		result.Children = append(result.Children, r)
+	}
+	return result
+}
+
+type notCondition struct {
+	c Condition
+}
+
+// Not passes if the Condition fails
+func Not(c Condition) Condition {
+	return notCondition{c: c}
+}
+
+func (c notCondition) Describe(m *Machine) string {
+	return fmt.Sprintf("Not(%s)", c.c.Describe(m))
+}
+
+func (c notCondition) Evaluate(m *Machine, current State, next State) GuardResult {
+	r := c.c.Evaluate(m, current, next)
+	return GuardResult{
+		Name:     "Not",
+		Passed:   !r.Passed,
+		Children: []GuardResult{r},
+	}
+}
+
+func describeAll(m *Machine, name string, c []Condition) string {
+	parts := make([]string, 0, len(c))
+	for _, child := range c {
+		parts = append(parts, child.Describe(m))
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(parts, ", "))
+}
+
+// contextCondition is a Condition over a single ContextKey
+type contextCondition struct {
+	key      ContextKey
+	describe func(key string) string
+	check    func(value interface{}) bool
+}
+
+func (c contextCondition) Describe(m *Machine) string {
+	if m == nil {
+		return c.describe(fmt.Sprintf("%d", c.key))
+	}
+	return c.describe(m.GetNameForContextKey(c.key))
+}
+
+func (c contextCondition) Evaluate(m *Machine, current State, next State) GuardResult {
+	return GuardResult{
+		Name:   c.Describe(m),
+		Passed: c.check(m.GetContext(c.key)),
+	}
+}
+
+// ContextEquals passes if the value for key equals value, which must be
+// comparable
+func ContextEquals(key ContextKey, value interface{}) Condition {
+	return contextCondition{
+		key: key,
+		describe: func(k string) string {
+			return fmt.Sprintf("%s == %v", k, value)
+		},
+		check: func(v interface{}) bool {
+			return v == value
+		},
+	}
+}
+
+// ContextIsTrue passes if the value for key is the bool true
+func ContextIsTrue(key ContextKey) Condition {
+	return contextCondition{
+		key: key,
+		describe: func(k string) string {
+			return fmt.Sprintf("%s is true", k)
+		},
+		check: func(v interface{}) bool {
+			b, ok := v.(bool)
+			return ok && b
+		},
+	}
+}
+
+// ContextInRange passes if the value for key is a number between min and
+// max, inclusive
+func ContextInRange(key ContextKey, min float64, max float64) Condition {
+	return contextCondition{
+		key: key,
+		describe: func(k string) string {
+			return fmt.Sprintf("%s in [%v, %v]", k, min, max)
+		},
+		check: func(v interface{}) bool {
+			f, ok := toFloat(v)
+			return ok && f >= min && f <= max
+		},
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
@@ -0,0 +1,152 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_Conditions(t *testing.T) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+	)
+
+	const (
+		KeyIsReady fsm.ContextKey = iota
+		KeyCounter
+		KeyMode
+	)
+
+	hasLicence := false
+
+	condition := fsm.And(
+		fsm.ContextIsTrue(KeyIsReady),
+		fsm.Or(
+			fsm.ContextInRange(KeyCounter, 0, 10),
+			fsm.ContextEquals(KeyMode, "admin"),
+		),
+		fsm.Not(fsm.NamedGuard("HasLicence", func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+			return hasLicence
+		})),
+	)
+
+	m := fsm.New(
+		"conditions",
+		10,
+		Inactive,
+		fsm.Context{
+			KeyIsReady: fsm.ContextMeta{Inital: false},
+			KeyCounter: fsm.ContextMeta{Inital: 20},
+			KeyMode:    fsm.ContextMeta{Inital: "user"},
+		},
+		[]fsm.Event{Activate},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{
+						State:     Active,
+						Condition: condition,
+					},
+				},
+			},
+		},
+		nil,
+	)
+	m.AddStateNames(fsm.StateNames{Inactive: "Inactive", Active: "Active"})
+	m.AddEventNames(fsm.EventNames{Activate: "Activate"})
+	m.AddContextKeyNames(fsm.ContextKeyNames{
+		KeyIsReady: "IsReady",
+		KeyCounter: "Counter",
+		KeyMode:    "Mode",
+	})
+
+	assert.Equal(
+		t,
+		"And(IsReady is true, Or(Counter in [0, 10], Mode == admin), Not(HasLicence))",
+		condition.Describe(m),
+	)
+
+	assert.False(t, m.SendEvent(Activate))
+
+	var tErr *fsm.TransitionError
+	assert.True(t, errors.As(m.LastError(), &tErr))
+	assert.Len(t, tErr.Tried, 1)
+
+	explained := tErr.Tried[0].Result.String()
+	assert.Equal(
+		t,
+		"And: fail [IsReady is true: fail, Or: fail [Counter in [0, 10]: fail, Mode == admin: fail], Not: pass [HasLicence: fail]]",
+		explained,
+	)
+
+	m.SetContext(KeyIsReady, true)
+	m.SetContext(KeyMode, "admin")
+	hasLicence = true
+	assert.False(t, m.SendEvent(Activate))
+	assert.True(t, errors.As(m.LastError(), &tErr))
+	assert.Contains(t, tErr.Error(), "Not: fail [HasLicence: pass]")
+
+	hasLicence = false
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	assert.True(t, m.SendEvent(Activate))
+	assert.Equal(t, Active, m.State())
+}
+
+func Test_ConditionsErrorHandler(t *testing.T) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+	)
+
+	const (
+		KeyIsReady fsm.ContextKey = iota
+	)
+
+	kinds := []fsm.MachineError{}
+	explained := []string{}
+
+	m := fsm.New(
+		"conditions",
+		10,
+		Inactive,
+		fsm.Context{KeyIsReady: fsm.ContextMeta{Inital: false}},
+		[]fsm.Event{Activate},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{
+						State:     Active,
+						Condition: fsm.ContextIsTrue(KeyIsReady),
+					},
+				},
+			},
+		},
+		func(m *fsm.Machine, current fsm.State, next fsm.State, kind fsm.MachineError) {
+			kinds = append(kinds, kind)
+
+			var tErr *fsm.TransitionError
+			if errors.As(m.LastError(), &tErr) && len(tErr.Tried) > 0 {
+				explained = append(explained, tErr.Tried[0].Result.String())
+			}
+		},
+	)
+	m.AddContextKeyNames(fsm.ContextKeyNames{KeyIsReady: "IsReady"})
+
+	assert.False(t, m.SendEvent(Activate))
+	assert.Equal(t, []fsm.MachineError{fsm.MachineErrorGuardFail}, kinds)
+	assert.Equal(t, []string{"IsReady is true: fail"}, explained)
+}
@@ -0,0 +1,147 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryEntry records one Event handled by a Machine, whether or not it
+// caused a Transition
+type HistoryEntry struct {
+	From  State
+	To    State
+	Event Event
+	Time  time.Time
+	// InPrevious is how long the Machine had been in From
+	InPrevious time.Duration
+	// Committed is true if the Transition happened
+	Committed bool
+	// Guards that were evaluated, in order
+	Guards []GuardAttempt
+	// Err is why the Event didn't cause a Transition, or why the
+	// StateChange wasn't sent
+	Err error
+}
+
+type historyBuffer struct {
+	entries []HistoryEntry
+	next    int
+	full    bool
+}
+
+func (h *historyBuffer) add(e HistoryEntry) {
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+func (h *historyBuffer) list() []HistoryEntry {
+	if !h.full {
+		return append([]HistoryEntry{}, h.entries[:h.next]...)
+	}
+	return append(
+		append([]HistoryEntry{}, h.entries[h.next:]...),
+		h.entries[:h.next]...,
+	)
+}
+
+// EnableHistory keeps the last size Events handled by the Machine, see
+// m.History(). Calling it again clears the history.
+func (m *Machine) EnableHistory(size int) {
+	m.checkIfCreatedCorrectly()
+	m.historyMtx.Lock()
+	defer m.historyMtx.Unlock()
+
+	if size <= 0 {
+		m.history = nil
+		return
+	}
+	m.history = &historyBuffer{entries: make([]HistoryEntry, size)}
+}
+
+// History returns the recent Events handled by the Machine, oldest first.
+// It's empty unless m.EnableHistory() has been called.
+func (m *Machine) History() []HistoryEntry {
+	m.checkIfCreatedCorrectly()
+	m.historyMtx.Lock()
+	defer m.historyMtx.Unlock()
+
+	if m.history == nil {
+		return []HistoryEntry{}
+	}
+	return m.history.list()
+}
+
+func (m *Machine) recordHistory(e HistoryEntry) {
+	m.historyMtx.Lock()
+	defer m.historyMtx.Unlock()
+
+	if m.history != nil {
+		m.history.add(e)
+	}
+}
+
+// FormatHistoryEntry returns a HistoryEntry on one line, using the Machine's
+// debug names
+func (m *Machine) FormatHistoryEntry(e HistoryEntry) string {
+	line := fmt.Sprintf(
+		"%s %s -> %s on %s after %s",
+		e.Time.Format(time.RFC3339Nano),
+		m.GetNameForState(e.From),
+		m.GetNameForState(e.To),
+		m.GetNameForEvent(e.Event),
+		e.InPrevious,
+	)
+
+	if !e.Committed {
+		line = fmt.Sprintf(
+			"%s %s rejected %s after %s",
+			e.Time.Format(time.RFC3339Nano),
+			m.GetNameForState(e.From),
+			m.GetNameForEvent(e.Event),
+			e.InPrevious,
+		)
+	}
+
+	if len(e.Guards) > 0 {
+		guards := make([]string, 0, len(e.Guards))
+		for _, g := range e.Guards {
+			guards = append(guards, fmt.Sprintf("%s=%t", m.GetNameForState(g.State), g.Passed))
+		}
+		line = fmt.Sprintf("%s guards[%s]", line, strings.Join(guards, " "))
+	}
+
+	if e.Err != nil {
+		line = fmt.Sprintf("%s: %s", line, e.Err)
+	}
+	return line
+}
+
+// Format implements fmt.Formatter. %v prints the id and current State, and
+// %+v adds the history, which is useful in panic messages and logs.
+func (m *Machine) Format(f fmt.State, verb rune) {
+	state := "(handling an Event)"
+	if m.stateChangeMtx.TryLock() {
+		state = m.GetNameForState(m.state)
+		m.stateChangeMtx.Unlock()
+	}
+
+	fmt.Fprintf(f, "[%s] %s", m.id, state)
+
+	if verb != 'v' || !f.Flag('+') {
+		return
+	}
+
+	history := m.History()
+	if len(history) == 0 {
+		return
+	}
+
+	fmt.Fprint(f, "\nhistory:")
+	for _, e := range history {
+		fmt.Fprintf(f, "\n  %s", m.FormatHistoryEntry(e))
+	}
+}
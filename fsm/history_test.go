@@ -0,0 +1,92 @@
+package fsm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_History(t *testing.T) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+		Deactivate
+	)
+
+	const (
+		KeyIsReady fsm.ContextKey = iota
+	)
+
+	m := fsm.New(
+		"history",
+		10,
+		Inactive,
+		fsm.Context{KeyIsReady: fsm.ContextMeta{Inital: false}},
+		[]fsm.Event{Activate, Deactivate},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{State: Active, Condition: fsm.ContextIsTrue(KeyIsReady)},
+				},
+			},
+			Active: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Deactivate: fsm.Transition{State: Inactive},
+				},
+			},
+		},
+		nil,
+	)
+	m.AddStateNames(fsm.StateNames{Inactive: "Inactive", Active: "Active"})
+	m.AddEventNames(fsm.EventNames{Activate: "Activate", Deactivate: "Deactivate"})
+
+	assert.Empty(t, m.History())
+	m.EnableHistory(3)
+
+	m.SendEvent(Activate)
+	m.SetContext(KeyIsReady, true)
+	m.SendEvent(Activate)
+
+	history := m.History()
+	assert.Len(t, history, 2)
+
+	assert.Equal(t, Inactive, history[0].From)
+	assert.Equal(t, Inactive, history[0].To)
+	assert.False(t, history[0].Committed)
+	assert.Len(t, history[0].Guards, 1)
+	assert.False(t, history[0].Guards[0].Passed)
+	assert.Error(t, history[0].Err)
+
+	assert.Equal(t, Active, history[1].To)
+	assert.True(t, history[1].Committed)
+	assert.NoError(t, history[1].Err)
+	assert.True(t, history[1].InPrevious >= history[0].InPrevious)
+	assert.False(t, history[1].Time.Before(history[0].Time))
+
+	// the oldest entries are dropped
+	m.SendEvent(Deactivate)
+	m.SendEvent(Deactivate)
+	history = m.History()
+	assert.Len(t, history, 3)
+	assert.Equal(t, Activate, history[0].Event)
+	assert.True(t, history[0].Committed)
+	assert.False(t, history[2].Committed)
+
+	assert.Equal(t, "[history] Inactive", fmt.Sprintf("%v", m))
+
+	dump := fmt.Sprintf("%+v", m)
+	lines := strings.Split(dump, "\n")
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "history:", lines[1])
+	assert.Contains(t, lines[2], "Inactive -> Active on Activate after")
+	assert.Contains(t, lines[2], "guards[Active=true]")
+	assert.Contains(t, lines[4], "Inactive rejected Deactivate after")
+	assert.Contains(t, lines[4], "MachineErrorEventNotFoundForState")
+}
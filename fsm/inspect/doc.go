@@ -0,0 +1,21 @@
+/*
+Package inspect serves a live view of running Machines over HTTP, for
+debugging in staging without attaching a debugger.
+
+	inspector := inspect.New(inspect.Options{})
+	inspector.Register(machine)
+	http.Handle("/fsm/", http.StripPrefix("/fsm", inspector))
+
+The routes are
+
+	GET  /                        registered Machines and their States
+	GET  /machines/{id}           State, Context, names, history and available Events
+	GET  /machines/{id}/diagram   Mermaid diagram, or ?format=dot, with the current State highlighted
+	GET  /machines/{id}/stream    StateChanges as Server-Sent Events
+	POST /machines/{id}/send      send ?event=Name, only if Options.AllowSend and SendToken are set
+
+Sending Events changes the Machine, so only allow it in test environments.
+Sends must have Options.SendToken as a bearer token, and without one they're
+refused.
+*/
+package inspect // import "ojkelly.dev/fsm/inspect"
@@ -0,0 +1,367 @@
+package inspect
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ojkelly.dev/fsm"
+)
+
+// Options for an Inspector
+type Options struct {
+	// AllowSend enables sending Events to Machines, which also needs a
+	// SendToken
+	AllowSend bool
+	// SendToken must be sent as "Authorization: Bearer <token>" to send
+	// Events. Without one, sending is refused even if AllowSend is set.
+	SendToken string
+}
+
+// Inspector is an http.Handler showing the Machines registered with it
+type Inspector struct {
+	options Options
+
+	mtx      sync.Mutex
+	machines map[string]*fsm.Machine
+}
+
+// New creates an Inspector
+func New(options Options) *Inspector {
+	return &Inspector{
+		options:  options,
+		machines: map[string]*fsm.Machine{},
+	}
+}
+
+// Register a Machine, by its Id()
+func (i *Inspector) Register(m *fsm.Machine) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	i.machines[m.Id()] = m
+}
+
+// Unregister a Machine
+func (i *Inspector) Unregister(id string) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	delete(i.machines, id)
+}
+
+func (i *Inspector) machine(id string) (*fsm.Machine, bool) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	m, ok := i.machines[id]
+	return m, ok
+}
+
+// ServeHTTP implements http.Handler
+func (i *Inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		i.list(w, r)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if parts[0] != "machines" || len(parts) < 2 || len(parts) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+
+	m, ok := i.machine(parts[1])
+	if !ok {
+		http.Error(w, fmt.Sprintf("no machine with id '%s'", parts[1]), http.StatusNotFound)
+		return
+	}
+
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+
+	switch action {
+	case "":
+		i.detail(w, r, m)
+	case "diagram":
+		i.diagram(w, r, m)
+	case "stream":
+		i.stream(w, r, m)
+	case "send":
+		i.send(w, r, m)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// MachineSummary is one Machine in the list
+type MachineSummary struct {
+	Id    string `json:"id"`
+	State string `json:"state"`
+}
+
+func (i *Inspector) list(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	// a Machine in the middle of a Transition blocks m.State(), so they're
+	// queried without holding the lock
+	i.mtx.Lock()
+	machines := make(map[string]*fsm.Machine, len(i.machines))
+	for id, m := range i.machines {
+		machines[id] = m
+	}
+	i.mtx.Unlock()
+
+	summaries := make([]MachineSummary, 0, len(machines))
+	for id, m := range machines {
+		summaries = append(summaries, MachineSummary{Id: id, State: m.GetNameForState(m.State())})
+	}
+
+	sort.Slice(summaries, func(a, b int) bool { return summaries[a].Id < summaries[b].Id })
+	writeJSON(w, summaries)
+}
+
+// MachineDetail is everything known about one Machine
+type MachineDetail struct {
+	Id              string         `json:"id"`
+	State           string         `json:"state"`
+	Context         []ContextValue `json:"context"`
+	Names           Names          `json:"names"`
+	History         []HistoryEntry `json:"history"`
+	AvailableEvents []string       `json:"availableEvents"`
+}
+
+// ContextValue is one key in a Machine's Context
+type ContextValue struct {
+	Key       fsm.ContextKey `json:"key"`
+	Name      string         `json:"name"`
+	Protected bool           `json:"protected"`
+	Value     interface{}    `json:"value"`
+}
+
+// Names registered on a Machine for debugging
+type Names struct {
+	States      fsm.StateNames      `json:"states"`
+	Events      fsm.EventNames      `json:"events"`
+	ContextKeys fsm.ContextKeyNames `json:"contextKeys"`
+}
+
+// HistoryEntry is one entry in a Machine's history
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Event      string    `json:"event"`
+	InPrevious string    `json:"inPrevious"`
+	Committed  bool      `json:"committed"`
+	Guards     []Guard   `json:"guards,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Guard is the outcome of one candidate Transition's guards
+type Guard struct {
+	Next        string `json:"next"`
+	Passed      bool   `json:"passed"`
+	Explanation string `json:"explanation,omitempty"`
+}
+
+func (i *Inspector) detail(w http.ResponseWriter, r *http.Request, m *fsm.Machine) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	d := m.Definition()
+	detail := MachineDetail{
+		Id:              m.Id(),
+		State:           m.GetNameForState(m.State()),
+		Context:         []ContextValue{},
+		Names:           Names{States: d.StateNames, Events: d.EventNames, ContextKeys: d.ContextKeyNames},
+		History:         []HistoryEntry{},
+		AvailableEvents: []string{},
+	}
+
+	for _, v := range m.ContextValues() {
+		detail.Context = append(detail.Context, ContextValue{
+			Key:       v.Key,
+			Name:      v.Name,
+			Protected: v.Protected,
+			Value:     v.Value,
+		})
+	}
+
+	for _, e := range m.History() {
+		entry := HistoryEntry{
+			Time:       e.Time,
+			From:       m.GetNameForState(e.From),
+			To:         m.GetNameForState(e.To),
+			Event:      m.GetNameForEvent(e.Event),
+			InPrevious: e.InPrevious.String(),
+			Committed:  e.Committed,
+		}
+		for _, g := range e.Guards {
+			guard := Guard{Next: m.GetNameForState(g.State), Passed: g.Passed}
+			if g.Result != nil {
+				guard.Explanation = g.Result.String()
+			}
+			entry.Guards = append(entry.Guards, guard)
+		}
+		if e.Err != nil {
+			entry.Error = e.Err.Error()
+		}
+		detail.History = append(detail.History, entry)
+	}
+
+	for _, e := range m.AvailableEvents() {
+		detail.AvailableEvents = append(detail.AvailableEvents, m.GetNameForEvent(e))
+	}
+
+	writeJSON(w, detail)
+}
+
+func (i *Inspector) diagram(w http.ResponseWriter, r *http.Request, m *fsm.Machine) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	d := m.Definition()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	switch r.URL.Query().Get("format") {
+	case "", "mermaid":
+		fmt.Fprint(w, d.Mermaid(m.State()))
+	case "dot":
+		fmt.Fprint(w, d.DOT(m.State()))
+	default:
+		http.Error(w, "format must be mermaid or dot", http.StatusBadRequest)
+	}
+}
+
+// StateChange is sent on the stream
+type StateChange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Event  string `json:"event"`
+	IsLast bool   `json:"isLast"`
+}
+
+func (i *Inspector) stream(w http.ResponseWriter, r *http.Request, m *fsm.Machine) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	changes, cancel := m.Watch(64)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": watching %s\n\n", m.Id())
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+			change := StateChange{
+				From:   m.GetNameForState(c.From),
+				To:     m.GetNameForState(c.To),
+				IsLast: c.IsLast,
+			}
+			// the last StateChange wasn't caused by an Event
+			if !c.IsLast {
+				change.Event = m.GetNameForEvent(c.Cause)
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", err)
+				flusher.Flush()
+				return
+			}
+			fmt.Fprintf(w, "event: stateChange\ndata: %s\n\n", data)
+			flusher.Flush()
+			if c.IsLast {
+				return
+			}
+		}
+	}
+}
+
+func (i *Inspector) send(w http.ResponseWriter, r *http.Request, m *fsm.Machine) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if !i.options.AllowSend || i.options.SendToken == "" {
+		http.Error(w, "sending events is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if !validToken(r.Header.Get("Authorization"), "Bearer "+i.options.SendToken) {
+		http.Error(w, "sending events is not allowed", http.StatusUnauthorized)
+		return
+	}
+
+	name := r.URL.Query().Get("event")
+	e, ok := m.EventByName(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no event named '%s'", name), http.StatusBadRequest)
+		return
+	}
+
+	if err := m.SendEventContext(r.Context(), e); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	writeJSON(w, MachineSummary{Id: m.Id(), State: m.GetNameForState(m.State())})
+}
+
+// validToken compares in constant time, so the token can't be guessed from
+// how long the comparison takes
+func validToken(got string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(expected)) == 1
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	// encoded in full first, so an error can still be the response
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,266 @@
+package inspect_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/inspect"
+)
+
+const (
+	Inactive fsm.State = iota
+	Active
+)
+
+const (
+	Activate fsm.Event = iota
+	Deactivate
+)
+
+const (
+	KeyCounter fsm.ContextKey = iota
+)
+
+func newMachine(id string) *fsm.Machine {
+	m := fsm.New(
+		id,
+		10,
+		Inactive,
+		fsm.Context{KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0}},
+		[]fsm.Event{Activate, Deactivate},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{
+						State: Active,
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{KeyCounter: 1}, nil
+						},
+					},
+				},
+			},
+			Active: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Deactivate: fsm.Transition{State: Inactive},
+				},
+			},
+		},
+		nil,
+	)
+	m.AddStateNames(fsm.StateNames{Inactive: "Inactive", Active: "Active"})
+	m.AddEventNames(fsm.EventNames{Activate: "Activate", Deactivate: "Deactivate"})
+	m.AddContextKeyNames(fsm.ContextKeyNames{KeyCounter: "Counter"})
+	m.EnableHistory(10)
+	return m
+}
+
+func get(t *testing.T, url string, v interface{}) *http.Response {
+	res, err := http.Get(url)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	if v != nil {
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(v))
+	}
+	return res
+}
+
+func Test_Inspector(t *testing.T) {
+	m := newMachine("light")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	assert.True(t, m.SendEvent(Activate))
+
+	inspector := inspect.New(inspect.Options{})
+	inspector.Register(m)
+	server := httptest.NewServer(inspector)
+	defer server.Close()
+
+	list := []inspect.MachineSummary{}
+	get(t, server.URL+"/", &list)
+	assert.Equal(t, []inspect.MachineSummary{{Id: "light", State: "Active"}}, list)
+
+	detail := inspect.MachineDetail{}
+	get(t, server.URL+"/machines/light", &detail)
+	assert.Equal(t, "Active", detail.State)
+	assert.Equal(t, []string{"Deactivate"}, detail.AvailableEvents)
+	assert.Equal(t, "Counter", detail.Context[0].Name)
+	assert.True(t, detail.Context[0].Protected)
+	assert.Equal(t, float64(1), detail.Context[0].Value)
+	assert.Equal(t, "Inactive", detail.Names.States[Inactive])
+	assert.Len(t, detail.History, 1)
+	assert.Equal(t, "Activate", detail.History[0].Event)
+	assert.True(t, detail.History[0].Committed)
+
+	res, err := http.Get(server.URL + "/machines/light/diagram")
+	assert.Nil(t, err)
+	body := new(strings.Builder)
+	bufio.NewReader(res.Body).WriteTo(body)
+	res.Body.Close()
+	assert.Contains(t, body.String(), "stateDiagram-v2")
+	assert.Contains(t, body.String(), "class s_Active current")
+
+	res = get(t, server.URL+"/machines/missing", nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// sending is off unless it's allowed
+	res, err = http.Post(server.URL+"/machines/light/send?event=Deactivate", "", nil)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Equal(t, Active, m.State())
+}
+
+func Test_InspectorSendAndStream(t *testing.T) {
+	m := newMachine("light")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+
+	inspector := inspect.New(inspect.Options{AllowSend: true, SendToken: "secret"})
+	inspector.Register(m)
+	server := httptest.NewServer(inspector)
+	defer server.Close()
+
+	stream, err := http.Get(server.URL + "/machines/light/stream")
+	assert.Nil(t, err)
+	defer stream.Body.Close()
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+	lines := bufio.NewScanner(stream.Body)
+	// the comment sent once the stream is watching
+	lines.Scan()
+	lines.Scan()
+
+	send := func(event, token string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/machines/light/send?event="+event, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("Activate", ""))
+	assert.Equal(t, http.StatusUnauthorized, send("Activate", "wrong"))
+	assert.Equal(t, http.StatusBadRequest, send("Explode", "secret"))
+	assert.Equal(t, http.StatusOK, send("Activate", "secret"))
+	assert.Equal(t, http.StatusConflict, send("Activate", "secret"))
+	assert.Equal(t, Active, m.State())
+
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "event: stateChange", lines.Text())
+	assert.True(t, lines.Scan())
+	change := inspect.StateChange{}
+	assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(lines.Text(), "data: ")), &change))
+	assert.Equal(t, inspect.StateChange{From: "Inactive", To: "Active", Event: "Activate"}, change)
+
+	// the stream ends when the Machine stops
+	m.Stop()
+	lines.Scan()
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "event: stateChange", lines.Text())
+	assert.True(t, lines.Scan())
+	assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(lines.Text(), "data: ")), &change))
+	assert.Equal(t, inspect.StateChange{From: "Active", To: "Active", IsLast: true}, change)
+	lines.Scan()
+	assert.False(t, lines.Scan())
+
+	// and straight away for a stopped Machine
+	stopped, err := http.Get(server.URL + "/machines/light/stream")
+	assert.Nil(t, err)
+	defer stopped.Body.Close()
+	lines = bufio.NewScanner(stopped.Body)
+	n := 0
+	for lines.Scan() {
+		n++
+	}
+	assert.Equal(t, 5, n)
+}
+
+func Test_InspectorSendNeedsToken(t *testing.T) {
+	m := newMachine("light")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+
+	inspector := inspect.New(inspect.Options{AllowSend: true})
+	inspector.Register(m)
+	server := httptest.NewServer(inspector)
+	defer server.Close()
+
+	res, err := http.Post(server.URL+"/machines/light/send?event=Activate", "", nil)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Equal(t, Inactive, m.State())
+}
+
+func Test_InspectorBusyMachine(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	stuck := newMachine("stuck")
+	stuck.AddWildcardTransitions(fsm.EventToTransition{
+		Deactivate: fsm.Transition{
+			State: Inactive,
+			Actions: []fsm.TransitionEventHandler{
+				func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+					close(entered)
+					<-release
+				},
+			},
+		},
+	})
+
+	inspector := inspect.New(inspect.Options{})
+	inspector.Register(stuck)
+	server := httptest.NewServer(inspector)
+	defer server.Close()
+
+	go stuck.SendEvent(Deactivate)
+	<-entered
+
+	// listing waits for the stuck Machine, without blocking the Inspector
+	listed := make(chan []inspect.MachineSummary, 1)
+	go func() {
+		summaries := []inspect.MachineSummary{}
+		get(t, server.URL+"/", &summaries)
+		listed <- summaries
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	other := newMachine("other")
+	registered := make(chan struct{})
+	go func() {
+		inspector.Register(other)
+		close(registered)
+	}()
+	select {
+	case <-registered:
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("Register was blocked by a Machine in a Transition")
+	}
+
+	// other Machines can still be inspected
+	detail := inspect.MachineDetail{}
+	get(t, server.URL+"/machines/other", &detail)
+	assert.Equal(t, "Inactive", detail.State)
+
+	close(release)
+	<-stuck.StateChangeChannel()
+	summaries := <-listed
+	assert.Equal(t, inspect.MachineSummary{Id: "stuck", State: "Inactive"}, summaries[len(summaries)-1])
+}
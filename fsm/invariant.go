@@ -0,0 +1,110 @@
+package fsm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Invariant is a predicate over the State and Context that must hold after
+// every committed Transition, for example
+//
+//	fsm.Invariant{
+//		Name: "Counter >= 0",
+//		Check: func(m *fsm.Machine, s fsm.State) bool {
+//			return m.GetContext(KeyCounter).(int) >= 0
+//		},
+//	}
+//
+// Check is called while the Machine is locked, so it's given the State
+// rather than calling m.State(). It can read the Context with m.GetContext().
+type Invariant struct {
+	Name  string
+	Check func(m *Machine, s State) bool
+}
+
+// InvariantError is returned, or in debug builds panicked, when an Invariant
+// doesn't hold
+type InvariantError struct {
+	MachineId string
+	Invariant string
+	State     State
+	// Event that caused the Transition into State, if any
+	Event    Event
+	HasEvent bool
+
+	m *Machine
+}
+
+func (e *InvariantError) Error() string {
+	msg := fmt.Sprintf(
+		"[%s] invariant '%s' does not hold in state '%s'",
+		e.MachineId,
+		e.Invariant,
+		e.m.GetNameForState(e.State),
+	)
+	if e.HasEvent {
+		msg += fmt.Sprintf(" after event '%s'", e.m.GetNameForEvent(e.Event))
+	}
+	return msg
+}
+
+// AddInvariants adds Invariants to check. They are checked after every
+// committed Transition in builds with the fsmdebug tag, and whenever
+// m.CheckInvariants() is called.
+func (m *Machine) AddInvariants(invariants ...Invariant) {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	m.invariants = append(m.invariants, invariants...)
+}
+
+// CheckInvariants returns an *InvariantError for the first Invariant that
+// doesn't hold in the current State, or nil
+func (m *Machine) CheckInvariants() error {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	if err := m.checkInvariants(m.state, 0, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkInvariants must be called while holding m.stateChangeMtx
+func (m *Machine) checkInvariants(s State, e Event, hasEvent bool) *InvariantError {
+	for _, inv := range m.invariants {
+		if !inv.Check(m, s) {
+			return &InvariantError{
+				MachineId: m.id,
+				Invariant: inv.Name,
+				State:     s,
+				Event:     e,
+				HasEvent:  hasEvent,
+				m:         m,
+			}
+		}
+	}
+	return nil
+}
+
+// assertInvariants panics if an Invariant doesn't hold after a Transition,
+// in builds with the fsmdebug tag
+func (m *Machine) assertInvariants(s State, e Event) {
+	if !debugInvariants {
+		return
+	}
+
+	if err := m.checkInvariants(s, e, true); err != nil {
+		history := ""
+		if entries := m.History(); len(entries) > 0 {
+			lines := []string{}
+			for _, entry := range entries {
+				lines = append(lines, m.FormatHistoryEntry(entry))
+			}
+			history = "\nhistory:\n" + strings.Join(lines, "\n")
+		}
+		panic(fmt.Errorf("%w%s", err, history))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build fsmdebug
+// +build fsmdebug
+
+package fsm
+
+// debugInvariants checks Invariants after every Transition
+const debugInvariants = true
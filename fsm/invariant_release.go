@@ -0,0 +1,8 @@
+//go:build !fsmdebug
+// +build !fsmdebug
+
+package fsm
+
+// debugInvariants checks Invariants after every Transition, build with
+// -tags fsmdebug to turn it on
+const debugInvariants = false
@@ -0,0 +1,59 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_Invariants(t *testing.T) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+		Deactivate
+	)
+
+	const (
+		KeyIsReady fsm.ContextKey = iota
+	)
+
+	d := fsm.Definition{
+		StateChangeChannelSize: 1,
+		InitialState:           Inactive,
+		Context:                fsm.Context{KeyIsReady: fsm.ContextMeta{Inital: false}},
+		Events:                 []fsm.Event{Activate, Deactivate},
+		States: fsm.States{
+			Inactive: fsm.StateNode{Events: fsm.EventToTransition{Activate: fsm.Transition{State: Active}}},
+			Active:   fsm.StateNode{Events: fsm.EventToTransition{Deactivate: fsm.Transition{State: Inactive}}},
+		},
+		StateNames: fsm.StateNames{Inactive: "Inactive", Active: "Active"},
+		Invariants: []fsm.Invariant{{
+			Name: "if Active then IsReady",
+			Check: func(m *fsm.Machine, s fsm.State) bool {
+				return s != Active || m.GetContext(KeyIsReady) == true
+			},
+		}},
+	}
+
+	m := d.New("light")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	assert.Nil(t, m.CheckInvariants())
+	assert.Len(t, m.Definition().Invariants, 1)
+
+	m.SetContext(KeyIsReady, true)
+	assert.True(t, m.SendEvent(Activate))
+	assert.Nil(t, m.CheckInvariants())
+
+	m.SetContext(KeyIsReady, false)
+	err := m.CheckInvariants()
+	assert.IsType(t, &fsm.InvariantError{}, err)
+	assert.EqualError(t, err, "[light] invariant 'if Active then IsReady' does not hold in state 'Active'")
+}
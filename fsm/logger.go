@@ -0,0 +1,135 @@
+package fsm
+
+import "context"
+
+// LogKind is the type of a LogRecord
+type LogKind string
+
+const (
+	// LogEventReceived is logged for every Event sent to the Machine
+	LogEventReceived LogKind = "EventReceived"
+	// LogGuard is logged for every candidate Transition's guards
+	LogGuard LogKind = "Guard"
+	// LogTransition is logged when a Transition is committed
+	LogTransition LogKind = "Transition"
+	// LogContextUpdate is logged for every Context value that's set
+	LogContextUpdate LogKind = "ContextUpdate"
+	// LogMachineError is logged for every MachineError
+	LogMachineError LogKind = "MachineError"
+)
+
+// LogRecord is a structured record of something the Machine did. Names are
+// the ones registered with m.AddStateNames() and friends.
+type LogRecord struct {
+	Kind      LogKind
+	MachineId string
+	Message   string
+	// State the Machine was in
+	State string
+	// Event being handled, empty if there isn't one
+	Event string
+	// Next State, for LogGuard and LogTransition
+	Next string
+	// Passed, for LogGuard
+	Passed bool
+	// Explanation of the guards, for LogGuard with a Condition
+	Explanation string
+	// ContextKey and Value, for LogContextUpdate
+	ContextKey string
+	Value      interface{}
+	// Error, for LogMachineError
+	Error        error
+	MachineError MachineError
+}
+
+// Logger receives a LogRecord for everything the Machine does. Log is called
+// while the Machine is locked, so it must not call back into the Machine.
+type Logger interface {
+	Log(ctx context.Context, record LogRecord)
+}
+
+// SetLogger sets a Logger, or removes it if l is nil
+func (m *Machine) SetLogger(l Logger) {
+	m.checkIfCreatedCorrectly()
+	m.hooksMtx.Lock()
+	defer m.hooksMtx.Unlock()
+
+	m.logger = l
+}
+
+func (m *Machine) log(ctx context.Context, r LogRecord) {
+	m.hooksMtx.RLock()
+	l := m.logger
+	m.hooksMtx.RUnlock()
+
+	if l == nil {
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r.MachineId = m.id
+	l.Log(ctx, r)
+}
+
+func (m *Machine) logEventReceived(ctx context.Context, current State, e Event) {
+	m.log(ctx, LogRecord{
+		Kind:    LogEventReceived,
+		Message: "event received",
+		State:   m.GetNameForState(current),
+		Event:   m.GetNameForEvent(e),
+	})
+}
+
+func (m *Machine) logGuards(ctx context.Context, current State, e Event, tried []GuardAttempt) {
+	for _, g := range tried {
+		r := LogRecord{
+			Kind:    LogGuard,
+			Message: "guard evaluated",
+			State:   m.GetNameForState(current),
+			Event:   m.GetNameForEvent(e),
+			Next:    m.GetNameForState(g.State),
+			Passed:  g.Passed,
+		}
+		if g.Result != nil {
+			r.Explanation = g.Result.String()
+		}
+		m.log(ctx, r)
+	}
+}
+
+func (m *Machine) logTransition(ctx context.Context, change StateChange) {
+	m.log(ctx, LogRecord{
+		Kind:    LogTransition,
+		Message: "transition",
+		State:   m.GetNameForState(change.From),
+		Event:   m.GetNameForEvent(change.Cause),
+		Next:    m.GetNameForState(change.To),
+	})
+}
+
+// logContextUpdate state is empty when the Context is set outside of an Event
+func (m *Machine) logContextUpdate(ctx context.Context, state string, key ContextKey, value interface{}) {
+	m.log(ctx, LogRecord{
+		Kind:       LogContextUpdate,
+		Message:    "context updated",
+		State:      state,
+		ContextKey: m.GetNameForContextKey(key),
+		Value:      value,
+	})
+}
+
+func (m *Machine) logMachineError(ctx context.Context, current State, e error, machineError MachineError) {
+	r := LogRecord{
+		Kind:         LogMachineError,
+		Message:      "machine error",
+		State:        m.GetNameForState(current),
+		Error:        e,
+		MachineError: machineError,
+	}
+	if tErr, ok := e.(*TransitionError); ok {
+		r.Event = m.GetNameForEvent(tErr.Event)
+	}
+	m.log(ctx, r)
+}
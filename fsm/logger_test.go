@@ -0,0 +1,110 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+type recordingLogger struct {
+	records []fsm.LogRecord
+}
+
+func (l *recordingLogger) Log(ctx context.Context, r fsm.LogRecord) {
+	l.records = append(l.records, r)
+}
+
+// runLoggedCounter runs a counter Machine through every kind of LogRecord
+func runLoggedCounter(logger fsm.Logger) {
+	const (
+		Inactive fsm.State = iota
+		Active
+	)
+
+	const (
+		Activate fsm.Event = iota
+		Increment
+	)
+
+	const (
+		KeyIsReady fsm.ContextKey = iota
+		KeyCounter
+	)
+
+	m := fsm.New(
+		"logged",
+		10,
+		Inactive,
+		fsm.Context{
+			KeyIsReady: fsm.ContextMeta{Inital: false},
+			KeyCounter: fsm.ContextMeta{Protected: true, Inital: 0},
+		},
+		[]fsm.Event{Activate, Increment},
+		fsm.States{
+			Inactive: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Activate: fsm.Transition{State: Active, Condition: fsm.ContextIsTrue(KeyIsReady)},
+				},
+			},
+			Active: fsm.StateNode{
+				Events: fsm.EventToTransition{
+					Increment: fsm.Transition{
+						State: Active,
+						UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+							return fsm.UpdateContext{KeyCounter: m.GetContext(KeyCounter).(int) + 1}, nil
+						},
+					},
+				},
+			},
+		},
+		nil,
+	)
+	m.AddStateNames(fsm.StateNames{Inactive: "Inactive", Active: "Active"})
+	m.AddEventNames(fsm.EventNames{Activate: "Activate", Increment: "Increment"})
+	m.AddContextKeyNames(fsm.ContextKeyNames{KeyIsReady: "IsReady", KeyCounter: "Counter"})
+	m.SetLogger(logger)
+
+	m.SendEvent(Increment)
+	m.SendEvent(Activate)
+	m.SetContext(KeyIsReady, true)
+	m.SendEvent(Activate)
+	m.SendEvent(Increment)
+	m.Error(errors.New("something broke"))
+}
+
+func Test_Logger(t *testing.T) {
+	logger := &recordingLogger{}
+	runLoggedCounter(logger)
+
+	kinds := []fsm.LogKind{}
+	for _, r := range logger.records {
+		assert.Equal(t, "logged", r.MachineId)
+		kinds = append(kinds, r.Kind)
+	}
+	assert.Equal(t, []fsm.LogKind{
+		// Increment while Inactive
+		fsm.LogEventReceived, fsm.LogMachineError,
+		// Activate before IsReady
+		fsm.LogEventReceived, fsm.LogGuard, fsm.LogMachineError,
+		fsm.LogContextUpdate,
+		fsm.LogEventReceived, fsm.LogGuard, fsm.LogTransition,
+		fsm.LogEventReceived, fsm.LogContextUpdate, fsm.LogTransition,
+		fsm.LogMachineError,
+	}, kinds)
+
+	r := logger.records
+	assert.Equal(t, "Increment", r[0].Event)
+	assert.Equal(t, "Inactive", r[0].State)
+	assert.Equal(t, fsm.MachineErrorEventNotFoundForState, r[1].MachineError)
+	assert.Equal(t, "IsReady is true: fail", r[3].Explanation)
+	assert.Equal(t, "IsReady", r[5].ContextKey)
+	assert.True(t, r[7].Passed)
+	assert.Equal(t, "Active", r[8].Next)
+	assert.Equal(t, "Counter", r[10].ContextKey)
+	assert.Equal(t, 1, r[10].Value)
+	assert.Equal(t, fsm.MachineErrorExternal, r[12].MachineError)
+	assert.EqualError(t, r[12].Error, "something broke")
+}
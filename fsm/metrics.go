@@ -0,0 +1,100 @@
+package fsm
+
+import "time"
+
+// Metrics is told about every Transition, guard rejection and MachineError,
+// so they can be counted across many Machines. States and Events are passed
+// by their debug names.
+//
+// Methods are called while the Machine is locked, so they must not call back
+// into the Machine.
+type Metrics interface {
+	// StateEntered is called when a Machine enters a State, and with the
+	// current State when the Metrics are set
+	StateEntered(machineId string, state string)
+	// StateExited is called when a Machine leaves a State, and with the
+	// current State when it's stopped
+	StateExited(machineId string, state string, dwell time.Duration)
+	// Transition is called for every committed Transition, including ones
+	// back to the same State
+	Transition(machineId string, from string, event string, to string)
+	// GuardRejected is called for every candidate Transition whose guards
+	// failed
+	GuardRejected(machineId string, state string, event string, next string)
+	// MachineError is called for every MachineError
+	MachineError(machineId string, state string, machineError MachineError)
+}
+
+// SetMetrics sets the Metrics, or removes them if mt is nil
+func (m *Machine) SetMetrics(mt Metrics) {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	m.hooksMtx.Lock()
+	previous := m.metrics
+	m.metrics = mt
+	m.hooksMtx.Unlock()
+
+	state := m.GetNameForState(m.state)
+	if previous != nil && !m.stopped {
+		previous.StateExited(m.id, state, time.Since(m.enteredAt))
+	}
+	if mt != nil && !m.stopped {
+		mt.StateEntered(m.id, state)
+	}
+}
+
+func (m *Machine) getMetrics() Metrics {
+	m.hooksMtx.RLock()
+	defer m.hooksMtx.RUnlock()
+
+	return m.metrics
+}
+
+func (m *Machine) countTransition(change StateChange, dwell time.Duration) {
+	mt := m.getMetrics()
+	if mt == nil {
+		return
+	}
+
+	from := m.GetNameForState(change.From)
+	to := m.GetNameForState(change.To)
+
+	if change.From != change.To {
+		mt.StateExited(m.id, from, dwell)
+		mt.StateEntered(m.id, to)
+	}
+	mt.Transition(m.id, from, m.GetNameForEvent(change.Cause), to)
+}
+
+func (m *Machine) countGuards(current State, e Event, tried []GuardAttempt) {
+	mt := m.getMetrics()
+	if mt == nil {
+		return
+	}
+
+	for _, g := range tried {
+		if !g.Passed {
+			mt.GuardRejected(
+				m.id,
+				m.GetNameForState(current),
+				m.GetNameForEvent(e),
+				m.GetNameForState(g.State),
+			)
+		}
+	}
+}
+
+func (m *Machine) countMachineError(current State, machineError MachineError) {
+	if mt := m.getMetrics(); mt != nil {
+		mt.MachineError(m.id, m.GetNameForState(current), machineError)
+	}
+}
+
+// countStopped must be called while holding m.stateChangeMtx
+func (m *Machine) countStopped() {
+	if mt := m.getMetrics(); mt != nil {
+		mt.StateExited(m.id, m.GetNameForState(m.state), time.Since(m.enteredAt))
+	}
+}
@@ -0,0 +1,227 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Migration upgrades Snapshots taken with one version of a Definition to the
+// next. Use Diff to see what changed between them.
+//
+//	migration := fsm.Migration{
+//		Version: 1,
+//		From:    v1,
+//		To:      v2,
+//		States:  map[string]string{"Shipping": "InTransit"},
+//		Context: map[string]fsm.ContextTransform{
+//			"Total": func(state string, old map[string]interface{}) (interface{}, error) {
+//				return old["Amount"], nil
+//			},
+//		},
+//	}
+type Migration struct {
+	// Version of From, Snapshots are upgraded to Version+1
+	Version int
+	From    Definition
+	To      Definition
+
+	// States maps State names in From to names in To. A State that isn't
+	// listed maps to the State in To with the same name.
+	States map[string]string
+
+	// Context transforms by key name in To. A key that isn't listed keeps
+	// its value if From has a key with the same name, otherwise it gets its
+	// initial value in To.
+	Context map[string]ContextTransform
+}
+
+// ContextTransform computes a Context value for the new version, from the
+// old State and Context by name
+type ContextTransform func(state string, old map[string]interface{}) (interface{}, error)
+
+// MigrationError lists everything wrong with a Migration, or with a Snapshot
+// given to it
+type MigrationError struct {
+	Version  int
+	Problems []string
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf(
+		"migration from version %d to %d: %s",
+		e.Version,
+		e.Version+1,
+		strings.Join(e.Problems, "; "),
+	)
+}
+
+// Validate checks that every State in From maps to a State in To, and that
+// the States and Context transforms only name States and keys that exist.
+// It returns a *MigrationError if they don't.
+func (mg Migration) Validate() error {
+	from, to := mg.From.New("from"), mg.To.New("to")
+	problems := []string{}
+
+	for _, s := range mg.From.SortedStates() {
+		name := from.GetNameForState(s)
+		if _, ok := mg.To.stateNamed(to, mg.mapState(name)); !ok {
+			problems = append(problems, fmt.Sprintf("state '%s' doesn't map to a state in version %d", name, mg.Version+1))
+		}
+	}
+
+	for _, name := range sortedNames(mg.States) {
+		if _, ok := mg.From.stateNamed(from, name); !ok {
+			problems = append(problems, fmt.Sprintf("state '%s' is mapped, but it's not in version %d", name, mg.Version))
+		}
+		if _, ok := mg.To.stateNamed(to, mg.States[name]); !ok {
+			problems = append(problems, fmt.Sprintf(
+				"state '%s' maps to '%s', but it's not in version %d",
+				name,
+				mg.States[name],
+				mg.Version+1,
+			))
+		}
+	}
+
+	names := make([]string, 0, len(mg.Context))
+	for name := range mg.Context {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := mg.To.contextKeyNamed(to, name); !ok {
+			problems = append(problems, fmt.Sprintf("context '%s' has a transform, but it's not in version %d", name, mg.Version+1))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &MigrationError{Version: mg.Version, Problems: problems}
+	}
+	return nil
+}
+
+func (mg Migration) mapState(name string) string {
+	if mapped, ok := mg.States[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+// Migrate upgrades a Snapshot taken with Version to Version+1. It returns a
+// *MigrationError if the Migration isn't valid, the Snapshot isn't for
+// Version, or a Context value doesn't have the type To expects. Numbers are
+// converted to the type To expects when they fit exactly.
+func (mg Migration) Migrate(s Snapshot) (Snapshot, error) {
+	if s.Version != mg.Version {
+		return Snapshot{}, &MigrationError{
+			Version:  mg.Version,
+			Problems: []string{fmt.Sprintf("snapshot is for version %d", s.Version)},
+		}
+	}
+	if err := mg.Validate(); err != nil {
+		return Snapshot{}, err
+	}
+
+	from, to := mg.From.New("from"), mg.To.New("to")
+	if _, ok := mg.From.stateNamed(from, s.State); !ok {
+		return Snapshot{}, &MigrationError{
+			Version:  mg.Version,
+			Problems: []string{fmt.Sprintf("snapshot is in state '%s', but it's not in version %d", s.State, mg.Version)},
+		}
+	}
+
+	migrated := Snapshot{Version: mg.Version + 1, State: mg.mapState(s.State), Context: map[string]interface{}{}}
+	problems := []string{}
+	for key, meta := range mg.To.Context {
+		name := to.GetNameForContextKey(key)
+
+		value, ok := s.Context[name]
+		if _, exists := mg.From.contextKeyNamed(from, name); !ok || !exists {
+			value = meta.Inital
+		}
+		if transform, ok := mg.Context[name]; ok {
+			var err error
+			if value, err = transform(s.State, s.Context); err != nil {
+				problems = append(problems, fmt.Sprintf("context '%s': %s", name, err))
+				continue
+			}
+		}
+
+		converted, ok := convertValue(value, meta.Inital)
+		if !ok {
+			problems = append(problems, fmt.Sprintf(
+				"context '%s' is %s, version %d expects %s",
+				name,
+				reflect.TypeOf(value),
+				mg.Version+1,
+				reflect.TypeOf(meta.Inital),
+			))
+			continue
+		}
+		migrated.Context[name] = converted
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return Snapshot{}, &MigrationError{Version: mg.Version, Problems: problems}
+	}
+	return migrated, nil
+}
+
+// Migrations upgrade Snapshots through consecutive versions of a Definition
+type Migrations []Migration
+
+// Validate checks every Migration, and that their versions are consecutive
+func (ms Migrations) Validate() error {
+	for i, mg := range ms {
+		if i > 0 && mg.Version != ms[i-1].Version+1 {
+			return &MigrationError{
+				Version:  mg.Version,
+				Problems: []string{fmt.Sprintf("it follows the migration from version %d", ms[i-1].Version)},
+			}
+		}
+		if err := mg.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrate upgrades a Snapshot to the latest version, one Migration at a time
+func (ms Migrations) Migrate(s Snapshot) (Snapshot, error) {
+	if err := ms.Validate(); err != nil {
+		return Snapshot{}, err
+	}
+	if len(ms) == 0 {
+		return s, nil
+	}
+
+	latest := ms[len(ms)-1].Version + 1
+	if s.Version < ms[0].Version || s.Version > latest {
+		return Snapshot{}, fmt.Errorf(
+			"can't migrate a snapshot from version %d, only versions %d to %d are known",
+			s.Version,
+			ms[0].Version,
+			latest,
+		)
+	}
+
+	for _, mg := range ms[s.Version-ms[0].Version:] {
+		var err error
+		if s, err = mg.Migrate(s); err != nil {
+			return Snapshot{}, err
+		}
+	}
+	return s, nil
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
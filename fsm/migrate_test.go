@@ -0,0 +1,186 @@
+package fsm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+// orders v1 has Shipping, v2 renames it to InTransit and adds Delivered, v3
+// renames Amount to Total in cents
+func orderVersions() (fsm.Definition, fsm.Definition, fsm.Definition) {
+	v1 := fsm.Definition{
+		StateChangeChannelSize: 10,
+		InitialState:           0,
+		Context:                fsm.Context{0: fsm.ContextMeta{Inital: 0.0}},
+		Events:                 []fsm.Event{0},
+		States: fsm.States{
+			0: fsm.StateNode{Events: fsm.EventToTransition{0: fsm.Transition{State: 1}}},
+			1: fsm.StateNode{Final: true},
+		},
+		StateNames:      fsm.StateNames{0: "Paid", 1: "Shipping"},
+		EventNames:      fsm.EventNames{0: "Ship"},
+		ContextKeyNames: fsm.ContextKeyNames{0: "Amount"},
+	}
+
+	v2 := v1
+	v2.Events = []fsm.Event{0, 1}
+	v2.States = fsm.States{
+		0: fsm.StateNode{Events: fsm.EventToTransition{0: fsm.Transition{State: 1}}},
+		1: fsm.StateNode{Events: fsm.EventToTransition{1: fsm.Transition{State: 2}}},
+		2: fsm.StateNode{Final: true},
+	}
+	v2.StateNames = fsm.StateNames{0: "Paid", 1: "InTransit", 2: "Delivered"}
+	v2.EventNames = fsm.EventNames{0: "Ship", 1: "Deliver"}
+
+	v3 := v2
+	v3.Context = fsm.Context{0: fsm.ContextMeta{Inital: 0}, 1: fsm.ContextMeta{Inital: ""}}
+	v3.ContextKeyNames = fsm.ContextKeyNames{0: "Total", 1: "Note"}
+
+	return v1, v2, v3
+}
+
+func orderMigrations() fsm.Migrations {
+	v1, v2, v3 := orderVersions()
+	return fsm.Migrations{
+		{
+			Version: 1,
+			From:    v1,
+			To:      v2,
+			States:  map[string]string{"Shipping": "InTransit"},
+		},
+		{
+			Version: 2,
+			From:    v2,
+			To:      v3,
+			Context: map[string]fsm.ContextTransform{
+				"Total": func(state string, old map[string]interface{}) (interface{}, error) {
+					amount, ok := old["Amount"].(float64)
+					if !ok {
+						return nil, errors.New("amount isn't a number")
+					}
+					return int(amount * 100), nil
+				},
+			},
+		},
+	}
+}
+
+func Test_Migrate(t *testing.T) {
+	v1, _, v3 := orderVersions()
+
+	m := v1.New("order")
+	m.SetContext(0, 12.5)
+	assert.True(t, m.SendEvent(0))
+	snapshot := m.Snapshot(1)
+	assert.Equal(t, fsm.Snapshot{Version: 1, State: "Shipping", Context: map[string]interface{}{"Amount": 12.5}}, snapshot)
+
+	migrations := orderMigrations()
+	assert.NoError(t, migrations.Validate())
+
+	migrated, err := migrations.Migrate(snapshot)
+	assert.NoError(t, err)
+	assert.Equal(t, fsm.Snapshot{Version: 3, State: "InTransit", Context: map[string]interface{}{"Total": 1250, "Note": ""}}, migrated)
+
+	restored, err := v3.Restore("order", migrated)
+	assert.NoError(t, err)
+	assert.Equal(t, fsm.State(1), restored.State())
+	assert.Equal(t, 1250, restored.GetContext(0))
+	assert.True(t, restored.SendEvent(1))
+	assert.Equal(t, fsm.State(2), restored.State())
+
+	// already the latest version
+	same, err := migrations.Migrate(migrated)
+	assert.NoError(t, err)
+	assert.Equal(t, migrated, same)
+
+	_, err = migrations.Migrate(fsm.Snapshot{Version: 7, State: "Paid"})
+	assert.EqualError(t, err, "can't migrate a snapshot from version 7, only versions 1 to 3 are known")
+
+	_, err = migrations.Migrate(fsm.Snapshot{Version: 2, State: "Paid", Context: map[string]interface{}{"Amount": "12.50"}})
+	assert.EqualError(t, err, "migration from version 2 to 3: context 'Total': amount isn't a number")
+
+	_, err = v3.Restore("order", fsm.Snapshot{Version: 3, State: "Shipping"})
+	assert.EqualError(t, err, "[order] can't restore state 'Shipping', it's not in the definition")
+
+	// numbers are converted if they fit
+	_, err = v3.Restore("order", fsm.Snapshot{Version: 3, State: "Paid", Context: map[string]interface{}{"Total": 12.5}})
+	assert.EqualError(t, err, "[order] can't restore context 'Total', it's float64 and the definition expects int")
+
+	_, err = v3.Restore("order", fsm.Snapshot{Version: 3, State: "Paid", Context: map[string]interface{}{"Note": 1}})
+	assert.EqualError(t, err, "[order] can't restore context 'Note', it's int and the definition expects string")
+}
+
+func Test_SnapshotJSON(t *testing.T) {
+	_, _, v3 := orderVersions()
+	migrations := orderMigrations()
+
+	// JSON decodes every number as a float64
+	decoded := fsm.Snapshot{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"version":3,"state":"InTransit","context":{"Total":1250,"Note":"fragile"}}`), &decoded))
+	assert.Equal(t, 1250.0, decoded.Context["Total"])
+
+	restored, err := v3.Restore("order", decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, 1250, restored.GetContext(0))
+	assert.Equal(t, "fragile", restored.GetContext(1))
+
+	encoded, err := json.Marshal(restored.Snapshot(3))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":3,"state":"InTransit","context":{"Total":1250,"Note":"fragile"}}`, string(encoded))
+
+	// a snapshot from an older version can be migrated, then restored
+	decoded = fsm.Snapshot{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"version":1,"state":"Shipping","context":{"Amount":8}}`), &decoded))
+	migrated, err := migrations.Migrate(decoded)
+	assert.NoError(t, err)
+	restored, err = v3.Restore("order", migrated)
+	assert.NoError(t, err)
+	assert.Equal(t, 800, restored.GetContext(0))
+}
+
+func Test_MigrationValidate(t *testing.T) {
+	v1, v2, _ := orderVersions()
+
+	// Shipping was renamed, but not mapped
+	mg := fsm.Migration{
+		Version: 1,
+		From:    v1,
+		To:      v2,
+		States:  map[string]string{"Refunded": "Paid", "Paid": "Cancelled"},
+		Context: map[string]fsm.ContextTransform{"Total": nil},
+	}
+
+	err := mg.Validate()
+	var mErr *fsm.MigrationError
+	assert.True(t, errors.As(err, &mErr))
+	assert.Equal(t, []string{
+		"state 'Paid' doesn't map to a state in version 2",
+		"state 'Shipping' doesn't map to a state in version 2",
+		"state 'Paid' maps to 'Cancelled', but it's not in version 2",
+		"state 'Refunded' is mapped, but it's not in version 1",
+		"context 'Total' has a transform, but it's not in version 2",
+	}, mErr.Problems)
+
+	_, err = mg.Migrate(fsm.Snapshot{Version: 1, State: "Paid"})
+	assert.Equal(t, mErr, err)
+
+	// the values don't have the types v2 expects
+	mg = fsm.Migration{Version: 1, From: v1, To: v2, States: map[string]string{"Shipping": "InTransit"}}
+	_, err = mg.Migrate(fsm.Snapshot{Version: 1, State: "Paid", Context: map[string]interface{}{"Amount": "12"}})
+	assert.EqualError(t, err, "migration from version 1 to 2: context 'Amount' is string, version 2 expects float64")
+
+	// a number that fits is converted
+	migrated, err := mg.Migrate(fsm.Snapshot{Version: 1, State: "Paid", Context: map[string]interface{}{"Amount": 12}})
+	assert.NoError(t, err)
+	assert.Equal(t, 12.0, migrated.Context["Amount"])
+
+	_, err = mg.Migrate(fsm.Snapshot{Version: 2, State: "Paid"})
+	assert.EqualError(t, err, "migration from version 1 to 2: snapshot is for version 2")
+
+	migrations := fsm.Migrations{mg, mg}
+	assert.EqualError(t, migrations.Validate(), "migration from version 1 to 2: it follows the migration from version 1")
+}
@@ -0,0 +1,195 @@
+package modelcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ojkelly.dev/fsm"
+)
+
+// trace is a run through the graph, being built up into a Counterexample
+type trace struct {
+	g     *graph
+	nodes []int
+	arcs  []arc
+	// loop is the index of the first arc that's repeated forever, or -1
+	loop int
+}
+
+func (t *trace) last() int {
+	return t.nodes[len(t.nodes)-1]
+}
+
+func (t *trace) extend(arcs []arc) {
+	for _, a := range arcs {
+		t.arcs = append(t.arcs, a)
+		t.nodes = append(t.nodes, a.to)
+	}
+}
+
+// avoid extends the trace with a run that stays in a nodes forever, ending
+// in a terminal node or repeating a cycle
+func (t *trace) avoid(a []bool) {
+	g := t.g
+	ends := g.lassos(a)
+	arcs, ok := g.path(t.last(), a, func(i int) bool { return ends[i] })
+	if !ok {
+		return
+	}
+	t.extend(arcs)
+
+	end := t.last()
+	if g.terminal(end) {
+		return
+	}
+
+	// go round the cycle, taking a Transition within it
+	component := g.components(a)
+	inside := make([]bool, len(g.configs))
+	for i := range g.configs {
+		inside[i] = component[i] == component[end]
+	}
+	for u, out := range g.out {
+		if !inside[u] {
+			continue
+		}
+		for _, step := range out {
+			if step.set || !inside[step.to] {
+				continue
+			}
+			toU, _ := g.path(end, inside, func(i int) bool { return i == u })
+			back, _ := g.path(step.to, inside, func(i int) bool { return i == end })
+
+			t.loop = len(t.arcs)
+			t.extend(toU)
+			t.extend([]arc{step})
+			t.extend(back)
+			return
+		}
+	}
+}
+
+func (t *trace) counterexample(d fsm.Definition, property string) *Counterexample {
+	c := &Counterexample{
+		Property:   property,
+		Steps:      make([]Step, 0, len(t.arcs)),
+		Loop:       t.loop,
+		terminal:   t.loop == -1 && t.g.terminal(t.last()),
+		definition: d,
+	}
+	for _, a := range t.arcs {
+		c.Steps = append(c.Steps, Step{
+			Edge:  a.edge,
+			Set:   a.set,
+			Key:   a.key,
+			Value: a.value,
+			To:    t.g.configs[a.to],
+		})
+	}
+	return c
+}
+
+// Step of a Counterexample
+type Step struct {
+	// Edge that was taken, unless Set is true
+	Edge fsm.Edge
+
+	// Set is true when the Context value for Key was set to Value
+	Set   bool
+	Key   fsm.ContextKey
+	Value interface{}
+
+	// To is the Configuration after the Step
+	To fsm.Configuration
+}
+
+// Counterexample is a run from the initial Configuration that breaks a
+// Property
+type Counterexample struct {
+	Property string
+	Steps    []Step
+
+	// Loop is the index of the first Step that's repeated forever, or -1 if
+	// the run ends after the last Step
+	Loop int
+
+	// terminal is true if no Transition can be taken after the last Step
+	terminal   bool
+	definition fsm.Definition
+}
+
+// Events sent in the Counterexample, without the Context values set
+func (c *Counterexample) Events() []fsm.Event {
+	events := []fsm.Event{}
+	for _, s := range c.Steps {
+		if !s.Set {
+			events = append(events, s.Edge.Event)
+		}
+	}
+	return events
+}
+
+// Error describes the run, one Step per line
+func (c *Counterexample) Error() string {
+	m := c.definition.New("counterexample")
+
+	lines := []string{fmt.Sprintf("property '%s' doesn't hold:", c.Property)}
+	lines = append(lines, fmt.Sprintf("    start in %s", m.GetNameForState(c.definition.InitialState)))
+	for i, s := range c.Steps {
+		if i == c.Loop {
+			lines = append(lines, "    repeat forever:")
+		}
+		if s.Set {
+			lines = append(lines, fmt.Sprintf("    set %s = %#v", m.GetNameForContextKey(s.Key), s.Value))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf(
+			"    %s --%s--> %s",
+			m.GetNameForState(s.Edge.From),
+			m.GetNameForEvent(s.Edge.Event),
+			m.GetNameForState(s.Edge.To),
+		))
+	}
+	if c.terminal {
+		lines = append(lines, "    and no more events can be taken")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Replay the Counterexample on a Machine in the initial Configuration, going
+// round a Loop once. It returns an error if an Event is rejected, or the
+// Machine ends up in another State. Context values may differ, as the
+// Machine runs every handler.
+//
+// StateChanges must be received from m.StateChangeChannel(), or it must be
+// big enough for them.
+func (c *Counterexample) Replay(m *fsm.Machine) error {
+	if s := m.State(); s != c.definition.InitialState {
+		return fmt.Errorf(
+			"replay must start in state '%s', the machine is in '%s'",
+			m.GetNameForState(c.definition.InitialState),
+			m.GetNameForState(s),
+		)
+	}
+
+	for i, s := range c.Steps {
+		if s.Set {
+			m.SetContext(s.Key, s.Value)
+			continue
+		}
+
+		if err := m.SendEventContext(context.Background(), s.Edge.Event); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if got := m.State(); got != s.To.State {
+			return fmt.Errorf(
+				"step %d: expected state '%s', got '%s'",
+				i+1,
+				m.GetNameForState(s.To.State),
+				m.GetNameForState(got),
+			)
+		}
+	}
+	return nil
+}
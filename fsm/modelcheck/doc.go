@@ -0,0 +1,38 @@
+/*
+Package modelcheck checks temporal properties of a Definition, by exploring
+every State together with its Context, up to a bound.
+
+Properties are built from Predicates over a Configuration, with Always,
+Eventually, Until and NeverBefore.
+
+	results := modelcheck.Check(definition, modelcheck.Options{
+		Domains: map[fsm.ContextKey][]interface{}{KeyApproved: {true, false}},
+	},
+		// every Requested eventually reaches Completed or Failed
+		modelcheck.Always(modelcheck.Implies(
+			modelcheck.In(Requested),
+			modelcheck.Eventually(modelcheck.In(Completed, Failed)),
+		)),
+		// Refunded is never entered before Paid
+		modelcheck.NeverBefore(modelcheck.In(Refunded), modelcheck.In(Paid)),
+	)
+
+Guards and UpdateContext handlers are run on a copy of the Context, no other
+handlers are. Values that unprotected ContextKeys can be set to between
+Events are listed in Domains.
+
+A run is a sequence of Events. It ends in a Configuration where no Transition
+can be taken, and runs that only set Context values forever are ignored, so
+Eventually doesn't fail just because the Machine is left alone.
+
+When a property doesn't hold, the Result has a Counterexample, the Events
+and Context values that break it, which can be replayed on a real Machine.
+If it repeats forever, Loop is where the repeated part starts.
+
+	err := result.Counterexample.Replay(definition.New("replay"))
+
+If Bound is reached, properties are only checked on what was explored, so
+one that holds may still fail beyond it. Counterexamples only take
+Transitions that were explored, so they're never made up.
+*/
+package modelcheck // import "ojkelly.dev/fsm/modelcheck"
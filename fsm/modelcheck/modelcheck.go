@@ -0,0 +1,346 @@
+package modelcheck
+
+import (
+	"sort"
+
+	"ojkelly.dev/fsm"
+)
+
+// defaultBound is how many Configurations are explored, unless
+// Options.Bound is set
+const defaultBound = 10000
+
+// Options change how the Definition is explored
+type Options struct {
+	// Domains are the values each unprotected ContextKey can be set to
+	// between Events. Protected keys are ignored, only Transitions can
+	// change them.
+	Domains map[fsm.ContextKey][]interface{}
+
+	// Bound is how many Configurations are explored, 10000 by default
+	Bound int
+}
+
+// Result of checking one Property
+type Result struct {
+	// Property described with the Definition's names
+	Property string
+	Holds    bool
+
+	// Counterexample is set when the Property doesn't hold
+	Counterexample *Counterexample
+
+	// Explored is how many Configurations were explored, and Truncated is
+	// true if Bound was reached before all of them were
+	Explored  int
+	Truncated bool
+}
+
+// Check explores the Definition once, and checks each Property from its
+// initial Configuration
+func Check(d fsm.Definition, o Options, properties ...Property) []Result {
+	if o.Bound <= 0 {
+		o.Bound = defaultBound
+	}
+
+	g := explore(d, o)
+	m := d.New("modelcheck")
+
+	results := make([]Result, 0, len(properties))
+	for _, p := range properties {
+		r := Result{
+			Property:  p.Describe(m),
+			Holds:     p.sat(g)[0],
+			Explored:  g.explored,
+			Truncated: g.truncated,
+		}
+		if !r.Holds {
+			t := &trace{g: g, nodes: []int{0}, arcs: []arc{}, loop: -1}
+			p.counterexample(g, t)
+			r.Counterexample = t.counterexample(d, r.Property)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// graph of the Configurations explored
+type graph struct {
+	configs []fsm.Configuration
+	out     [][]arc
+	// expanded is false for nodes whose arcs weren't explored, because the
+	// bound was reached
+	expanded  []bool
+	explored  int
+	truncated bool
+}
+
+// arc from one node to another, taking an Edge or setting a Context value
+type arc struct {
+	to    int
+	edge  fsm.Edge
+	set   bool
+	key   fsm.ContextKey
+	value interface{}
+}
+
+func explore(d fsm.Definition, o Options) *graph {
+	out := map[fsm.State][]fsm.Edge{}
+	for _, e := range d.Edges() {
+		out[e.From] = append(out[e.From], e)
+	}
+
+	domains := []fsm.ContextKey{}
+	for key := range o.Domains {
+		if meta, ok := d.Context[key]; ok && !meta.Protected {
+			domains = append(domains, key)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i] < domains[j] })
+
+	g := &graph{}
+	index := map[string]int{}
+	add := func(c fsm.Configuration) int {
+		key := c.Key()
+		if i, ok := index[key]; ok {
+			return i
+		}
+		index[key] = len(g.configs)
+		g.configs = append(g.configs, c)
+		g.out = append(g.out, nil)
+		g.expanded = append(g.expanded, false)
+		return index[key]
+	}
+
+	add(d.InitialConfiguration())
+	for i := 0; i < len(g.configs); i++ {
+		if i >= o.Bound {
+			g.truncated = true
+			break
+		}
+		g.expanded[i] = true
+		g.explored++
+		c := g.configs[i]
+
+		for _, e := range out[c.State] {
+			next, blocked := d.Step(c, e)
+			if blocked != nil {
+				continue
+			}
+			g.out[i] = append(g.out[i], arc{to: add(next), edge: e})
+		}
+
+		for _, key := range domains {
+			for _, v := range o.Domains[key] {
+				next := c.With(key, v)
+				if next.Key() == c.Key() {
+					continue
+				}
+				g.out[i] = append(g.out[i], arc{to: add(next), set: true, key: key, value: v})
+			}
+		}
+	}
+	return g
+}
+
+// terminal nodes are where a run ends, as no Transition can be taken
+func (g *graph) terminal(i int) bool {
+	if !g.expanded[i] {
+		return false
+	}
+	for _, a := range g.out[i] {
+		if !a.set {
+			return false
+		}
+	}
+	return true
+}
+
+// existsUntil returns the nodes with a path through a nodes to a b node
+func (g *graph) existsUntil(a []bool, b []bool) []bool {
+	in := make([][]int, len(g.configs))
+	for i, arcs := range g.out {
+		for _, arc := range arcs {
+			in[arc.to] = append(in[arc.to], i)
+		}
+	}
+
+	sat := make([]bool, len(g.configs))
+	queue := []int{}
+	for i := range g.configs {
+		if b[i] {
+			sat[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range in[i] {
+			if !sat[j] && a[j] {
+				sat[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+	return sat
+}
+
+// existsGlobally returns the nodes with a run that stays in a nodes forever,
+// either ending in a terminal node, or repeating a cycle that takes at least
+// one Transition
+func (g *graph) existsGlobally(a []bool) []bool {
+	return g.existsUntil(a, g.lassos(a))
+}
+
+// lassos returns the a nodes where a run can stay in a forever, without
+// leaving them
+func (g *graph) lassos(a []bool) []bool {
+	component := g.components(a)
+
+	cycles := map[int]bool{}
+	for i, arcs := range g.out {
+		if !a[i] {
+			continue
+		}
+		for _, arc := range arcs {
+			if !arc.set && a[arc.to] && component[arc.to] == component[i] {
+				cycles[component[i]] = true
+			}
+		}
+	}
+
+	ends := make([]bool, len(g.configs))
+	for i := range g.configs {
+		ends[i] = a[i] && (g.terminal(i) || cycles[component[i]])
+	}
+	return ends
+}
+
+// components numbers the strongly connected components of the a nodes,
+// with Tarjan's algorithm. Other nodes are -1.
+func (g *graph) components(a []bool) []int {
+	index := make([]int, len(g.configs))
+	low := make([]int, len(g.configs))
+	onStack := make([]bool, len(g.configs))
+	component := make([]int, len(g.configs))
+	for i := range index {
+		index[i] = -1
+		component[i] = -1
+	}
+
+	next := 0
+	components := 0
+	stack := []int{}
+
+	var connect func(v int)
+	connect = func(v int) {
+		index[v] = next
+		low[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, arc := range g.out[v] {
+			w := arc.to
+			if !a[w] {
+				continue
+			}
+			if index[w] == -1 {
+				connect(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+
+		if low[v] == index[v] {
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component[w] = components
+				if w == v {
+					break
+				}
+			}
+			components++
+		}
+	}
+
+	for v := range g.configs {
+		if a[v] && index[v] == -1 {
+			connect(v)
+		}
+	}
+	return component
+}
+
+// path returns the shortest arcs from node from to a node in to, only
+// passing through via nodes, or false if there isn't one
+func (g *graph) path(from int, via []bool, to func(i int) bool) ([]arc, bool) {
+	if to(from) {
+		return []arc{}, true
+	}
+
+	parent := map[int]arc{}
+	previous := map[int]int{}
+	seen := map[int]bool{from: true}
+	queue := []int{from}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, a := range g.out[i] {
+			if seen[a.to] || !via[a.to] {
+				continue
+			}
+			seen[a.to] = true
+			parent[a.to] = a
+			previous[a.to] = i
+
+			if to(a.to) {
+				arcs := []arc{}
+				for j := a.to; j != from; j = previous[j] {
+					arcs = append([]arc{parent[j]}, arcs...)
+				}
+				return arcs, true
+			}
+			queue = append(queue, a.to)
+		}
+	}
+	return nil, false
+}
+
+func all(g *graph) []bool {
+	sat := make([]bool, len(g.configs))
+	for i := range sat {
+		sat[i] = true
+	}
+	return sat
+}
+
+func not(a []bool) []bool {
+	sat := make([]bool, len(a))
+	for i := range a {
+		sat[i] = !a[i]
+	}
+	return sat
+}
+
+func both(a []bool, b []bool) []bool {
+	sat := make([]bool, len(a))
+	for i := range a {
+		sat[i] = a[i] && b[i]
+	}
+	return sat
+}
+
+func either(a []bool, b []bool) []bool {
+	sat := make([]bool, len(a))
+	for i := range a {
+		sat[i] = a[i] || b[i]
+	}
+	return sat
+}
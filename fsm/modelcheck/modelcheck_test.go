@@ -0,0 +1,179 @@
+package modelcheck_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+	mc "ojkelly.dev/fsm/modelcheck"
+)
+
+const (
+	Requested fsm.State = iota
+	Working
+	Paused
+	Completed
+	Failed
+)
+
+const (
+	Start fsm.Event = iota
+	Finish
+	Fail
+	Pause
+	Resume
+)
+
+const (
+	KeyApproved fsm.ContextKey = iota
+)
+
+func job(pause bool) fsm.Definition {
+	working := fsm.EventToTransition{
+		Finish: fsm.Transition{State: Completed, Condition: fsm.ContextIsTrue(KeyApproved)},
+		Fail:   fsm.Transition{State: Failed},
+	}
+	if pause {
+		working[Pause] = fsm.Transition{State: Paused}
+	}
+
+	return fsm.Definition{
+		StateChangeChannelSize: 10,
+		InitialState:           Requested,
+		Context:                fsm.Context{KeyApproved: fsm.ContextMeta{Inital: false}},
+		Events:                 []fsm.Event{Start, Finish, Fail, Pause, Resume},
+		States: fsm.States{
+			Requested: fsm.StateNode{Events: fsm.EventToTransition{Start: fsm.Transition{State: Working}}},
+			Working:   fsm.StateNode{Events: working},
+			Paused:    fsm.StateNode{Events: fsm.EventToTransition{Resume: fsm.Transition{State: Working}}},
+			Completed: fsm.StateNode{Final: true},
+			Failed:    fsm.StateNode{Final: true},
+		},
+		StateNames: fsm.StateNames{
+			Requested: "Requested",
+			Working:   "Working",
+			Paused:    "Paused",
+			Completed: "Completed",
+			Failed:    "Failed",
+		},
+		EventNames: fsm.EventNames{
+			Start:  "Start",
+			Finish: "Finish",
+			Fail:   "Fail",
+			Pause:  "Pause",
+			Resume: "Resume",
+		},
+		ContextKeyNames: fsm.ContextKeyNames{KeyApproved: "Approved"},
+	}
+}
+
+var (
+	finishes = mc.Always(mc.Implies(
+		mc.In(Requested),
+		mc.Eventually(mc.In(Completed, Failed)),
+	))
+	working = mc.Until(mc.In(Requested, Working), mc.In(Completed, Failed))
+)
+
+func Test_Eventually(t *testing.T) {
+	approved := mc.Options{Domains: map[fsm.ContextKey][]interface{}{KeyApproved: {true, false}}}
+
+	// setting Approved back and forth forever isn't a run
+	results := mc.Check(job(false), approved, finishes, working)
+	assert.True(t, results[0].Holds)
+	assert.True(t, results[1].Holds)
+	assert.Equal(t, 8, results[0].Explored)
+
+	results = mc.Check(job(true), mc.Options{}, finishes, working)
+	assert.False(t, results[0].Holds)
+	assert.Equal(t, "always (Requested implies eventually (Completed or Failed))", results[0].Property)
+
+	c := results[0].Counterexample
+	assert.Equal(t, []fsm.Event{Start, Pause, Resume}, c.Events())
+	assert.Equal(t, 1, c.Loop)
+	assert.EqualError(t, c, `property 'always (Requested implies eventually (Completed or Failed))' doesn't hold:
+    start in Requested
+    Requested --Start--> Working
+    repeat forever:
+    Working --Pause--> Paused
+    Paused --Resume--> Working`)
+	assert.Nil(t, c.Replay(job(true).New("replay")))
+
+	// Paused is neither
+	assert.False(t, results[1].Holds)
+	assert.Equal(t, []fsm.Event{Start, Pause}, results[1].Counterexample.Events())
+	assert.Equal(t, -1, results[1].Counterexample.Loop)
+}
+
+func Test_Context(t *testing.T) {
+	// Approved can be unset after Finish
+	approvedWhenCompleted := mc.Always(mc.Implies(mc.In(Completed), mc.ContextEquals(KeyApproved, true)))
+	approved := mc.Options{Domains: map[fsm.ContextKey][]interface{}{KeyApproved: {true, false}}}
+
+	results := mc.Check(job(false), approved, approvedWhenCompleted)
+	assert.False(t, results[0].Holds)
+
+	c := results[0].Counterexample
+	assert.Equal(t, []fsm.Event{Start, Finish}, c.Events())
+	assert.Equal(t, mc.Step{Set: true, Key: KeyApproved, Value: false, To: fsm.Configuration{
+		State:   Completed,
+		Context: map[fsm.ContextKey]interface{}{KeyApproved: false},
+	}}, c.Steps[len(c.Steps)-1])
+	assert.Nil(t, c.Replay(job(false).New("replay")))
+
+	results = mc.Check(job(false), mc.Options{}, approvedWhenCompleted, mc.Always(mc.Not(mc.In(Completed))))
+	assert.True(t, results[0].Holds)
+	assert.True(t, results[1].Holds)
+}
+
+const (
+	Created fsm.State = iota
+	Paid
+	Cancelled
+	Refunded
+)
+
+const (
+	Pay fsm.Event = iota
+	Cancel
+	Refund
+)
+
+func Test_NeverBefore(t *testing.T) {
+	d := fsm.Definition{
+		StateChangeChannelSize: 10,
+		InitialState:           Created,
+		Events:                 []fsm.Event{Pay, Cancel, Refund},
+		States: fsm.States{
+			Created: fsm.StateNode{Events: fsm.EventToTransition{
+				Pay:    fsm.Transition{State: Paid},
+				Cancel: fsm.Transition{State: Cancelled},
+			}},
+			Paid:      fsm.StateNode{Events: fsm.EventToTransition{Refund: fsm.Transition{State: Refunded}}},
+			Cancelled: fsm.StateNode{Events: fsm.EventToTransition{Refund: fsm.Transition{State: Refunded}}},
+			Refunded:  fsm.StateNode{Final: true},
+		},
+		StateNames: fsm.StateNames{Created: "Created", Paid: "Paid", Cancelled: "Cancelled", Refunded: "Refunded"},
+		EventNames: fsm.EventNames{Pay: "Pay", Cancel: "Cancel", Refund: "Refund"},
+	}
+
+	results := mc.Check(d, mc.Options{}, mc.NeverBefore(mc.In(Refunded), mc.In(Paid)))
+	assert.False(t, results[0].Holds)
+	assert.Equal(t, "never (Refunded) before (Paid)", results[0].Property)
+	assert.Equal(t, []fsm.Event{Cancel, Refund}, results[0].Counterexample.Events())
+
+	m := d.New("replay")
+	assert.Nil(t, results[0].Counterexample.Replay(m))
+	assert.Equal(t, Refunded, m.State())
+
+	// a Machine somewhere else can't replay it
+	assert.EqualError(t, results[0].Counterexample.Replay(m), "replay must start in state 'Created', the machine is in 'Refunded'")
+
+	delete(d.States[Cancelled].Events, Refund)
+	results = mc.Check(d, mc.Options{}, mc.NeverBefore(mc.In(Refunded), mc.In(Paid)))
+	assert.True(t, results[0].Holds)
+
+	results = mc.Check(d, mc.Options{Bound: 1}, mc.Always(mc.Not(mc.In(Refunded))))
+	assert.True(t, results[0].Truncated)
+	assert.True(t, results[0].Holds)
+}
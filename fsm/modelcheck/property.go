@@ -0,0 +1,276 @@
+package modelcheck
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"ojkelly.dev/fsm"
+)
+
+// Property of the runs from a Configuration
+type Property interface {
+	// Describe returns a readable name, using the Machine's debug names
+	Describe(m *fsm.Machine) string
+
+	// sat returns the nodes where the Property holds
+	sat(g *graph) []bool
+	// counterexample extends t, which ends at a node where the Property
+	// doesn't hold, with the run that breaks it
+	counterexample(g *graph, t *trace)
+}
+
+// Predicate is a Property of a single Configuration
+type Predicate struct {
+	describe func(m *fsm.Machine) string
+	match    func(c fsm.Configuration) bool
+}
+
+// Matches is a Predicate named name
+func Matches(name string, match func(c fsm.Configuration) bool) Predicate {
+	return Predicate{
+		describe: func(m *fsm.Machine) string { return name },
+		match:    match,
+	}
+}
+
+// In is true in any of the States
+func In(states ...fsm.State) Predicate {
+	return Predicate{
+		describe: func(m *fsm.Machine) string {
+			names := make([]string, 0, len(states))
+			for _, s := range states {
+				names = append(names, m.GetNameForState(s))
+			}
+			return strings.Join(names, " or ")
+		},
+		match: func(c fsm.Configuration) bool {
+			for _, s := range states {
+				if c.State == s {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// ContextEquals is true when the Context value for key is value
+func ContextEquals(key fsm.ContextKey, value interface{}) Predicate {
+	return Predicate{
+		describe: func(m *fsm.Machine) string {
+			return fmt.Sprintf("%s == %v", m.GetNameForContextKey(key), value)
+		},
+		match: func(c fsm.Configuration) bool {
+			return reflect.DeepEqual(c.Context[key], value)
+		},
+	}
+}
+
+// Not is true when p is false
+func Not(p Predicate) Predicate {
+	return Predicate{
+		describe: func(m *fsm.Machine) string { return "not " + p.describe(m) },
+		match:    func(c fsm.Configuration) bool { return !p.match(c) },
+	}
+}
+
+func (p Predicate) Describe(m *fsm.Machine) string {
+	return p.describe(m)
+}
+
+func (p Predicate) sat(g *graph) []bool {
+	sat := make([]bool, len(g.configs))
+	for i, c := range g.configs {
+		sat[i] = p.match(c)
+	}
+	return sat
+}
+
+// counterexample is the Configuration itself
+func (p Predicate) counterexample(g *graph, t *trace) {}
+
+type and []Property
+
+// And holds when every Property holds
+func And(p ...Property) Property {
+	return and(p)
+}
+
+func (p and) Describe(m *fsm.Machine) string {
+	return describeAll(m, " and ", p)
+}
+
+func (p and) sat(g *graph) []bool {
+	sat := all(g)
+	for _, c := range p {
+		sat = both(sat, c.sat(g))
+	}
+	return sat
+}
+
+func (p and) counterexample(g *graph, t *trace) {
+	for _, c := range p {
+		if !c.sat(g)[t.last()] {
+			c.counterexample(g, t)
+			return
+		}
+	}
+}
+
+type or []Property
+
+// Or holds when any Property holds
+func Or(p ...Property) Property {
+	return or(p)
+}
+
+// Implies holds when p is false, or q holds
+func Implies(p Predicate, q Property) Property {
+	return implies{or{Not(p), q}, p, q}
+}
+
+type implies struct {
+	or
+	p Predicate
+	q Property
+}
+
+func (p implies) Describe(m *fsm.Machine) string {
+	return fmt.Sprintf("%s implies %s", p.p.Describe(m), p.q.Describe(m))
+}
+
+func (p or) Describe(m *fsm.Machine) string {
+	return describeAll(m, " or ", p)
+}
+
+func (p or) sat(g *graph) []bool {
+	sat := make([]bool, len(g.configs))
+	for _, c := range p {
+		sat = either(sat, c.sat(g))
+	}
+	return sat
+}
+
+// counterexample continues with the first Property that isn't a Predicate,
+// as every one of them is false
+func (p or) counterexample(g *graph, t *trace) {
+	for _, c := range p {
+		if _, ok := c.(Predicate); !ok {
+			c.counterexample(g, t)
+			return
+		}
+	}
+}
+
+func describeAll(m *fsm.Machine, join string, p []Property) string {
+	names := make([]string, 0, len(p))
+	for _, c := range p {
+		names = append(names, "("+c.Describe(m)+")")
+	}
+	return strings.Join(names, join)
+}
+
+type always struct {
+	p Property
+}
+
+// Always holds when p holds in every Configuration of every run
+func Always(p Property) Property {
+	return always{p}
+}
+
+func (p always) Describe(m *fsm.Machine) string {
+	return fmt.Sprintf("always (%s)", p.p.Describe(m))
+}
+
+func (p always) sat(g *graph) []bool {
+	return not(g.existsUntil(all(g), not(p.p.sat(g))))
+}
+
+func (p always) counterexample(g *graph, t *trace) {
+	bad := not(p.p.sat(g))
+	arcs, _ := g.path(t.last(), all(g), func(i int) bool { return bad[i] })
+	t.extend(arcs)
+	p.p.counterexample(g, t)
+}
+
+type eventually struct {
+	p Property
+}
+
+// Eventually holds when every run reaches a Configuration where p holds
+func Eventually(p Property) Property {
+	return eventually{p}
+}
+
+func (p eventually) Describe(m *fsm.Machine) string {
+	return fmt.Sprintf("eventually (%s)", p.p.Describe(m))
+}
+
+func (p eventually) sat(g *graph) []bool {
+	return not(g.existsGlobally(not(p.p.sat(g))))
+}
+
+func (p eventually) counterexample(g *graph, t *trace) {
+	t.avoid(not(p.p.sat(g)))
+}
+
+type until struct {
+	p Property
+	q Property
+}
+
+// Until holds when p holds in every Configuration of every run, until one
+// where q holds, which every run reaches
+func Until(p Property, q Property) Property {
+	return until{p, q}
+}
+
+func (p until) Describe(m *fsm.Machine) string {
+	return fmt.Sprintf("(%s) until (%s)", p.p.Describe(m), p.q.Describe(m))
+}
+
+func (p until) sat(g *graph) []bool {
+	notQ := not(p.q.sat(g))
+	broken := g.existsUntil(notQ, both(not(p.p.sat(g)), notQ))
+	return not(either(broken, g.existsGlobally(notQ)))
+}
+
+func (p until) counterexample(g *graph, t *trace) {
+	notQ := not(p.q.sat(g))
+	bad := both(not(p.p.sat(g)), notQ)
+	if arcs, ok := g.path(t.last(), notQ, func(i int) bool { return bad[i] }); ok && notQ[t.last()] {
+		t.extend(arcs)
+		p.p.counterexample(g, t)
+		return
+	}
+	t.avoid(notQ)
+}
+
+type neverBefore struct {
+	p Property
+	q Property
+}
+
+// NeverBefore holds when no run reaches a Configuration where p holds
+// before one where q holds. Runs that never reach q must never reach p.
+func NeverBefore(p Property, q Property) Property {
+	return neverBefore{p, q}
+}
+
+func (p neverBefore) Describe(m *fsm.Machine) string {
+	return fmt.Sprintf("never (%s) before (%s)", p.p.Describe(m), p.q.Describe(m))
+}
+
+func (p neverBefore) sat(g *graph) []bool {
+	notQ := not(p.q.sat(g))
+	return not(g.existsUntil(notQ, both(p.p.sat(g), notQ)))
+}
+
+func (p neverBefore) counterexample(g *graph, t *trace) {
+	notQ := not(p.q.sat(g))
+	bad := both(p.p.sat(g), notQ)
+	arcs, _ := g.path(t.last(), notQ, func(i int) bool { return bad[i] })
+	t.extend(arcs)
+}
@@ -0,0 +1,322 @@
+package fsm
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultPlanLimit is how many States are explored, unless PlanOptions.Limit
+// is set
+const defaultPlanLimit = 10000
+
+// PlanOptions change how a Plan is searched for
+type PlanOptions struct {
+	// Cost of taking an Edge, which must not be negative. By default every
+	// Edge costs 1, so the Plan with the fewest Events is found.
+	Cost func(e Edge) int
+
+	// EvaluateGuards runs guards and UpdateContext handlers against a copy
+	// of the Context, so the Plan only takes Transitions that would really
+	// be taken. No other handlers are run. Otherwise guards are ignored.
+	EvaluateGuards bool
+
+	// Limit is how many States are explored before giving up, 10000 by
+	// default. When guards are evaluated, each State is explored once for
+	// each Context it's reached with.
+	Limit int
+}
+
+func (o PlanOptions) cost(e Edge) int {
+	if o.Cost == nil {
+		return 1
+	}
+	c := o.Cost(e)
+	if c < 0 {
+		panic(fmt.Sprintf("fsm.PlanOptions.Cost returned %d, costs must not be negative", c))
+	}
+	return c
+}
+
+// Plan is the cheapest sequence of Transitions from one State to another
+type Plan struct {
+	From  State
+	To    State
+	Steps []Edge
+	Cost  int
+}
+
+// Events to send to follow the Plan
+func (p Plan) Events() []Event {
+	events := make([]Event, 0, len(p.Steps))
+	for _, s := range p.Steps {
+		events = append(events, s.Event)
+	}
+	return events
+}
+
+type PlanErrorKind string
+
+const (
+	// PlanErrorUnknownState occurs when the target is not a State in the
+	// Definition
+	PlanErrorUnknownState PlanErrorKind = "PlanErrorUnknownState"
+
+	// PlanErrorNoPath occurs when no sequence of Events leads to the
+	// target, even ignoring guards
+	PlanErrorNoPath PlanErrorKind = "PlanErrorNoPath"
+
+	// PlanErrorBlocked occurs when every sequence of Events that leads to
+	// the target is blocked by a guard
+	PlanErrorBlocked PlanErrorKind = "PlanErrorBlocked"
+
+	// PlanErrorLimit occurs when PlanOptions.Limit was reached before the
+	// target was
+	PlanErrorLimit PlanErrorKind = "PlanErrorLimit"
+)
+
+// PlanError explains why a target State can't be reached
+type PlanError struct {
+	MachineId string
+	From      State
+	Target    State
+	Kind      PlanErrorKind
+
+	// Reachable are the States that can be reached from From, ignoring
+	// guards
+	Reachable []State
+
+	// Blocked are the Transitions that guards prevented, when guards are
+	// evaluated
+	Blocked []BlockedTransition
+
+	// Explored is how many States, with their Context, were explored
+	Explored int
+
+	m *Machine
+}
+
+// BlockedTransition is a Transition that wasn't taken while planning
+type BlockedTransition struct {
+	Edge Edge
+	// Tried lists every Guard that was evaluated for the Event, in order
+	Tried []GuardAttempt
+}
+
+func (e *PlanError) Error() string {
+	msg := fmt.Sprintf(
+		"[%s] %s: can't reach state '%s' from '%s'",
+		e.MachineId,
+		e.Kind,
+		e.m.GetNameForState(e.Target),
+		e.m.GetNameForState(e.From),
+	)
+
+	switch e.Kind {
+	case PlanErrorUnknownState:
+		return msg + ", it's not in the definition"
+	case PlanErrorNoPath:
+		names := make([]string, 0, len(e.Reachable))
+		for _, s := range e.Reachable {
+			names = append(names, e.m.GetNameForState(s))
+		}
+		return fmt.Sprintf("%s, only these states are reachable: %s", msg, strings.Join(names, ", "))
+	case PlanErrorLimit:
+		msg = fmt.Sprintf("%s, gave up after exploring %d states", msg, e.Explored)
+	}
+
+	if len(e.Blocked) == 0 {
+		return msg
+	}
+
+	blocked := make([]string, 0, len(e.Blocked))
+	for _, b := range e.Blocked {
+		tried := make([]string, 0, len(b.Tried))
+		for _, g := range b.Tried {
+			attempt := fmt.Sprintf("#%d -> '%s' passed=%t", g.Candidate, e.m.GetNameForState(g.State), g.Passed)
+			if g.Result != nil {
+				attempt = fmt.Sprintf("%s (%s)", attempt, g.Result)
+			}
+			tried = append(tried, attempt)
+		}
+		blocked = append(blocked, fmt.Sprintf(
+			"%s --%s--> %s: %s",
+			e.m.GetNameForState(b.Edge.From),
+			e.m.GetNameForEvent(b.Edge.Event),
+			e.m.GetNameForState(b.Edge.To),
+			strings.Join(tried, ", "),
+		))
+	}
+	return fmt.Sprintf("%s, blocked: %s", msg, strings.Join(blocked, "; "))
+}
+
+// PlanTo finds the cheapest sequence of Events from the current State to
+// target, starting with the current Context. It returns a *PlanError
+// explaining why if there isn't one.
+//
+//	plan, err := machine.PlanTo(Refunded, fsm.PlanOptions{EvaluateGuards: true})
+func (m *Machine) PlanTo(target State, o PlanOptions) (Plan, error) {
+	m.checkIfCreatedCorrectly()
+
+	return newPlanner(m.id, m.Definition(), o).plan(m.Configuration(), target)
+}
+
+// Plan finds the cheapest sequence of Events from one State to another,
+// starting with the initial Context
+func (d Definition) Plan(from State, target State, o PlanOptions) (Plan, error) {
+	c := d.InitialConfiguration()
+	c.State = from
+	return newPlanner("plan", d, o).plan(c, target)
+}
+
+type planner struct {
+	id    string
+	d     Definition
+	o     PlanOptions
+	m     *Machine
+	out   map[State][]Edge
+	known map[State]bool
+}
+
+func newPlanner(id string, d Definition, o PlanOptions) *planner {
+	p := &planner{
+		id:    id,
+		d:     d,
+		o:     o,
+		m:     d.simulate(id, d.InitialConfiguration()),
+		out:   map[State][]Edge{},
+		known: map[State]bool{},
+	}
+	for _, s := range d.SortedStates() {
+		p.known[s] = true
+	}
+	for _, e := range d.Edges() {
+		p.out[e.From] = append(p.out[e.From], e)
+	}
+	if p.o.Limit <= 0 {
+		p.o.Limit = defaultPlanLimit
+	}
+	return p
+}
+
+// planNode is a Configuration, and the cheapest Steps found to it
+type planNode struct {
+	config Configuration
+	steps  []Edge
+	cost   int
+	// order breaks ties between nodes with the same cost, so the first one
+	// found wins
+	order int
+}
+
+type planQueue []*planNode
+
+func (q planQueue) Len() int { return len(q) }
+func (q planQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].order < q[j].order
+}
+func (q planQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *planQueue) Push(x interface{}) { *q = append(*q, x.(*planNode)) }
+func (q *planQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// key identifies a node, Context is only part of it when guards are
+// evaluated
+func (p *planner) key(n *planNode) string {
+	if !p.o.EvaluateGuards {
+		return fmt.Sprintf("%d", n.config.State)
+	}
+	return n.config.Key()
+}
+
+func (p *planner) plan(from Configuration, target State) (Plan, error) {
+	pErr := &PlanError{MachineId: p.id, From: from.State, Target: target, m: p.m}
+
+	if !p.known[target] {
+		pErr.Kind = PlanErrorUnknownState
+		return Plan{}, pErr
+	}
+
+	// ignoring guards first tells us if there's any path at all
+	if p.o.EvaluateGuards {
+		graph := *p
+		graph.o.EvaluateGuards = false
+		if _, err := graph.search(from, target); err != nil {
+			return Plan{}, err
+		}
+	}
+	return p.search(from, target)
+}
+
+// search for target with Dijkstra's algorithm, every Edge costing 1 makes it
+// a breadth first search
+func (p *planner) search(from Configuration, target State) (Plan, error) {
+	pErr := &PlanError{MachineId: p.id, From: from.State, Target: target, m: p.m}
+
+	order := 0
+	queue := &planQueue{{config: from, steps: []Edge{}}}
+	done := map[string]bool{}
+	reachable := map[State]bool{}
+	blocked := map[string]bool{}
+
+	for queue.Len() > 0 {
+		n := heap.Pop(queue).(*planNode)
+		key := p.key(n)
+		if done[key] {
+			continue
+		}
+		done[key] = true
+		reachable[n.config.State] = true
+		pErr.Explored++
+
+		if n.config.State == target {
+			return Plan{From: from.State, To: target, Steps: n.steps, Cost: n.cost}, nil
+		}
+		if pErr.Explored >= p.o.Limit {
+			pErr.Kind = PlanErrorLimit
+			return Plan{}, pErr
+		}
+
+		for _, e := range p.out[n.config.State] {
+			next := &planNode{config: Configuration{State: e.To, Context: n.config.Context}}
+			if p.o.EvaluateGuards {
+				var b *BlockedTransition
+				next.config, b = p.d.Step(n.config, e)
+				if b != nil {
+					// each Transition is explained once
+					if edge := fmt.Sprintf("%d %d %d", e.From, e.Event, e.Candidate); !blocked[edge] {
+						blocked[edge] = true
+						pErr.Blocked = append(pErr.Blocked, *b)
+					}
+					continue
+				}
+			}
+
+			order++
+			next.steps = append(append([]Edge{}, n.steps...), e)
+			next.cost = n.cost + p.o.cost(e)
+			next.order = order
+			heap.Push(queue, next)
+		}
+	}
+
+	if p.o.EvaluateGuards {
+		pErr.Kind = PlanErrorBlocked
+		return Plan{}, pErr
+	}
+
+	pErr.Kind = PlanErrorNoPath
+	for s := range reachable {
+		pErr.Reachable = append(pErr.Reachable, s)
+	}
+	sort.Slice(pErr.Reachable, func(i, j int) bool { return pErr.Reachable[i] < pErr.Reachable[j] })
+	return Plan{}, pErr
+}
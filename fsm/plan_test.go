@@ -0,0 +1,187 @@
+package fsm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_Plan(t *testing.T) {
+	const (
+		Created fsm.State = iota
+		Paid
+		Shipped
+		Backordered
+		Refunded
+		Archived
+	)
+
+	const (
+		Pay fsm.Event = iota
+		Ship
+		Restock
+		Refund
+		Skip
+	)
+
+	const (
+		KeyInStock fsm.ContextKey = iota
+	)
+
+	definition := func(restock bool) fsm.Definition {
+		restocked := fsm.Transition{State: Paid}
+		if restock {
+			restocked.UpdateContext = func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+				return fsm.UpdateContext{KeyInStock: true}, nil
+			}
+		}
+
+		return fsm.Definition{
+			StateChangeChannelSize: 1,
+			InitialState:           Created,
+			Context:                fsm.Context{KeyInStock: fsm.ContextMeta{Protected: true, Inital: false}},
+			Events:                 []fsm.Event{Pay, Ship, Restock, Refund, Skip},
+			States: fsm.States{
+				Created: fsm.StateNode{Events: fsm.EventToTransition{
+					Pay:  fsm.Transition{State: Paid},
+					Skip: fsm.Transition{State: Backordered},
+				}},
+				Paid: fsm.StateNode{Choices: fsm.EventToTransitions{
+					Ship: {
+						{State: Shipped, Condition: fsm.ContextIsTrue(KeyInStock)},
+						{State: Backordered},
+					},
+				}},
+				Backordered: fsm.StateNode{Events: fsm.EventToTransition{Restock: restocked}},
+				Shipped:     fsm.StateNode{Final: true},
+				Refunded:    fsm.StateNode{Final: true},
+				Archived:    fsm.StateNode{Final: true},
+			},
+			Wildcards: fsm.EventToTransition{Refund: fsm.Transition{State: Refunded}},
+			StateNames: fsm.StateNames{
+				Created:     "Created",
+				Paid:        "Paid",
+				Shipped:     "Shipped",
+				Backordered: "Backordered",
+				Refunded:    "Refunded",
+				Archived:    "Archived",
+			},
+			EventNames: fsm.EventNames{
+				Pay:     "Pay",
+				Ship:    "Ship",
+				Restock: "Restock",
+				Refund:  "Refund",
+				Skip:    "Skip",
+			},
+			ContextKeyNames: fsm.ContextKeyNames{KeyInStock: "InStock"},
+		}
+	}
+	d := definition(true)
+
+	// guards are ignored by default
+	plan, err := d.Plan(Created, Shipped, fsm.PlanOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []fsm.Event{Pay, Ship}, plan.Events())
+	assert.Equal(t, 2, plan.Cost)
+
+	plan, err = d.Plan(Created, Shipped, fsm.PlanOptions{EvaluateGuards: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []fsm.Event{Skip, Restock, Ship}, plan.Events())
+
+	plan, err = d.Plan(Created, Backordered, fsm.PlanOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []fsm.Event{Skip}, plan.Events())
+
+	plan, err = d.Plan(Created, Backordered, fsm.PlanOptions{Cost: func(e fsm.Edge) int {
+		if e.Event == Skip {
+			return 5
+		}
+		return 1
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, []fsm.Event{Pay, Ship}, plan.Events())
+
+	// from the Machine's current State and Context
+	m := d.New("order")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	assert.True(t, m.SendEvent(Pay))
+	plan, err = m.PlanTo(Refunded, fsm.PlanOptions{EvaluateGuards: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []fsm.Event{Refund}, plan.Events())
+	assert.Equal(t, Paid, plan.From)
+
+	var pErr *fsm.PlanError
+	_, err = d.Plan(Created, fsm.State(42), fsm.PlanOptions{})
+	assert.True(t, errors.As(err, &pErr))
+	assert.Equal(t, fsm.PlanErrorUnknownState, pErr.Kind)
+	assert.EqualError(t, err, "[plan] PlanErrorUnknownState: can't reach state '42' from 'Created', it's not in the definition")
+
+	_, err = d.Plan(Created, Archived, fsm.PlanOptions{EvaluateGuards: true})
+	assert.EqualError(t, err, "[plan] PlanErrorNoPath: can't reach state 'Archived' from 'Created', only these states are reachable: Created, Paid, Shipped, Backordered, Refunded")
+
+	// Restock doesn't change InStock, so Shipped is never reached
+	_, err = definition(false).Plan(Created, Shipped, fsm.PlanOptions{EvaluateGuards: true})
+	assert.EqualError(t, err, "[plan] PlanErrorBlocked: can't reach state 'Shipped' from 'Created', blocked: Paid --Ship--> Shipped: #0 -> 'Shipped' passed=false (InStock is true: fail)")
+
+	_, err = d.Plan(Created, Shipped, fsm.PlanOptions{EvaluateGuards: true, Limit: 2})
+	assert.EqualError(t, err, "[plan] PlanErrorLimit: can't reach state 'Shipped' from 'Created', gave up after exploring 2 states")
+}
+
+func Test_StepUpdateContextError(t *testing.T) {
+	const (
+		Created fsm.State = iota
+		Paid
+	)
+	const Pay fsm.Event = 0
+	const KeyTotal fsm.ContextKey = 0
+
+	failed := errors.New("receipt not sent")
+	d := fsm.Definition{
+		StateChangeChannelSize: 1,
+		InitialState:           Created,
+		Context:                fsm.Context{KeyTotal: fsm.ContextMeta{Protected: true, Inital: 0}},
+		Events:                 []fsm.Event{Pay},
+		States: fsm.States{
+			Created: fsm.StateNode{Events: fsm.EventToTransition{
+				Pay: fsm.Transition{
+					State: Paid,
+					UpdateContext: func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) (fsm.UpdateContext, error) {
+						return fsm.UpdateContext{KeyTotal: 10}, failed
+					},
+				},
+			}},
+			Paid: fsm.StateNode{Final: true},
+		},
+	}
+
+	// like a Machine, the Transition is taken and the error recorded
+	next, blocked := d.Step(d.InitialConfiguration(), d.Edges()[0])
+	assert.Nil(t, blocked)
+	assert.Equal(t, Paid, next.State)
+	assert.Equal(t, 10, next.Context[KeyTotal])
+	assert.Equal(t, failed, next.Err)
+
+	kinds := []fsm.MachineError{}
+	d.ErrorHandler = func(m *fsm.Machine, current fsm.State, next fsm.State, kind fsm.MachineError) {
+		kinds = append(kinds, kind)
+	}
+	m := d.New("order")
+	go func() {
+		for range m.StateChangeChannel() {
+		}
+	}()
+	m.SendEvent(Pay)
+	assert.Equal(t, next.State, m.State())
+	assert.Equal(t, next.Context[KeyTotal], m.GetContext(KeyTotal))
+	assert.Equal(t, []fsm.MachineError{fsm.MachineErrorUpdateContext}, kinds)
+	m.Stop()
+
+	plan, err := d.Plan(Created, Paid, fsm.PlanOptions{EvaluateGuards: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []fsm.Event{Pay}, plan.Events())
+}
@@ -0,0 +1,90 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+)
+
+// EventPolicy decides what happens to an Event the Machine can't act on
+type EventPolicy int
+
+const (
+	// EventPolicyError calls the error handler
+	EventPolicyError EventPolicy = iota
+	// EventPolicyIgnore drops the Event silently
+	EventPolicyIgnore
+	// EventPolicyPanic panics, for Events that should never be sent
+	EventPolicyPanic
+	// EventPolicyDeadLetter sends a DeadLetter to the channel set with
+	// m.SetDeadLetterChannel()
+	EventPolicyDeadLetter
+)
+
+// DeadLetter is an Event the Machine couldn't act on
+type DeadLetter struct {
+	MachineId string
+	State     State
+	Event     Event
+	Reason    MachineError
+}
+
+// SetUnhandledEventPolicy sets what happens when an Event is sent that the
+// current State has no Transition for. Defaults to EventPolicyError.
+func (m *Machine) SetUnhandledEventPolicy(p EventPolicy) {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	m.unhandledEventPolicy = p
+}
+
+// SetUnregisteredEventPolicy sets what happens when an Event is sent that was
+// not registered in fsm.New(). Defaults to EventPolicyPanic.
+func (m *Machine) SetUnregisteredEventPolicy(p EventPolicy) {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	m.unregisteredEventPolicy = p
+}
+
+// SetDeadLetterChannel sets the channel that receives Events handled with
+// EventPolicyDeadLetter. Sending blocks, so make sure it's read from.
+func (m *Machine) SetDeadLetterChannel(c chan<- DeadLetter) {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	m.deadLetterChannel = c
+}
+
+func (m *Machine) applyEventPolicy(ctx context.Context, p EventPolicy, tErr *TransitionError) error {
+	switch p {
+	case EventPolicyIgnore:
+		return nil
+	case EventPolicyPanic:
+		if tErr.Kind == MachineErrorEventNotRegistered {
+			panic(fmt.Sprintf("[%s] fsm.Machine.Event() called with unregistered Event. All events must be registered in fsm.New()", m.id))
+		}
+		panic(tErr.Error())
+	case EventPolicyDeadLetter:
+		if m.deadLetterChannel == nil {
+			panic(fmt.Sprintf("[%s] fsm.EventPolicyDeadLetter used without calling m.SetDeadLetterChannel()", m.id))
+		}
+		select {
+		case m.deadLetterChannel <- DeadLetter{
+			MachineId: m.id,
+			State:     tErr.State,
+			Event:     tErr.Event,
+			Reason:    tErr.Kind,
+		}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		if m.errorHandler != nil {
+			m.errorHandler(m, tErr.State, tErr.State, tErr.Kind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+/*
+Package repl steps through a definition file in the terminal, for exploring it
+with people who don't read Go.
+
+Guards are stubbed to pass, and can be switched off and on. Each line is a
+command, and the current State and its Events are shown after each one.
+
+	[Created] Pay, Cancel > Pay
+	Created --Pay--> Paid
+	    Paid: false -> true
+	[Paid] Ship, Refund > guard InStock off
+	[Paid] Ship, Refund > Ship
+	Ship: rejected, ...
+
+The commands are
+
+	<Event>                send an Event, also "send <Event>"
+	set <key> <value>      set an unprotected Context value, value is JSON
+	guard <name> on|off    make a guard pass or fail
+	undo                   go back one step
+	save <file>            save the steps as a script
+	show                   show the Context and guards
+	help, quit
+
+A saved script is a list of commands, replayed with Session.Run or
+"fsmrepl -script".
+*/
+package repl // import "ojkelly.dev/fsm/repl"
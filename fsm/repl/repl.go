@@ -0,0 +1,307 @@
+package repl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"ojkelly.dev/fsm"
+	"ojkelly.dev/fsm/fsmfile"
+)
+
+const help = `commands:
+  <Event>, send <Event>   send an Event
+  set <key> <value>       set an unprotected Context value, value is JSON
+  guard <name> on|off     make a guard pass or fail
+  undo                    go back one step
+  save <file>             save the steps as a script
+  show                    show the Context and guards
+  help                    show this
+  quit                    leave
+`
+
+// Session is a Machine created from a definition file, and the steps taken
+// so far
+type Session struct {
+	file       *fsmfile.File
+	definition fsm.Definition
+	machine    *fsm.Machine
+	guards     map[string]bool
+	steps      []string
+	out        io.Writer
+}
+
+// New Session for a definition file, with every guard passing
+func New(f *fsmfile.File) (*Session, error) {
+	s := &Session{file: f, out: ioutil.Discard}
+
+	guards := fsmfile.Guards{}
+	for _, name := range f.GuardNames() {
+		name := name
+		guards[name] = func(m *fsm.Machine, current fsm.State, next fsm.State) bool {
+			return s.guards[name]
+		}
+	}
+
+	d, err := f.Definition(guards)
+	if err != nil {
+		return nil, err
+	}
+	s.definition = d
+	s.reset()
+
+	return s, nil
+}
+
+// reset to a new Machine in the initial State
+func (s *Session) reset() {
+	if s.machine != nil {
+		s.machine.Stop()
+	}
+
+	s.guards = map[string]bool{}
+	for _, name := range s.file.GuardNames() {
+		s.guards[name] = true
+	}
+	s.steps = nil
+
+	s.machine = s.definition.New("repl")
+	s.machine.SetUnregisteredEventPolicy(fsm.EventPolicyError)
+	go func(changes <-chan fsm.StateChange) {
+		for c := range changes {
+			if c.IsLast {
+				return
+			}
+		}
+	}(s.machine.StateChangeChannel())
+	s.machine.Start()
+}
+
+// Machine being stepped
+func (s *Session) Machine() *fsm.Machine {
+	return s.machine
+}
+
+// Steps taken so far, as a script
+func (s *Session) Steps() []string {
+	return append([]string{}, s.steps...)
+}
+
+// Prompt shows the current State and its available Events
+func (s *Session) Prompt() string {
+	events := []string{}
+	for _, e := range s.machine.AvailableEvents() {
+		events = append(events, s.machine.GetNameForEvent(e))
+	}
+
+	state := s.machine.GetNameForState(s.machine.State())
+	if len(events) == 0 {
+		return fmt.Sprintf("[%s] no events > ", state)
+	}
+	return fmt.Sprintf("[%s] %s > ", state, strings.Join(events, ", "))
+}
+
+// Run commands from in until it ends or "quit", writing to out. When
+// interactive, a prompt is written before each command, and a failed command
+// doesn't stop the Session.
+func (s *Session) Run(in io.Reader, out io.Writer, interactive bool) error {
+	s.out = out
+	defer func() { s.out = ioutil.Discard }()
+
+	lines := bufio.NewScanner(in)
+	for {
+		if interactive {
+			fmt.Fprint(out, s.Prompt())
+		}
+		if !lines.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(lines.Text())
+		if line == "quit" || line == "exit" {
+			return nil
+		}
+
+		if err := s.Exec(line); err != nil {
+			if !interactive {
+				return fmt.Errorf("%s: %w", line, err)
+			}
+			fmt.Fprintln(out, err)
+		}
+	}
+	if interactive {
+		fmt.Fprintln(out)
+	}
+	return lines.Err()
+}
+
+// Exec runs one command. Commands that change the Machine are kept as steps.
+func (s *Session) Exec(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+		return nil
+	}
+
+	var err error
+	step := true
+	switch fields[0] {
+	case "send":
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: send <Event>")
+		}
+		err = s.send(fields[1])
+	case "set":
+		if len(fields) < 3 {
+			return fmt.Errorf("usage: set <key> <value>")
+		}
+		err = s.set(fields[1], strings.Join(fields[2:], " "))
+	case "guard":
+		if len(fields) != 3 || (fields[2] != "on" && fields[2] != "off") {
+			return fmt.Errorf("usage: guard <name> on|off")
+		}
+		err = s.guard(fields[1], fields[2] == "on")
+	case "undo":
+		step = false
+		err = s.undo()
+	case "save":
+		step = false
+		if len(fields) != 2 {
+			return fmt.Errorf("usage: save <file>")
+		}
+		err = s.save(fields[1])
+	case "show":
+		step = false
+		s.show()
+	case "help":
+		step = false
+		fmt.Fprint(s.out, help)
+	default:
+		if len(fields) != 1 {
+			return fmt.Errorf("unknown command '%s', try help", fields[0])
+		}
+		line = "send " + fields[0]
+		err = s.send(fields[0])
+	}
+
+	if err != nil {
+		return err
+	}
+	if step {
+		s.steps = append(s.steps, line)
+	}
+	return nil
+}
+
+func (s *Session) send(name string) error {
+	m := s.machine
+	e, ok := m.EventByName(name)
+	if !ok {
+		return fmt.Errorf("no event named '%s'", name)
+	}
+
+	before := m.ContextValues()
+	from := m.State()
+	if err := m.SendEventContext(context.Background(), e); err != nil {
+		return fmt.Errorf("%s: rejected, %w", name, err)
+	}
+
+	fmt.Fprintf(s.out, "%s --%s--> %s\n", m.GetNameForState(from), name, m.GetNameForState(m.State()))
+	for i, v := range m.ContextValues() {
+		if !reflect.DeepEqual(before[i].Value, v.Value) {
+			fmt.Fprintf(s.out, "    %s: %v -> %v\n", v.Name, before[i].Value, v.Value)
+		}
+	}
+	return nil
+}
+
+func (s *Session) set(name string, raw string) error {
+	m := s.machine
+	key, ok := m.ContextKeyByName(name)
+	if !ok {
+		return fmt.Errorf("no context key named '%s'", name)
+	}
+
+	for _, v := range m.ContextValues() {
+		if v.Key == key && v.Protected {
+			return fmt.Errorf("'%s' is protected, it can only be changed by a transition", name)
+		}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		// anything that isn't JSON is a string
+		value = raw
+	}
+
+	m.SetContext(key, value)
+	return nil
+}
+
+func (s *Session) guard(name string, on bool) error {
+	if _, ok := s.guards[name]; !ok {
+		return fmt.Errorf("no guard named '%s'", name)
+	}
+	s.guards[name] = on
+	return nil
+}
+
+// undo replays every step but the last on a new Machine
+func (s *Session) undo() error {
+	if len(s.steps) == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+
+	steps := s.steps[:len(s.steps)-1]
+	out := s.out
+	s.out = ioutil.Discard
+	defer func() { s.out = out }()
+
+	s.reset()
+	for _, step := range steps {
+		if err := s.Exec(step); err != nil {
+			return fmt.Errorf("replaying '%s': %w", step, err)
+		}
+	}
+	return nil
+}
+
+func (s *Session) save(path string) error {
+	script := strings.Join(s.steps, "\n")
+	if script != "" {
+		script += "\n"
+	}
+	if err := ioutil.WriteFile(path, []byte(script), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "saved %d steps to %s\n", len(s.steps), path)
+	return nil
+}
+
+func (s *Session) show() {
+	for _, v := range s.machine.ContextValues() {
+		protected := ""
+		if v.Protected {
+			protected = " (protected)"
+		}
+		fmt.Fprintf(s.out, "%s = %v%s\n", v.Name, v.Value, protected)
+	}
+
+	names := make([]string, 0, len(s.guards))
+	for name := range s.guards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		state := "on"
+		if !s.guards[name] {
+			state = "off"
+		}
+		fmt.Fprintf(s.out, "guard %s %s\n", name, state)
+	}
+}
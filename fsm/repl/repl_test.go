@@ -0,0 +1,97 @@
+package repl_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm/fsmfile"
+	"ojkelly.dev/fsm/repl"
+)
+
+const order = `{
+	"initial": "Created",
+	"events": ["Pay", "Ship", "Refund"],
+	"context": [
+		{"name": "Paid", "protected": true, "initial": false},
+		{"name": "Note", "initial": ""}
+	],
+	"states": [
+		{"name": "Created", "on": [{"event": "Pay", "target": "Paid", "set": {"Paid": true}}]},
+		{"name": "Paid", "on": [
+			{"event": "Ship", "target": "Shipped", "guard": "InStock"},
+			{"event": "Refund", "target": "Refunded"}
+		]},
+		{"name": "Shipped", "final": true},
+		{"name": "Refunded", "final": true}
+	]
+}`
+
+func newSession(t *testing.T) *repl.Session {
+	f, err := fsmfile.Parse(strings.NewReader(order))
+	assert.Nil(t, err)
+	s, err := repl.New(f)
+	assert.Nil(t, err)
+	return s
+}
+
+func Test_Session(t *testing.T) {
+	s := newSession(t)
+	assert.Equal(t, "[Created] Pay > ", s.Prompt())
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, s.Run(strings.NewReader(strings.Join([]string{
+		"Pay",
+		"guard InStock off",
+		"Ship",
+		"set Paid false",
+		"set Note \"gift\"",
+		"show",
+	}, "\n")), out, true))
+
+	assert.Contains(t, out.String(), "Created --Pay--> Paid\n    Paid: false -> true\n")
+	assert.Contains(t, out.String(), "Ship: rejected")
+	assert.Contains(t, out.String(), "InStock: fail")
+	assert.Contains(t, out.String(), "'Paid' is protected")
+	assert.Contains(t, out.String(), "Note = gift\nguard InStock off\n")
+	assert.Equal(t, "[Paid] Ship, Refund > ", s.Prompt())
+	assert.Equal(t, []string{"send Pay", "guard InStock off", "set Note \"gift\""}, s.Steps())
+
+	// undo the Note, then the guard
+	assert.Nil(t, s.Exec("undo"))
+	assert.Nil(t, s.Exec("undo"))
+	assert.Nil(t, s.Exec("Ship"))
+	assert.Equal(t, "[Shipped] no events > ", s.Prompt())
+
+	assert.Nil(t, s.Exec("undo"))
+	assert.Nil(t, s.Exec("undo"))
+	assert.Equal(t, "[Created] Pay > ", s.Prompt())
+	assert.NotNil(t, s.Exec("undo"))
+
+	assert.NotNil(t, s.Exec("guard Missing on"))
+	assert.NotNil(t, s.Exec("Explode"))
+	assert.NotNil(t, s.Exec("dance wildly"))
+}
+
+func Test_SaveAndReplay(t *testing.T) {
+	s := newSession(t)
+	assert.Nil(t, s.Exec("Pay"))
+	assert.Nil(t, s.Exec("Refund"))
+
+	path := filepath.Join(t.TempDir(), "refund.txt")
+	assert.Nil(t, s.Exec("save "+path))
+	script, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "send Pay\nsend Refund\n", string(script))
+
+	replayed := newSession(t)
+	assert.Nil(t, replayed.Run(bytes.NewReader(script), ioutil.Discard, false))
+	assert.Equal(t, "Refunded", replayed.Machine().GetNameForState(replayed.Machine().State()))
+
+	// a script stops at the first failed step
+	err = newSession(t).Run(strings.NewReader("Refund\n"), ioutil.Discard, false)
+	assert.NotNil(t, err)
+}
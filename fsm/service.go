@@ -0,0 +1,105 @@
+package fsm
+
+import (
+	"context"
+)
+
+// Service is long running work bound to the lifetime of a State, like polling
+// a job or holding a connection open. It's started when the State is entered,
+// and its ctx is cancelled when the State is left or m.Stop() is called.
+//
+// When Run returns, the Done or Error Event is sent to the Machine, unless
+// it's nil or the State has already been left. Both Events must be
+// registered in fsm.New().
+type Service struct {
+	// Id is used to get the result with m.ServiceResult()
+	Id string
+	// Run does the work, and should return once ctx is done
+	Run func(ctx context.Context, m *Machine) (interface{}, error)
+	// Done is sent when Run returns without an error, if it's set
+	Done *Event
+	// Error is sent when Run returns an error, if it's set
+	Error *Event
+}
+
+// ServiceResult is what a Service's Run returned
+type ServiceResult struct {
+	Value interface{}
+	Err   error
+}
+
+type runningService struct {
+	cancel context.CancelFunc
+}
+
+// ServiceResult returns the result of the last Service with id to finish,
+// usually called from the Transition handling its Done or Error Event.
+func (m *Machine) ServiceResult(id string) (ServiceResult, bool) {
+	m.checkIfCreatedCorrectly()
+	m.serviceMtx.Lock()
+	defer m.serviceMtx.Unlock()
+
+	r, ok := m.serviceResults[id]
+	return r, ok
+}
+
+// startServices must be called while holding m.stateChangeMtx
+func (m *Machine) startServices(s State, node StateNode) {
+	if m.stopped {
+		return
+	}
+
+	for _, service := range node.Invoke {
+		ctx, cancel := context.WithCancel(m.lifetime)
+		m.services = append(m.services, &runningService{cancel: cancel})
+
+		m.servicesWg.Add(1)
+		go m.runService(ctx, service)
+	}
+}
+
+// stopServices must be called while holding m.stateChangeMtx
+func (m *Machine) stopServices() {
+	for _, s := range m.services {
+		s.cancel()
+	}
+	m.services = nil
+}
+
+func (m *Machine) runService(ctx context.Context, s Service) {
+	defer m.servicesWg.Done()
+
+	value, err := s.Run(ctx, m)
+
+	// the State was left, nobody is waiting for this result
+	if ctx.Err() != nil {
+		return
+	}
+
+	m.serviceMtx.Lock()
+	m.serviceResults[s.Id] = ServiceResult{Value: value, Err: err}
+	m.serviceMtx.Unlock()
+
+	e := s.Done
+	if err != nil {
+		e = s.Error
+	}
+	if e == nil {
+		return
+	}
+
+	// ctx is cancelled if the State is left before we get the lock, so the
+	// Event can't be handled by a different State
+	if err := m.stateChangeMtx.LockContext(ctx); err != nil {
+		return
+	}
+	defer m.stateChangeMtx.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	// handling the Event may leave the State and cancel ctx, so the rest of
+	// the Transition is bound to the Machine instead
+	m.handleEvent(m.lifetime, *e)
+}
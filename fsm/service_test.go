@@ -0,0 +1,194 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"ojkelly.dev/fsm"
+)
+
+func Test_InvokedServices(t *testing.T) {
+	const (
+		Polling fsm.State = iota
+		Finished
+		Failed
+		Cancelled
+	)
+
+	const (
+		JobFinished fsm.Event = iota
+		JobFailed
+		Cancel
+		Retry
+	)
+
+	results := make(chan error)
+	cancelled := make(chan struct{}, 10)
+
+	newMachine := func() *fsm.Machine {
+		m := fsm.New(
+			"services",
+			10,
+			Polling,
+			fsm.Context{},
+			[]fsm.Event{JobFinished, JobFailed, Cancel, Retry},
+			fsm.States{
+				Polling: fsm.StateNode{
+					Invoke: []fsm.Service{{
+						Id: "poll",
+						Run: func(ctx context.Context, m *fsm.Machine) (interface{}, error) {
+							select {
+							case err := <-results:
+								return "job-1", err
+							case <-ctx.Done():
+								cancelled <- struct{}{}
+								return nil, ctx.Err()
+							}
+						},
+						Done:  fsm.EventPtr(JobFinished),
+						Error: fsm.EventPtr(JobFailed),
+					}},
+					Events: fsm.EventToTransition{
+						JobFinished: fsm.Transition{State: Finished},
+						JobFailed:   fsm.Transition{State: Failed},
+						Cancel:      fsm.Transition{State: Cancelled},
+					},
+				},
+				Failed: fsm.StateNode{
+					Events: fsm.EventToTransition{
+						Retry: fsm.Transition{State: Polling},
+					},
+				},
+			},
+			nil,
+		)
+		m.Start()
+		return m
+	}
+
+	m := newMachine()
+	results <- nil
+	change := <-m.StateChangeChannel()
+	assert.Equal(t, Finished, change.To)
+	assert.Equal(t, JobFinished, change.Cause)
+
+	result, ok := m.ServiceResult("poll")
+	assert.True(t, ok)
+	assert.Equal(t, "job-1", result.Value)
+	m.Stop()
+
+	// errors come back as the Error Event, and re-entering restarts it
+	m = newMachine()
+	results <- errors.New("job failed")
+	change = <-m.StateChangeChannel()
+	assert.Equal(t, Failed, change.To)
+	result, _ = m.ServiceResult("poll")
+	assert.EqualError(t, result.Err, "job failed")
+
+	assert.True(t, m.SendEvent(Retry))
+	<-m.StateChangeChannel()
+
+	// leaving the State cancels the Service
+	assert.True(t, m.SendEvent(Cancel))
+	<-cancelled
+	assert.Equal(t, Cancelled, (<-m.StateChangeChannel()).To)
+	m.Stop()
+	assert.True(t, (<-m.StateChangeChannel()).IsLast)
+
+	// stopping cancels the Service and waits for it
+	m = newMachine()
+	m.Stop()
+	assert.Len(t, cancelled, 1)
+	<-cancelled
+	assert.True(t, (<-m.StateChangeChannel()).IsLast)
+}
+
+func Test_InvokedServicesWithoutEvents(t *testing.T) {
+	const (
+		Waiting fsm.State = iota
+		Next
+	)
+
+	const (
+		Go fsm.Event = iota
+	)
+
+	returned := make(chan struct{})
+	m := fsm.New(
+		"services",
+		10,
+		Waiting,
+		fsm.Context{},
+		[]fsm.Event{Go},
+		fsm.States{
+			Waiting: fsm.StateNode{
+				// Done isn't set, so Go isn't sent when it returns
+				Invoke: []fsm.Service{{
+					Id: "fire-and-forget",
+					Run: func(ctx context.Context, m *fsm.Machine) (interface{}, error) {
+						defer close(returned)
+						return nil, nil
+					},
+				}},
+				Events: fsm.EventToTransition{
+					Go: fsm.Transition{State: Next},
+				},
+			},
+		},
+		nil,
+	)
+	m.Start()
+	<-returned
+	m.Stop()
+
+	assert.Equal(t, Waiting, m.State())
+	assert.True(t, (<-m.StateChangeChannel()).IsLast)
+}
+
+func Test_StopFromService(t *testing.T) {
+	const (
+		Running fsm.State = iota
+		Failed
+	)
+
+	const (
+		Fail fsm.Event = iota
+	)
+
+	m := fsm.New(
+		"services",
+		10,
+		Running,
+		fsm.Context{},
+		[]fsm.Event{Fail},
+		fsm.States{
+			Running: fsm.StateNode{
+				Invoke: []fsm.Service{{
+					Id: "watchdog",
+					Run: func(ctx context.Context, m *fsm.Machine) (interface{}, error) {
+						m.SendEvent(Fail)
+						<-ctx.Done()
+						return nil, ctx.Err()
+					},
+				}},
+				Events: fsm.EventToTransition{Fail: fsm.Transition{State: Failed}},
+			},
+			Failed: fsm.StateNode{
+				OnEntry: []fsm.TransitionEventHandler{
+					func(m *fsm.Machine, current fsm.State, next fsm.State, event fsm.TransitionEvent) {
+						// m.Stop() would wait for this handler to return
+						go m.Stop()
+					},
+				},
+			},
+		},
+		nil,
+	)
+	m.Start()
+
+	assert.Equal(t, fsm.StateChange{From: Running, To: Failed, Cause: Fail}, <-m.StateChangeChannel())
+	assert.True(t, (<-m.StateChangeChannel()).IsLast)
+	assert.Equal(t, Failed, m.State())
+}
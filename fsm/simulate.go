@@ -0,0 +1,116 @@
+package fsm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Configuration is the State and Context of a Machine, without the Machine.
+// It's used to explore a Definition without sending Events.
+type Configuration struct {
+	State   State
+	Context map[ContextKey]interface{}
+	// Err is returned by the UpdateContext handler of the Transition Step
+	// took to get here. Like a Machine, Step still takes the Transition.
+	// It's not part of the Key.
+	Err error
+}
+
+// InitialConfiguration is the initial State, with the initial Context
+func (d Definition) InitialConfiguration() Configuration {
+	values := map[ContextKey]interface{}{}
+	for key, meta := range d.Context {
+		values[key] = meta.Inital
+	}
+	return Configuration{State: d.InitialState, Context: values}
+}
+
+// Configuration of the Machine now
+func (m *Machine) Configuration() Configuration {
+	m.checkIfCreatedCorrectly()
+
+	values := map[ContextKey]interface{}{}
+	for _, v := range m.ContextValues() {
+		values[v.Key] = v.Value
+	}
+	return Configuration{State: m.State(), Context: values}
+}
+
+// Key identifies the Configuration, two Configurations with the same State
+// and Context have the same Key
+func (c Configuration) Key() string {
+	keys := make([]ContextKey, 0, len(c.Context))
+	for k := range c.Context {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	parts := make([]string, 0, len(keys)+1)
+	parts = append(parts, fmt.Sprintf("%d", c.State))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%d=%#v", k, c.Context[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// With returns a copy of the Configuration, with a Context value changed
+func (c Configuration) With(key ContextKey, value interface{}) Configuration {
+	values := make(map[ContextKey]interface{}, len(c.Context))
+	for k, v := range c.Context {
+		values[k] = v
+	}
+	values[key] = value
+	return Configuration{State: c.State, Context: values}
+}
+
+// Step takes Edge e from c, and returns the Configuration after it, or why
+// it wasn't taken. Only guards block a Transition, an error from its
+// UpdateContext handler is kept in the Configuration's Err.
+//
+// Guards and UpdateContext handlers are run on a Machine in c that only has
+// the Definition's States, Events and names. No other handlers are run.
+func (d Definition) Step(c Configuration, e Edge) (Configuration, *BlockedTransition) {
+	sim := d.simulate("step", c)
+
+	taken, tried, _ := sim.selectTransition(c.State, sim.states[c.State], e.Event)
+	if taken.To != e.To || taken.Candidate != e.Candidate {
+		return Configuration{}, &BlockedTransition{Edge: e, Tried: tried}
+	}
+
+	next := sim.Configuration()
+	next.State = e.To
+	if e.Transition.UpdateContext == nil {
+		return next, nil
+	}
+
+	update, err := e.Transition.UpdateContext(sim, c.State, e.To, TransitionEventEntry)
+	next.Err = err
+	for key, value := range update {
+		if _, ok := next.Context[key]; ok && value != nil {
+			next.Context[key] = value
+		}
+	}
+	return next, nil
+}
+
+// simulate returns a Machine in c, that only has the Definition's States,
+// Events and names
+func (d Definition) simulate(id string, c Configuration) *Machine {
+	context := Context{}
+	for key, meta := range d.Context {
+		if v, ok := c.Context[key]; ok {
+			meta.Inital = v
+		}
+		context[key] = meta
+	}
+
+	m := New(id, 0, c.State, context, d.Events, d.States, nil)
+	if d.Wildcards != nil {
+		m.AddWildcardTransitions(d.Wildcards)
+	}
+	m.AddStateNames(d.StateNames)
+	m.AddEventNames(d.EventNames)
+	m.AddContextKeyNames(d.ContextKeyNames)
+	return m
+}
@@ -0,0 +1,131 @@
+package fsm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Snapshot is what's persisted of a Machine, its State and Context by name,
+// so they can be restored by a later version of its Definition that numbers
+// them differently
+type Snapshot struct {
+	// Version of the Definition the Machine was created with
+	Version int                    `json:"version"`
+	State   string                 `json:"state"`
+	Context map[string]interface{} `json:"context"`
+}
+
+// Snapshot of the Machine now, taken with version of its Definition
+func (m *Machine) Snapshot(version int) Snapshot {
+	m.checkIfCreatedCorrectly()
+
+	values := map[string]interface{}{}
+	for _, v := range m.ContextValues() {
+		values[v.Name] = v.Value
+	}
+	return Snapshot{Version: version, State: m.GetNameForState(m.State()), Context: values}
+}
+
+// Restore creates a Machine in the Snapshot's State, with its Context. Keys
+// missing from the Snapshot keep their initial value.
+//
+// The Snapshot must be for this version of the Definition, use a Migration
+// to upgrade older ones. Each value must have the same type as the key's
+// initial value. Numbers are converted when they fit exactly, as JSON decodes
+// every number as a float64.
+func (d Definition) Restore(id string, s Snapshot) (*Machine, error) {
+	m := d.New(id)
+
+	state, ok := d.stateNamed(m, s.State)
+	if !ok {
+		return nil, fmt.Errorf("[%s] can't restore state '%s', it's not in the definition", id, s.State)
+	}
+
+	for name, value := range s.Context {
+		key, ok := d.contextKeyNamed(m, name)
+		if !ok {
+			return nil, fmt.Errorf("[%s] can't restore context '%s', it's not in the definition", id, name)
+		}
+		initial := d.Context[key].Inital
+		converted, ok := convertValue(value, initial)
+		if !ok {
+			return nil, fmt.Errorf(
+				"[%s] can't restore context '%s', it's %s and the definition expects %s",
+				id,
+				name,
+				reflect.TypeOf(value),
+				reflect.TypeOf(initial),
+			)
+		}
+		m.context[key].value = converted
+	}
+
+	m.state = state
+	return m, nil
+}
+
+// convertValue gives value the type of initial. Numbers are converted to
+// other numeric types if they fit exactly, so 1250.0 becomes 1250 but 12.5
+// and -1 can't become an int or a uint. Anything else must already have the
+// type, or be nil.
+func convertValue(value interface{}, initial interface{}) (interface{}, bool) {
+	if value == nil || initial == nil {
+		return value, true
+	}
+
+	v, want := reflect.ValueOf(value), reflect.TypeOf(initial)
+	if v.Type() == want {
+		return value, true
+	}
+	if !isNumber(v.Kind()) || !isNumber(want.Kind()) {
+		return value, false
+	}
+
+	// converting back catches fractions and overflow, but not a negative
+	// number wrapping around to a uint and back
+	converted := v.Convert(want)
+	if converted.Convert(v.Type()).Interface() != value || (isUnsigned(want.Kind()) && isNegative(v)) {
+		return value, false
+	}
+	return converted.Interface(), true
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64 && k != reflect.Uintptr
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+func isNegative(v reflect.Value) bool {
+	switch {
+	case isUnsigned(v.Kind()):
+		return false
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return v.Float() < 0
+	default:
+		return v.Int() < 0
+	}
+}
+
+// stateNamed finds a State by the name m gives it, which is its number if it
+// has no name
+func (d Definition) stateNamed(m *Machine, name string) (State, bool) {
+	for _, s := range d.SortedStates() {
+		if m.GetNameForState(s) == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// contextKeyNamed finds a ContextKey by the name m gives it
+func (d Definition) contextKeyNamed(m *Machine, name string) (ContextKey, bool) {
+	for key := range d.Context {
+		if m.GetNameForContextKey(key) == name {
+			return key, true
+		}
+	}
+	return 0, false
+}
@@ -1,6 +1,11 @@
 package fsm
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
 
 // State
 //
@@ -53,11 +58,32 @@ type StateNode struct {
 	// Error is a special predefined event
 	Error MachineErrorHandler
 
-	// Success is a special predefined event
+	// Success is a special predefined event, called after a Transition out of
+	// this State has been committed
 	Success TransitionEventHandler
 
+	// OnEntry handlers run whenever this State is entered, regardless of
+	// which Transition entered it
+	OnEntry []TransitionEventHandler
+
+	// OnExit handlers run whenever this State is left, regardless of which
+	// Transition left it
+	OnExit []TransitionEventHandler
+
+	// Invoke Services when this State is entered, they are cancelled when
+	// it's left
+	Invoke []Service
+
+	// Final marks a State the Machine has finished in. A spawned child in a
+	// Final State tells its parent it's done.
+	Final bool
+
 	// Events this State can Transition to
 	Events EventToTransition
+
+	// Choices are Events with several candidate Transitions, the first one
+	// whose Guard passes wins. They are tried before Events.
+	Choices EventToTransitions
 }
 
 // State returns the current state the Machine is in
@@ -71,8 +97,68 @@ func (m *Machine) State() State {
 // GetNextStates returns the States if any that can be transistioned to
 func (m *Machine) GetNextStates() []State {
 	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	seen := map[State]bool{}
+	next := []State{}
+	for _, e := range m.availableEvents() {
+		for _, t := range m.transitionsFor(m.state, e) {
+			if !seen[t.State] {
+				seen[t.State] = true
+				next = append(next, t.State)
+			}
+		}
+	}
+
+	sort.Slice(next, func(i, j int) bool { return next[i] < next[j] })
+	return next
+}
+
+// AvailableEvents returns the Events the current State has a Transition for,
+// including wildcard Transitions. Guards are not evaluated.
+func (m *Machine) AvailableEvents() []Event {
+	m.checkIfCreatedCorrectly()
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	return m.availableEvents()
+}
+
+func (m *Machine) availableEvents() []Event {
+	events := []Event{}
+	for e := range m.events {
+		if len(m.transitionsFor(m.state, e)) > 0 {
+			events = append(events, e)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	return events
+}
+
+// transitionsFor returns the candidate Transitions for an Event in a State,
+// falling back to the wildcard Transitions
+func (m *Machine) transitionsFor(s State, e Event) []Transition {
+	if candidates := m.states[s].candidates(e); len(candidates) > 0 {
+		return candidates
+	}
+	if t, ok := m.wildcards[e]; ok {
+		return []Transition{t}
+	}
+	return nil
+}
+
+// StateByName returns the State registered with a name in m.AddStateNames()
+func (m *Machine) StateByName(name string) (State, bool) {
+	m.checkIfCreatedCorrectly()
 
-	return []State{}
+	for s, n := range m.stateNames {
+		if n == name {
+			return s, true
+		}
+	}
+	return 0, false
 }
 
 // StateChange event sent via m.StateChangeChannel() after a State transition
@@ -91,3 +177,83 @@ type StateChange struct {
 func (m *Machine) StateChangeChannel() <-chan StateChange {
 	return m.stateChangeChannel
 }
+
+// observer is called with every committed StateChange while the Machine is
+// locked, so it must not block. The last StateChange is sent by m.Stop().
+type observer func(ctx context.Context, change StateChange)
+
+type registeredObserver struct {
+	id       int
+	observer observer
+}
+
+func (m *Machine) observe(o observer) int {
+	m.stateChangeMtx.Lock()
+	defer m.stateChangeMtx.Unlock()
+
+	return m.addObserver(o)
+}
+
+// addObserver must be called while holding m.stateChangeMtx
+func (m *Machine) addObserver(o observer) int {
+	m.nextObserverId++
+	m.observers = append(m.observers, registeredObserver{id: m.nextObserverId, observer: o})
+	return m.nextObserverId
+}
+
+// unobserve must be called while holding m.stateChangeMtx
+func (m *Machine) unobserve(id int) {
+	for i, o := range m.observers {
+		if o.id == id {
+			m.observers = append(m.observers[:i:i], m.observers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Watch returns a channel that receives a copy of every StateChange, without
+// taking them from m.StateChangeChannel(). StateChanges are dropped if the
+// channel is full. When m is stopped, or straight away if it already is, the
+// last one with IsLast set is sent and the channel is closed. Call cancel to
+// stop watching sooner, which closes the channel.
+func (m *Machine) Watch(buffer int) (changes <-chan StateChange, cancel func()) {
+	m.checkIfCreatedCorrectly()
+
+	c := make(chan StateChange, buffer)
+	// closed is guarded by m.stateChangeMtx, like the observers
+	closed := false
+	send := func(ctx context.Context, change StateChange) {
+		if closed {
+			return
+		}
+		select {
+		case c <- change:
+		default:
+		}
+		if change.IsLast {
+			close(c)
+			closed = true
+		}
+	}
+
+	m.stateChangeMtx.Lock()
+	id := m.addObserver(send)
+	if m.stopped {
+		send(context.Background(), StateChange{From: m.state, To: m.state, IsLast: true})
+	}
+	m.stateChangeMtx.Unlock()
+
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			m.stateChangeMtx.Lock()
+			defer m.stateChangeMtx.Unlock()
+
+			m.unobserve(id)
+			if !closed {
+				close(c)
+				closed = true
+			}
+		})
+	}
+}
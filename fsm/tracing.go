@@ -0,0 +1,99 @@
+package fsm
+
+import "context"
+
+// SpanKind is the step of handling an Event a span covers
+type SpanKind string
+
+const (
+	// SpanEvent covers handling an Event, from start to finish
+	SpanEvent SpanKind = "Event"
+	// SpanGuard covers evaluating one candidate Transition's guards
+	SpanGuard SpanKind = "Guard"
+	// SpanHandler covers one TransitionEventHandler
+	SpanHandler SpanKind = "Handler"
+	// SpanContextUpdate covers an UpdateContextHandler and applying its
+	// update
+	SpanContextUpdate SpanKind = "ContextUpdate"
+)
+
+// Attribute keys set on spans
+const (
+	AttributeMachineId = "fsm.machine.id"
+	AttributeState     = "fsm.state"
+	AttributeEvent     = "fsm.event"
+	AttributeNextState = "fsm.next_state"
+	AttributeHandler   = "fsm.handler"
+	AttributePassed    = "fsm.guard.passed"
+)
+
+// Attribute is a key, value pair on a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Instrumentation is called at the start of each step of handling an Event,
+// and the Span it returns is ended when the step is done. The ctx returned
+// by Start is the parent of nested steps, and is what m.EventContext()
+// returns to handlers while the step runs.
+type Instrumentation interface {
+	Start(ctx context.Context, kind SpanKind, name string, attrs []Attribute) (context.Context, Span)
+}
+
+// Span is one step of handling an Event
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// SetInstrumentation sets the Instrumentation, or removes it if i is nil
+func (m *Machine) SetInstrumentation(i Instrumentation) {
+	m.checkIfCreatedCorrectly()
+	m.hooksMtx.Lock()
+	defer m.hooksMtx.Unlock()
+
+	m.instrumentation = i
+}
+
+// activeSpan is a Span that's the m.EventContext() until it ends
+type activeSpan struct {
+	m    *Machine
+	span Span
+	prev context.Context
+}
+
+// startSpan must be called while holding m.stateChangeMtx
+func (m *Machine) startSpan(kind SpanKind, name string, attrs ...Attribute) activeSpan {
+	m.hooksMtx.RLock()
+	i := m.instrumentation
+	m.hooksMtx.RUnlock()
+
+	if i == nil {
+		return activeSpan{}
+	}
+
+	attrs = append([]Attribute{{Key: AttributeMachineId, Value: m.id}}, attrs...)
+	ctx, span := i.Start(m.EventContext(), kind, name, attrs)
+
+	return activeSpan{m: m, span: span, prev: m.setEventContext(ctx)}
+}
+
+func (s activeSpan) SetAttributes(attrs ...Attribute) {
+	if s.span != nil {
+		s.span.SetAttributes(attrs...)
+	}
+}
+
+func (s activeSpan) End(err error) {
+	if s.span == nil {
+		return
+	}
+
+	if err != nil {
+		s.span.RecordError(err)
+	}
+	s.span.End()
+	s.m.setEventContext(s.prev)
+}
@@ -1,10 +1,16 @@
 package fsm
 
+import (
+	"fmt"
+	"time"
+)
+
 type TransitionEvent string
 
 const (
 	TransitionEventEntry   TransitionEvent = "Entry"
 	TransitionEventExit    TransitionEvent = "Exit"
+	TransitionEventAction  TransitionEvent = "Action"
 	TransitionEventSuccess TransitionEvent = "Success"
 )
 
@@ -16,12 +22,176 @@ type Transition struct {
 	State State
 	// Guard hook can prevent this transition
 	Guard Guard
+	// Condition is a named Guard that can explain why it failed. If both
+	// Guard and Condition are set, both have to pass.
+	Condition Condition
 	// Entry called when transitioning to this State
 	Entry TransitionEventHandler
 	// Exit called when leaving this State for another
 	Exit TransitionEventHandler
 
+	// Actions run on every Transition, after the OnExit handlers of the
+	// current State and before the OnEntry handlers of the next State
+	Actions []TransitionEventHandler
+
 	// UpdateContext allows to update protected context values in response
 	// to an Event
 	UpdateContext UpdateContextHandler
 }
+
+// runTransition runs all the handlers for a Transition that has passed its
+// guards, and commits the new State.
+//
+// The order follows UML semantics:
+//
+//	stop Services -> StateNode.OnExit -> Transition.Exit    (only if the State changes)
+//	Transition.UpdateContext -> Transition.Actions
+//	Transition.Entry -> StateNode.OnEntry -> start Services (only if the State changes)
+//	commit -> StateNode.Success
+func (m *Machine) runTransition(currentState State, node StateNode, t Transition) {
+	next := t.State
+	changed := currentState != next
+
+	if changed {
+		m.stopServices()
+		m.runHandlers("OnExit", node.OnExit, currentState, next, TransitionEventExit)
+		m.runHandler("Exit", t.Exit, currentState, next, TransitionEventExit)
+	}
+
+	if t.UpdateContext != nil {
+		m.handleUpdateContext(t, currentState)
+	}
+
+	m.runHandlers("Action", t.Actions, currentState, next, TransitionEventAction)
+
+	if changed {
+		m.runHandler("Entry", t.Entry, currentState, next, TransitionEventEntry)
+		m.runHandlers("OnEntry", m.states[next].OnEntry, currentState, next, TransitionEventEntry)
+		m.startServices(next, m.states[next])
+	}
+
+	m.currentMtx.Lock()
+	m.state = next
+	m.currentMtx.Unlock()
+	if changed {
+		m.enteredAt = time.Now()
+	}
+
+	m.runHandler("Success", node.Success, currentState, next, TransitionEventSuccess)
+}
+
+func (m *Machine) runHandlers(
+	name string,
+	handlers []TransitionEventHandler,
+	current State,
+	next State,
+	event TransitionEvent,
+) {
+	for _, h := range handlers {
+		m.runHandler(name, h, current, next, event)
+	}
+}
+
+func (m *Machine) runHandler(
+	name string,
+	h TransitionEventHandler,
+	current State,
+	next State,
+	event TransitionEvent,
+) {
+	if h == nil {
+		return
+	}
+
+	span := m.startSpan(
+		SpanHandler,
+		"Handler "+name,
+		Attribute{Key: AttributeHandler, Value: name},
+		Attribute{Key: AttributeState, Value: m.GetNameForState(current)},
+		Attribute{Key: AttributeNextState, Value: m.GetNameForState(next)},
+	)
+	defer span.End(nil)
+
+	h(m, current, next, event)
+}
+
+// candidates returns the Transitions a StateNode has for an Event, in the
+// order they should be tried.
+func (n StateNode) candidates(e Event) []Transition {
+	candidates := append([]Transition{}, n.Choices[e]...)
+	if t, ok := n.Events[e]; ok {
+		candidates = append(candidates, t)
+	}
+	return candidates
+}
+
+// selectTransition picks the Transition to take for an Event, as an Edge.
+// Guarded candidates are tried in order, and the first unguarded candidate is
+// the fallback if none of them pass.
+func (m *Machine) selectTransition(
+	currentState State,
+	node StateNode,
+	e Event,
+) (Edge, []GuardAttempt, *TransitionError) {
+	candidates := node.candidates(e)
+	wildcard := false
+	if len(candidates) == 0 {
+		if t, ok := m.wildcards[e]; ok {
+			candidates = []Transition{t}
+			wildcard = true
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Edge{}, nil, m.newTransitionError(currentState, e, MachineErrorEventNotFoundForState)
+	}
+
+	edge := func(i int) Edge {
+		candidate := i
+		if wildcard {
+			candidate = -1
+		}
+		return Edge{From: currentState, Event: e, To: candidates[i].State, Candidate: candidate, Transition: candidates[i]}
+	}
+
+	fallback := -1
+	tried := []GuardAttempt{}
+
+	for i, t := range candidates {
+		if !t.guarded() {
+			if fallback == -1 {
+				fallback = i
+			}
+			continue
+		}
+
+		span := m.startSpan(
+			SpanGuard,
+			"Guard "+m.GetNameForState(t.State),
+			Attribute{Key: AttributeState, Value: m.GetNameForState(currentState)},
+			Attribute{Key: AttributeEvent, Value: m.GetNameForEvent(e)},
+			Attribute{Key: AttributeNextState, Value: m.GetNameForState(t.State)},
+		)
+		passed, result := m.evaluateGuards(t, currentState)
+		span.SetAttributes(Attribute{Key: AttributePassed, Value: fmt.Sprintf("%t", passed)})
+		span.End(nil)
+		tried = append(tried, GuardAttempt{
+			Candidate: i,
+			State:     t.State,
+			Passed:    passed,
+			Result:    result,
+		})
+
+		if passed {
+			return edge(i), tried, nil
+		}
+	}
+
+	if fallback != -1 {
+		return edge(fallback), tried, nil
+	}
+
+	tErr := m.newTransitionError(currentState, e, MachineErrorGuardFail)
+	tErr.Tried = tried
+	return Edge{}, tried, tErr
+}